@@ -1,8 +1,10 @@
 package iterm2
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/Tombar/iterm2/api"
@@ -12,11 +14,40 @@ import (
 // App represents an open iTerm2 application
 type App interface {
 	io.Closer
+	Quit(force bool) error
+	Run(ctx context.Context) error
 
 	CreateWindow() (Window, error)
+	CreateWindowWithFrame(f Frame) (Window, error)
+	CreateWindowWithTabs(specs []TabSpec) (Window, []Tab, error)
 	ListWindows() ([]Window, error)
+	ListWindowsOrdered() ([]Window, error)
+	GetWindow(id string) (Window, error)
+	GetTab(id string) (Tab, error)
+	GetSession(id string) (Session, error)
+	GetActiveWindow() (Window, error)
+	GetCurrentSession() (Session, error)
+	FocusSession(id string) error
+	GetFocusSnapshot() (FocusSnapshot, error)
+	Snapshot() (AppSnapshot, error)
+	RestoreFocus(snap FocusSnapshot) error
+	MoveSession(s Session, target Window) error
 	SelectMenuItem(item string) error
+	InvokeFunction(invocation string) (string, error)
+	SetClipboard(text string) error
+	PostNotification(title, body string) error
+	GetClipboard() (string, error)
+	ListColorPresets() ([]string, error)
+	GetAPIVersion() (APIVersion, error)
 	Activate(raiseAllWindows, ignoreOtherApps bool) error
+	GetScreens() ([]Screen, error)
+	GetVariable(name string) (string, error)
+	SetVariable(name, value string) error
+	SubscribeVariableChange(scope Scope, name string, opts ...SubscribeOption) (<-chan string, func(), error)
+	SubscribeNewSession(opts ...SubscribeOption) (<-chan Session, func(), error)
+	SubscribeSessionEnd(opts ...SubscribeOption) (<-chan string, func(), error)
+	SubscribeBroadcastDomainChange(onDrop func()) (<-chan [][]string, func(), error)
+	SubscribeProfileChange(opts ...SubscribeOption) (<-chan ProfileChange, func(), error)
 }
 
 // NewApp establishes a connection with iTerm2 and returns an App.
@@ -46,17 +77,47 @@ type App interface {
 //
 // Name is used to register your application with iTerm2 so that it doesn't
 // require explicit permissions every time you run the plugin. The name appears
-// in iTerm2's authorization dialog on first run.
-func NewApp(name string) (App, error) {
-	c, err := client.New(name)
+// in iTerm2's authorization dialog on first run. The connection can be
+// customized with options such as WithTimeout and WithReconnect.
+func NewApp(name string, opts ...Option) (App, error) {
+	return NewAppContext(context.Background(), name, opts...)
+}
+
+// NewAppContext is like NewApp, but cancelling ctx closes the connection to
+// iTerm2: calls in flight return and every later call fails with ctx.Err().
+// This gives a plugin a single place to stop all of its work on shutdown.
+// Close must still be called.
+func NewAppContext(ctx context.Context, name string, opts ...Option) (App, error) {
+	o := newAppOptions(opts)
+	dial := func() (conn, error) {
+		c, err := client.NewWithOptions(ctx, name, o.client)
+		if err != nil {
+			// Enhance error with typed sentinels for better error handling
+			return nil, enhanceConnectionError(err, name)
+		}
+		return c, nil
+	}
+	if o.reconnect {
+		rc, err := newReconnectingClient(dial)
+		if err != nil {
+			return nil, err
+		}
+		return &app{c: rc}, nil
+	}
+	c, err := dial()
 	if err != nil {
-		// Enhance error with typed sentinels for better error handling
-		return nil, enhanceConnectionError(err, name)
+		return nil, err
 	}
-
 	return &app{c: c}, nil
 }
 
+// NewAppWithClient returns an App that talks to iTerm2 through c instead of
+// connecting by itself, for example to record requests with a
+// client.Recorder. Closing the App closes c.
+func NewAppWithClient(c ClientInterface) App {
+	return &app{c: c}
+}
+
 // enhanceConnectionError wraps client connection errors with typed sentinels.
 // This allows users to programmatically detect and handle specific failure modes.
 func enhanceConnectionError(err error, appName string) error {
@@ -87,7 +148,7 @@ func enhanceConnectionError(err error, appName string) error {
 }
 
 type app struct {
-	c *client.Client
+	c ClientInterface
 }
 
 func (a *app) Activate(raiseAllWindows bool, ignoreOtherApps bool) error {
@@ -104,48 +165,226 @@ func (a *app) Activate(raiseAllWindows bool, ignoreOtherApps bool) error {
 }
 
 func (a *app) CreateWindow() (Window, error) {
+	w, _, err := a.createWindow(&api.CreateTabRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// createWindow creates a window holding a single tab as described by req,
+// and returns both.
+func (a *app) createWindow(req *api.CreateTabRequest) (*window, *tab, error) {
 	resp, err := a.c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_CreateTabRequest{
-			CreateTabRequest: &api.CreateTabRequest{},
+			CreateTabRequest: req,
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("could not create window tab: %w", err)
+		return nil, nil, fmt.Errorf("could not create window tab: %w", err)
 	}
 	ctr := resp.GetCreateTabResponse()
-	if ctr.GetStatus() != api.CreateTabResponse_OK {
-		return nil, fmt.Errorf("unexpected window tab status: %s", ctr.GetStatus())
+	if err := createTabError(req, ctr.GetStatus()); err != nil {
+		return nil, nil, fmt.Errorf("could not create window: %w", err)
 	}
-	return &window{
+	w := &window{
 		c:       a.c,
 		id:      ctr.GetWindowId(),
 		session: ctr.GetSessionId(),
-	}, nil
+	}
+	return w, &tab{c: a.c, id: strconv.Itoa(int(ctr.GetTabId())), windowID: w.id}, nil
+}
+
+// TabSpec describes a tab for CreateWindowWithTabs.
+type TabSpec struct {
+	// Profile is the name of the profile to create the tab with, or empty
+	// for the default profile.
+	Profile string
+	// Title, when set, replaces the tab's title.
+	Title string
+	// Color, when set, colors the tab.
+	Color *Color
+}
+
+// CreateWindowWithTabs opens a window with one tab per spec, in order, and
+// returns the window along with its tabs.
+//
+// Creation is all or nothing: if any tab cannot be created or styled, the
+// window is closed again, without asking the user, and only the error is
+// returned.
+func (a *app) CreateWindowWithTabs(specs []TabSpec) (Window, []Tab, error) {
+	if len(specs) == 0 {
+		return nil, nil, fmt.Errorf("at least one tab spec is required")
+	}
+	w, first, err := a.createWindow(tabRequest(specs[0]))
+	if err != nil {
+		return nil, nil, err
+	}
+	tabs, err := w.addTabs(first, specs)
+	if err != nil {
+		w.close()
+		return nil, nil, fmt.Errorf("could not create window with tabs, closed it again: %w", err)
+	}
+	return w, tabs, nil
+}
+
+func tabRequest(spec TabSpec) *api.CreateTabRequest {
+	req := &api.CreateTabRequest{}
+	if spec.Profile != "" {
+		req.ProfileName = str(spec.Profile)
+	}
+	return req
 }
 
+// CreateWindowWithFrame creates a window and moves it to f. Both happen in a
+// single transaction, so the window never shows up at its default size.
+func (a *app) CreateWindowWithFrame(f Frame) (Window, error) {
+	if f.Width <= 0 || f.Height <= 0 {
+		return nil, fmt.Errorf("invalid window frame %+v: width and height must be positive", f)
+	}
+	var w Window
+	err := a.transaction(func() error {
+		var err error
+		w, err = a.CreateWindow()
+		if err != nil {
+			return err
+		}
+		if err := w.(*window).setProperty("frame", newJSONFrame(f)); err != nil {
+			// Don't leave a window the caller has no handle to.
+			w.(*window).close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// ListWindows returns the open windows. Windows iTerm2 reports without an
+// id cannot be addressed and are left out; if there are any, the windows
+// that could be listed are returned together with an error matching
+// ErrMalformedResponse, which callers may choose to just log.
 func (a *app) ListWindows() ([]Window, error) {
 	list := []Window{}
-	resp, err := a.c.Call(&api.ClientOriginatedMessage{
-		Submessage: &api.ClientOriginatedMessage_ListSessionsRequest{
-			ListSessionsRequest: &api.ListSessionsRequest{},
-		},
-	})
+	lsr, err := listSessions(a.c)
 	if err != nil {
-		return nil, fmt.Errorf("could not list sessions: %w", err)
+		return nil, err
 	}
-	for _, w := range resp.GetListSessionsResponse().GetWindows() {
-		list = append(list, &window{
-			c:  a.c,
-			id: w.GetWindowId(),
-		})
+	skipped := 0
+	for _, w := range lsr.GetWindows() {
+		if w.GetWindowId() == "" {
+			skipped++
+			continue
+		}
+		list = append(list, newWindow(a.c, w))
+	}
+	if skipped > 0 {
+		return list, fmt.Errorf("%w: skipped %d of %d windows without an id",
+			ErrMalformedResponse, skipped, len(lsr.GetWindows()))
 	}
 	return list, nil
 }
 
+// GetWindow returns the window with the given id, or ErrWindowNotFound if
+// there is none.
+func (a *app) GetWindow(id string) (Window, error) {
+	lsr, err := listSessions(a.c)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range lsr.GetWindows() {
+		if w.GetWindowId() == id {
+			return newWindow(a.c, w), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrWindowNotFound, id)
+}
+
+// GetTab returns the tab with the given id in any window, or
+// ErrTabNotFound if there is none.
+func (a *app) GetTab(id string) (Tab, error) {
+	lsr, err := listSessions(a.c)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range lsr.GetWindows() {
+		for _, t := range w.GetTabs() {
+			if t.GetTabId() == id {
+				return &tab{c: a.c, id: id, windowID: w.GetWindowId()}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrTabNotFound, id)
+}
+
 func (a *app) Close() error {
 	return a.c.Close()
 }
 
+// GetSession returns the session with the given id, including buried
+// sessions, or ErrSessionNotFound if there is none.
+func (a *app) GetSession(id string) (Session, error) {
+	s, err := findSession(a.c, id)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// findSession looks up the session with the given id along with its window
+// and tab, which are left empty for buried sessions.
+func findSession(c ClientInterface, id string) (*session, error) {
+	lsr, err := listSessions(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range lsr.GetWindows() {
+		for _, t := range w.GetTabs() {
+			for _, sid := range sessionIDs(t.GetRoot()) {
+				if sid == id {
+					return &session{c: c, id: id, windowID: w.GetWindowId(), tabID: t.GetTabId()}, nil
+				}
+			}
+		}
+	}
+	for _, s := range lsr.GetBuriedSessions() {
+		if s.GetUniqueIdentifier() == id {
+			return &session{c: c, id: id}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, id)
+}
+
+// sessionIDs returns the ids of all sessions in a tab's split tree, in
+// layout order.
+func sessionIDs(node *api.SplitTreeNode) []string {
+	var ids []string
+	for _, link := range node.GetLinks() {
+		if s := link.GetSession(); s != nil {
+			ids = append(ids, s.GetUniqueIdentifier())
+		} else {
+			ids = append(ids, sessionIDs(link.GetNode())...)
+		}
+	}
+	return ids
+}
+
+// listSessions fetches iTerm2's current layout of windows, tabs and
+// sessions.
+func listSessions(c ClientInterface) (*api.ListSessionsResponse, error) {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ListSessionsRequest{
+			ListSessionsRequest: &api.ListSessionsRequest{},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list sessions: %w", err)
+	}
+	return resp.GetListSessionsResponse(), nil
+}
+
 func str(s string) *string {
 	return &s
 }
@@ -155,7 +394,11 @@ func b(b bool) *bool {
 }
 
 func (a *app) SelectMenuItem(item string) error {
-	resp, err := a.c.Call(&api.ClientOriginatedMessage{
+	return selectMenuItem(a.c, item)
+}
+
+func selectMenuItem(c ClientInterface, item string) error {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_MenuItemRequest{
 			MenuItemRequest: &api.MenuItemRequest{
 				Identifier: &item,
@@ -0,0 +1,403 @@
+package iterm2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+	"github.com/Tombar/iterm2/client"
+)
+
+// TestCreateWindowWithFrame verifies the window is created and moved inside a single transaction
+func TestCreateWindowWithFrame(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{
+			{},
+			createTabOK(1),
+			{},
+			{},
+		},
+	}
+	a := &app{c: mock}
+
+	w, err := a.CreateWindowWithFrame(Frame{X: 10, Y: 20, Width: 800, Height: 600})
+	if err != nil {
+		t.Fatalf("CreateWindowWithFrame() error = %v", err)
+	}
+	if w.(*window).id != "win-1" {
+		t.Errorf("window id = %q, want %q", w.(*window).id, "win-1")
+	}
+
+	if len(mock.calls) != 4 {
+		t.Fatalf("expected 4 Calls, got %d", len(mock.calls))
+	}
+	if !mock.calls[0].GetTransactionRequest().GetBegin() {
+		t.Error("expected the first call to begin a transaction")
+	}
+	if mock.calls[1].GetCreateTabRequest() == nil {
+		t.Errorf("expected CreateTabRequest, got %v", mock.calls[1])
+	}
+	set := mock.calls[2].GetSetPropertyRequest()
+	if set.GetWindowId() != "win-1" || set.GetName() != "frame" {
+		t.Errorf("unexpected SetPropertyRequest %v", set)
+	}
+	var got jsonFrame
+	if err := json.Unmarshal([]byte(set.GetJsonValue()), &got); err != nil {
+		t.Fatalf("frame is not valid JSON: %v", err)
+	}
+	if got.frame() != (Frame{X: 10, Y: 20, Width: 800, Height: 600}) {
+		t.Errorf("frame = %+v", got.frame())
+	}
+	if tr := mock.calls[3].GetTransactionRequest(); tr == nil || tr.GetBegin() {
+		t.Error("expected the last call to end the transaction")
+	}
+}
+
+// TestCreateWindowWithFrame_Invalid verifies empty frames are rejected before calling iTerm2
+func TestCreateWindowWithFrame_Invalid(t *testing.T) {
+	for _, f := range []Frame{{}, {Width: 100}, {Height: 100}, {Width: -1, Height: 100}} {
+		mock := &mockClient{}
+		a := &app{c: mock}
+		if _, err := a.CreateWindowWithFrame(f); err == nil {
+			t.Errorf("CreateWindowWithFrame(%+v) expected error, got nil", f)
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("expected no Calls for %+v, got %d", f, len(mock.calls))
+		}
+	}
+}
+
+// TestCreateWindowWithFrame_SetFails verifies the new window is closed and the transaction ended when moving it fails
+func TestCreateWindowWithFrame_SetFails(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{
+			{},
+			createTabOK(1),
+			{
+				Submessage: &api.ServerOriginatedMessage_SetPropertyResponse{
+					SetPropertyResponse: &api.SetPropertyResponse{Status: api.SetPropertyResponse_IMPOSSIBLE.Enum()},
+				},
+			},
+			{},
+			{},
+		},
+	}
+	a := &app{c: mock}
+
+	if _, err := a.CreateWindowWithFrame(Frame{Width: 800, Height: 600}); err == nil {
+		t.Fatal("CreateWindowWithFrame() expected error, got nil")
+	}
+	if len(mock.calls) != 5 {
+		t.Fatalf("expected 5 Calls, got %d", len(mock.calls))
+	}
+	if ids := mock.calls[3].GetCloseRequest().GetWindows().GetWindowIds(); len(ids) != 1 || ids[0] != "win-1" {
+		t.Errorf("expected window to be closed, got %v", mock.calls[3])
+	}
+	if mock.calls[4].GetTransactionRequest() == nil {
+		t.Error("expected the transaction to be ended")
+	}
+}
+
+func sessionLink(id string) *api.SplitTreeNode_SplitTreeLink {
+	return &api.SplitTreeNode_SplitTreeLink{
+		Child: &api.SplitTreeNode_SplitTreeLink_Session{
+			Session: &api.SessionSummary{UniqueIdentifier: str(id)},
+		},
+	}
+}
+
+// layout is a canned ListSessionsResponse with two windows. Tab 2 holds a
+// nested split: sess-2 next to sess-3 stacked over sess-4.
+func layout() *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{
+			ListSessionsResponse: &api.ListSessionsResponse{
+				Windows: []*api.ListSessionsResponse_Window{
+					{
+						WindowId: str("win-1"),
+						Tabs: []*api.ListSessionsResponse_Tab{
+							{
+								TabId: str("1"),
+								Root:  &api.SplitTreeNode{Links: []*api.SplitTreeNode_SplitTreeLink{sessionLink("sess-1")}},
+							},
+							{
+								TabId: str("2"),
+								Root: &api.SplitTreeNode{Links: []*api.SplitTreeNode_SplitTreeLink{
+									sessionLink("sess-2"),
+									{Child: &api.SplitTreeNode_SplitTreeLink_Node{Node: &api.SplitTreeNode{
+										Vertical: b(true),
+										Links:    []*api.SplitTreeNode_SplitTreeLink{sessionLink("sess-3"), sessionLink("sess-4")},
+									}}},
+								}},
+							},
+						},
+					},
+					{
+						WindowId: str("win-2"),
+						Tabs: []*api.ListSessionsResponse_Tab{
+							{
+								TabId: str("3"),
+								Root:  &api.SplitTreeNode{Links: []*api.SplitTreeNode_SplitTreeLink{sessionLink("sess-5")}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestGetTab verifies tabs are found in any window and missing ones report ErrTabNotFound
+func TestGetTab(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		wantWindow string
+		wantErr    error
+	}{
+		{name: "first window", id: "2", wantWindow: "win-1"},
+		{name: "second window", id: "3", wantWindow: "win-2"},
+		{name: "missing", id: "9", wantErr: ErrTabNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}}
+			got, err := a.GetTab(tt.id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetTab() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetTab() error = %v", err)
+			}
+			if got.GetID() != tt.id || got.(*tab).windowID != tt.wantWindow {
+				t.Errorf("GetTab() = %+v, want tab %q in %q", got, tt.id, tt.wantWindow)
+			}
+		})
+	}
+}
+
+// TestListWindows_Malformed verifies windows without an id are skipped and reported alongside the others
+func TestListWindows_Malformed(t *testing.T) {
+	resp := layout()
+	lsr := resp.GetListSessionsResponse()
+	lsr.Windows = append(lsr.Windows, &api.ListSessionsResponse_Window{}, &api.ListSessionsResponse_Window{WindowId: str("")})
+	a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{resp}}}
+
+	windows, err := a.ListWindows()
+	if !errors.Is(err, ErrMalformedResponse) {
+		t.Errorf("ListWindows() error = %v, want ErrMalformedResponse", err)
+	}
+	if len(windows) != 2 || windows[0].(*window).id != "win-1" || windows[1].(*window).id != "win-2" {
+		t.Errorf("ListWindows() = %v, want win-1 and win-2", windows)
+	}
+
+	a = &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}}
+	if windows, err := a.ListWindows(); err != nil || len(windows) != 2 {
+		t.Errorf("ListWindows() = %d windows, %v; want 2 and no error", len(windows), err)
+	}
+}
+
+// TestListWindows_Empty verifies no open windows is an empty list rather than ErrNoWindows
+func TestListWindows_Empty(t *testing.T) {
+	a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{{
+		Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{ListSessionsResponse: &api.ListSessionsResponse{}},
+	}}}}
+	windows, err := a.ListWindows()
+	if err != nil {
+		t.Fatalf("ListWindows() error = %v", err)
+	}
+	if windows == nil || len(windows) != 0 {
+		t.Errorf("ListWindows() = %#v, want an empty list", windows)
+	}
+}
+
+// TestGetWindow verifies windows are looked up by id and missing ones report ErrWindowNotFound
+func TestGetWindow(t *testing.T) {
+	a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}}
+	w, err := a.GetWindow("win-2")
+	if err != nil {
+		t.Fatalf("GetWindow() error = %v", err)
+	}
+	if w.(*window).id != "win-2" {
+		t.Errorf("GetWindow() id = %q, want %q", w.(*window).id, "win-2")
+	}
+
+	a = &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}}
+	if _, err := a.GetWindow("win-9"); !errors.Is(err, ErrWindowNotFound) {
+		t.Errorf("GetWindow() error = %v, want ErrWindowNotFound", err)
+	}
+}
+
+// TestGetSession verifies sessions are found anywhere in the split trees, including buried ones
+func TestGetSession(t *testing.T) {
+	buried := layout()
+	buried.GetListSessionsResponse().BuriedSessions = []*api.SessionSummary{{UniqueIdentifier: str("sess-9")}}
+
+	tests := []struct {
+		name       string
+		id         string
+		wantWindow string
+		wantTab    string
+		wantErr    error
+	}{
+		{name: "top level", id: "sess-1", wantWindow: "win-1", wantTab: "1"},
+		{name: "nested split", id: "sess-4", wantWindow: "win-1", wantTab: "2"},
+		{name: "second window", id: "sess-5", wantWindow: "win-2", wantTab: "3"},
+		{name: "buried", id: "sess-9"},
+		{name: "missing", id: "sess-0", wantErr: ErrSessionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{buried}}}
+			got, err := a.GetSession(tt.id)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetSession() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetSession() error = %v", err)
+			}
+			s := got.(*session)
+			if s.id != tt.id || s.windowID != tt.wantWindow || s.tabID != tt.wantTab {
+				t.Errorf("GetSession() = %+v, want %q in window %q tab %q", s, tt.id, tt.wantWindow, tt.wantTab)
+			}
+		})
+	}
+}
+
+// windowClient simulates a window win-1 whose tabs get ids 1, 2, ... and
+// hold one session each. Creating a tab with profile "Bogus" fails.
+func windowClient() *mockClient {
+	var tabs []*api.ListSessionsResponse_Tab
+	return &mockClient{
+		callFunc: func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+			switch {
+			case req.GetCreateTabRequest() != nil:
+				if req.GetCreateTabRequest().GetProfileName() == "Bogus" {
+					return &api.ServerOriginatedMessage{
+						Submessage: &api.ServerOriginatedMessage_CreateTabResponse{
+							CreateTabResponse: &api.CreateTabResponse{Status: api.CreateTabResponse_INVALID_PROFILE_NAME.Enum()},
+						},
+					}, nil
+				}
+				id := len(tabs) + 1
+				tabs = append(tabs, &api.ListSessionsResponse_Tab{
+					TabId: str(strconv.Itoa(id)),
+					Root:  &api.SplitTreeNode{Links: []*api.SplitTreeNode_SplitTreeLink{sessionLink("sess-" + strconv.Itoa(id))}},
+				})
+				return createTabOK(int32(id)), nil
+			case req.GetListSessionsRequest() != nil:
+				return &api.ServerOriginatedMessage{
+					Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{
+						ListSessionsResponse: &api.ListSessionsResponse{
+							Windows: []*api.ListSessionsResponse_Window{{WindowId: str("win-1"), Tabs: tabs}},
+						},
+					},
+				}, nil
+			}
+			return &api.ServerOriginatedMessage{}, nil
+		},
+	}
+}
+
+// TestCreateWindowWithTabs verifies each tab is created with its profile and styled
+func TestCreateWindowWithTabs(t *testing.T) {
+	mock := windowClient()
+	a := &app{c: mock}
+
+	w, tabs, err := a.CreateWindowWithTabs([]TabSpec{
+		{Title: "editor"},
+		{Profile: "Logs", Title: "logs", Color: &Color{R: 255}},
+		{},
+	})
+	if err != nil {
+		t.Fatalf("CreateWindowWithTabs() error = %v", err)
+	}
+	if w.(*window).id != "win-1" {
+		t.Errorf("window id = %q, want %q", w.(*window).id, "win-1")
+	}
+	if len(tabs) != 3 {
+		t.Fatalf("expected 3 tabs, got %d", len(tabs))
+	}
+	for i, tab := range tabs {
+		if want := strconv.Itoa(i + 1); tab.GetID() != want {
+			t.Errorf("tab %d id = %q, want %q", i, tab.GetID(), want)
+		}
+	}
+
+	var created []string
+	var titled []string
+	var colored []string
+	for _, req := range mock.calls {
+		switch {
+		case req.GetCreateTabRequest() != nil:
+			created = append(created, req.GetCreateTabRequest().GetProfileName())
+		case req.GetInvokeFunctionRequest() != nil:
+			titled = append(titled, req.GetInvokeFunctionRequest().GetMethod().GetReceiver())
+		case req.GetSetProfilePropertyRequest() != nil:
+			colored = append(colored, req.GetSetProfilePropertyRequest().GetSession())
+		}
+	}
+	if len(created) != 3 || created[0] != "" || created[1] != "Logs" || created[2] != "" {
+		t.Errorf("created tabs with profiles %q", created)
+	}
+	if len(titled) != 2 || titled[0] != "1" || titled[1] != "2" {
+		t.Errorf("set titles of tabs %q, want [1 2]", titled)
+	}
+	if len(colored) != 1 || colored[0] != "sess-2" {
+		t.Errorf("colored sessions %q, want [sess-2]", colored)
+	}
+}
+
+// TestCreateWindowWithTabs_RollsBack verifies the window is closed when a later tab fails
+func TestCreateWindowWithTabs_RollsBack(t *testing.T) {
+	mock := windowClient()
+	a := &app{c: mock}
+
+	w, tabs, err := a.CreateWindowWithTabs([]TabSpec{{}, {Profile: "Bogus"}})
+	if err == nil {
+		t.Fatal("CreateWindowWithTabs() expected error, got nil")
+	}
+	if w != nil || tabs != nil {
+		t.Errorf("expected no handles on failure, got %v and %v", w, tabs)
+	}
+	last := mock.calls[len(mock.calls)-1].GetCloseRequest()
+	if ids := last.GetWindows().GetWindowIds(); len(ids) != 1 || ids[0] != "win-1" || !last.GetForce() {
+		t.Errorf("expected window to be force-closed, got %v", last)
+	}
+}
+
+// TestCreateWindowWithTabs_Empty verifies an empty layout is rejected before calling iTerm2
+func TestCreateWindowWithTabs_Empty(t *testing.T) {
+	mock := &mockClient{}
+	a := &app{c: mock}
+	if _, _, err := a.CreateWindowWithTabs(nil); err == nil {
+		t.Error("CreateWindowWithTabs(nil) expected error, got nil")
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected no Calls, got %d", len(mock.calls))
+	}
+}
+
+// TestNewAppWithClient verifies an App runs its calls through the given client
+func TestNewAppWithClient(t *testing.T) {
+	var buf bytes.Buffer
+	a := NewAppWithClient(client.NewRecorder(&buf))
+	if err := a.SelectMenuItem("Minimize"); err != nil {
+		t.Fatalf("SelectMenuItem() error = %v", err)
+	}
+	if got := buf.String(); !strings.Contains(got, "menuItemRequest") || !strings.Contains(got, "Minimize") {
+		t.Errorf("recorded %q, want the menu item request", got)
+	}
+}
@@ -0,0 +1,101 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// BackgroundMode is how a background image is fitted to a session. The
+// values are the ones iTerm2 stores in the "Background Image Mode" profile
+// key.
+type BackgroundMode int
+
+// Background image modes.
+const (
+	// BackgroundStretch stretches the image to the session's size,
+	// distorting it if the proportions differ.
+	BackgroundStretch BackgroundMode = 0
+	// BackgroundTile repeats the image at its own size.
+	BackgroundTile BackgroundMode = 1
+	// BackgroundScale scales the image to cover the whole session,
+	// cropping what sticks out.
+	BackgroundScale BackgroundMode = 2
+	// BackgroundScaleToFit scales the image to fit inside the session,
+	// leaving bars where the proportions differ.
+	BackgroundScaleToFit BackgroundMode = 3
+)
+
+// String returns the name of the mode.
+func (m BackgroundMode) String() string {
+	switch m {
+	case BackgroundStretch:
+		return "stretch"
+	case BackgroundTile:
+		return "tile"
+	case BackgroundScale:
+		return "scale"
+	case BackgroundScaleToFit:
+		return "scale to fit"
+	default:
+		return fmt.Sprintf("BackgroundMode(%d)", int(m))
+	}
+}
+
+// SetBackgroundImage shows the image at path behind the session's text,
+// fitted according to mode. An empty path removes the background image.
+// The file must exist, but its format is left for iTerm2 to check: it
+// accepts any image macOS can open. Relative paths are made absolute first,
+// since iTerm2 does not share the program's working directory.
+//
+// How strongly the image shows through the background color is set by the
+// "Blend" profile key, which SetProfileProperties can change.
+func (s *session) SetBackgroundImage(path string, mode BackgroundMode) error {
+	switch mode {
+	case BackgroundStretch, BackgroundTile, BackgroundScale, BackgroundScaleToFit:
+	default:
+		return fmt.Errorf("could not set background image of session %q: invalid mode %d", s.id, int(mode))
+	}
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("could not set background image of session %q: %w", s.id, err)
+		}
+		fi, err := os.Stat(abs)
+		if err != nil {
+			return fmt.Errorf("could not set background image of session %q: %w", s.id, err)
+		}
+		if fi.IsDir() {
+			return fmt.Errorf("could not set background image of session %q: %q is a directory", s.id, abs)
+		}
+		path = abs
+	}
+	location, err := json.Marshal(path)
+	if err != nil {
+		return fmt.Errorf("could not encode background image path for session %q: %w", s.id, err)
+	}
+	err = setProfileProperties(s.c, s.id,
+		&api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Background Image Location"),
+			JsonValue: str(string(location)),
+		},
+		&api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Background Image Mode"),
+			JsonValue: str(strconv.Itoa(int(mode))),
+		},
+		// Versions of iTerm2 before modes were introduced only know
+		// whether the image is tiled.
+		&api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Background Image Is Tiled"),
+			JsonValue: str(strconv.FormatBool(mode == BackgroundTile)),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not set background image for session %q: %w", s.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSetBackgroundImage verifies the location and mode keys are written in one request
+func TestSetBackgroundImage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bg é.png")
+	if err := os.WriteFile(path, []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path      string
+		mode      BackgroundMode
+		wantMode  string
+		wantTiled string
+	}{
+		{path, BackgroundTile, "1", "true"},
+		{path, BackgroundScale, "2", "false"},
+		{"", BackgroundStretch, "0", "false"},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+		if err := s.SetBackgroundImage(tt.path, tt.mode); err != nil {
+			t.Fatalf("SetBackgroundImage(%q, %v) error = %v", tt.path, tt.mode, err)
+		}
+		if len(mock.calls) != 1 {
+			t.Fatalf("expected a single Call, got %d", len(mock.calls))
+		}
+		got := map[string]string{}
+		for _, a := range mock.calls[0].GetSetProfilePropertyRequest().GetAssignments() {
+			got[a.GetKey()] = a.GetJsonValue()
+		}
+		var location string
+		if err := json.Unmarshal([]byte(got["Background Image Location"]), &location); err != nil || location != tt.path {
+			t.Errorf("Background Image Location = %s, want %q", got["Background Image Location"], tt.path)
+		}
+		if got["Background Image Mode"] != tt.wantMode || got["Background Image Is Tiled"] != tt.wantTiled {
+			t.Errorf("mode keys = %v, want mode %s and tiled %s", got, tt.wantMode, tt.wantTiled)
+		}
+	}
+}
+
+// TestSetBackgroundImage_Invalid verifies missing files, directories and unknown modes are rejected before calling iTerm2
+func TestSetBackgroundImage_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		path string
+		mode BackgroundMode
+	}{
+		{filepath.Join(dir, "missing.png"), BackgroundStretch},
+		{dir, BackgroundStretch},
+		{"", BackgroundMode(7)},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+		if err := s.SetBackgroundImage(tt.path, tt.mode); err == nil || len(mock.calls) != 0 {
+			t.Errorf("SetBackgroundImage(%q, %v) expected error without Calls, got %v and %d calls", tt.path, tt.mode, err, len(mock.calls))
+		}
+	}
+}
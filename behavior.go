@@ -0,0 +1,148 @@
+package iterm2
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// OptionKeyMode is what an Option key sends when pressed with another key.
+// The values are the ones iTerm2 stores in the "Option Key Sends" and
+// "Right Option Key Sends" profile keys.
+type OptionKeyMode int
+
+// Option key modes.
+const (
+	// OptionKeyNormal types the character macOS maps the combination
+	// to, such as "å" for Option-A.
+	OptionKeyNormal OptionKeyMode = 0
+	// OptionKeyMeta sets the high bit of the key's character. Few
+	// programs expect this; prefer OptionKeyEsc.
+	OptionKeyMeta OptionKeyMode = 1
+	// OptionKeyEsc sends Escape followed by the key, which is what
+	// Emacs and shells expect for Meta.
+	OptionKeyEsc OptionKeyMode = 2
+)
+
+// String returns the name of the mode.
+func (m OptionKeyMode) String() string {
+	switch m {
+	case OptionKeyNormal:
+		return "normal"
+	case OptionKeyMeta:
+		return "meta"
+	case OptionKeyEsc:
+		return "esc+"
+	default:
+		return fmt.Sprintf("OptionKeyMode(%d)", int(m))
+	}
+}
+
+// termNameRe matches terminfo entry names such as "xterm-256color" or
+// "vt100+fnkeys".
+var termNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// maxTabStopWidth is the widest tab stop spacing SetTabStopWidth accepts.
+const maxTabStopWidth = 255
+
+// tabStopColumns is the number of columns SetTabStopWidth sets stops in.
+// Stops past the session's width are clamped to its last column.
+const tabStopColumns = 1024
+
+// SetOptionKeyMode sets what the left and right Option keys send.
+func (s *session) SetOptionKeyMode(left, right OptionKeyMode) error {
+	for _, m := range []OptionKeyMode{left, right} {
+		switch m {
+		case OptionKeyNormal, OptionKeyMeta, OptionKeyEsc:
+		default:
+			return fmt.Errorf("could not set option key mode for session %q: invalid mode %d", s.id, int(m))
+		}
+	}
+	err := setProfileProperties(s.c, s.id,
+		&api.SetProfilePropertyRequest_Assignment{Key: str("Option Key Sends"), JsonValue: str(strconv.Itoa(int(left)))},
+		&api.SetProfilePropertyRequest_Assignment{Key: str("Right Option Key Sends"), JsonValue: str(strconv.Itoa(int(right)))},
+	)
+	if err != nil {
+		return fmt.Errorf("could not set option key mode for session %q: %w", s.id, err)
+	}
+	return nil
+}
+
+// SetBellSilenced turns the audible bell off or back on.
+func (s *session) SetBellSilenced(silenced bool) error {
+	return s.setProfileProperty("Silence Bell", silenced)
+}
+
+// SetVisualBell makes the session flash when the bell rings, or stops it
+// from doing so.
+func (s *session) SetVisualBell(enabled bool) error {
+	return s.setProfileProperty("Visual Bell", enabled)
+}
+
+// SetTerminalType sets the terminal type the session reports, such as
+// "xterm-256color" or "vt100", stored in the "Terminal Type" profile key.
+// Only the form of term is checked, not whether the system has a terminfo
+// entry for it.
+//
+// iTerm2 exports the type as TERM when it starts a session's shell, so the
+// shell that is already running keeps its old TERM; programs that query the
+// terminal itself see the new type right away.
+func (s *session) SetTerminalType(term string) error {
+	if !termNameRe.MatchString(term) {
+		return fmt.Errorf("could not set terminal type of session %q: invalid type %q", s.id, term)
+	}
+	return s.setProfileProperty("Terminal Type", term)
+}
+
+// SetAnswerback sets the string the session sends back when a program
+// writes ENQ (Ctrl-E) to it, stored in the "Answerback String" profile key.
+// An empty string turns the answerback off.
+func (s *session) SetAnswerback(answerback string) error {
+	return s.setProfileProperty("Answerback String", answerback)
+}
+
+// SetWordCharacters sets which characters besides letters and digits count
+// as part of a word when double-clicking to select, such as "/-._~" to
+// select whole paths. It is stored in the "Characters Considered Part of a
+// Word" profile key, shown as "Word Characters" in iTerm2's settings.
+func (s *session) SetWordCharacters(chars string) error {
+	return s.setProfileProperty("Characters Considered Part of a Word", chars)
+}
+
+// SetTabStopWidth places the session's tab stops every width columns, like
+// the tabs(1) command. Tab stops are terminal state rather than a profile
+// setting: they last until the program running in the session changes them
+// or the terminal is reset.
+func (s *session) SetTabStopWidth(width int) error {
+	if width < 1 || width > maxTabStopWidth {
+		return fmt.Errorf("could not set tab stops of session %q: width %d is not between 1 and %d", s.id, width, maxTabStopWidth)
+	}
+	var seq strings.Builder
+	// Save the cursor, clear every tab stop, set the new ones on the
+	// cursor's line and put the cursor back.
+	seq.WriteString("\x1b7\x1b[3g")
+	for col := width + 1; col <= tabStopColumns; col += width {
+		seq.WriteString("\x1b[" + strconv.Itoa(col) + "G\x1bH")
+	}
+	seq.WriteString("\x1b8")
+	return s.inject([]byte(seq.String()))
+}
+
+// Reset puts the session's terminal back into a sane state after a program
+// has left it in a mess. A soft reset (DECSTR) restores modes, character
+// sets, margins and the cursor while keeping the screen contents; a hard
+// reset (RIS) additionally clears the screen and tab stops, as if the
+// terminal had just been opened. Scrollback history is kept either way.
+//
+// The sequence is processed by the terminal directly, so it works even when
+// the program in the session is not reading its input.
+func (s *session) Reset(hard bool) error {
+	seq := "\x1b[!p"
+	if hard {
+		seq = "\x1bc"
+	}
+	return s.inject([]byte(seq))
+}
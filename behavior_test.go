@@ -0,0 +1,142 @@
+package iterm2
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSetOptionKeyMode verifies both keys are written in one request and unknown modes rejected
+func TestSetOptionKeyMode(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetOptionKeyMode(OptionKeyEsc, OptionKeyNormal); err != nil {
+		t.Fatalf("SetOptionKeyMode() error = %v", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected a single Call, got %d", len(mock.calls))
+	}
+	a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+	if len(a) != 2 || a[0].GetKey() != "Option Key Sends" || a[0].GetJsonValue() != "2" ||
+		a[1].GetKey() != "Right Option Key Sends" || a[1].GetJsonValue() != "0" {
+		t.Errorf("assignments = %v", a)
+	}
+
+	if err := s.SetOptionKeyMode(OptionKeyNormal, OptionKeyMode(3)); err == nil || len(mock.calls) != 1 {
+		t.Errorf("SetOptionKeyMode() with invalid mode: error = %v, %d calls", err, len(mock.calls))
+	}
+}
+
+// TestSetBell verifies the bell toggles write their profile keys
+func TestSetBell(t *testing.T) {
+	tests := []struct {
+		set  func(s *session) error
+		key  string
+		want string
+	}{
+		{func(s *session) error { return s.SetBellSilenced(true) }, "Silence Bell", "true"},
+		{func(s *session) error { return s.SetVisualBell(false) }, "Visual Bell", "false"},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{}
+		if err := tt.set(&session{c: mock, id: "sess-1"}); err != nil {
+			t.Fatalf("setting %s error = %v", tt.key, err)
+		}
+		a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+		if len(a) != 1 || a[0].GetKey() != tt.key || a[0].GetJsonValue() != tt.want {
+			t.Errorf("assignments = %v, want %s=%s", a, tt.key, tt.want)
+		}
+	}
+}
+
+// TestSetTerminalIdentity verifies the terminal type and answerback are written to their profile keys
+func TestSetTerminalIdentity(t *testing.T) {
+	tests := []struct {
+		set  func(s *session) error
+		key  string
+		want string
+	}{
+		{func(s *session) error { return s.SetTerminalType("xterm-256color") }, "Terminal Type", `"xterm-256color"`},
+		{func(s *session) error { return s.SetTerminalType("vt100+fnkeys") }, "Terminal Type", `"vt100+fnkeys"`},
+		{func(s *session) error { return s.SetAnswerback("iTerm2 \"test\"") }, "Answerback String", `"iTerm2 \"test\""`},
+		{func(s *session) error { return s.SetAnswerback("") }, "Answerback String", `""`},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{}
+		if err := tt.set(&session{c: mock, id: "sess-1"}); err != nil {
+			t.Fatalf("setting %s error = %v", tt.key, err)
+		}
+		a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+		if len(a) != 1 || a[0].GetKey() != tt.key || a[0].GetJsonValue() != tt.want {
+			t.Errorf("assignments = %v, want %s=%s", a, tt.key, tt.want)
+		}
+	}
+
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	for _, term := range []string{"", "xterm 256color", "-xterm", "xterm\n"} {
+		if err := s.SetTerminalType(term); err == nil {
+			t.Errorf("SetTerminalType(%q) expected error, got nil", term)
+		}
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected no Calls for invalid types, got %d", len(mock.calls))
+	}
+}
+
+// TestSetWordCharacters verifies the characters are written to the word characters profile key
+func TestSetWordCharacters(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.SetWordCharacters("/-._~"); err != nil {
+		t.Fatalf("SetWordCharacters() error = %v", err)
+	}
+	a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+	if len(a) != 1 || a[0].GetKey() != "Characters Considered Part of a Word" || a[0].GetJsonValue() != `"/-._~"` {
+		t.Errorf("assignments = %v", a)
+	}
+}
+
+// TestSetTabStopWidth verifies the stops are cleared and reset with the cursor saved around them
+func TestSetTabStopWidth(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetTabStopWidth(4); err != nil {
+		t.Fatalf("SetTabStopWidth() error = %v", err)
+	}
+	got := string(mock.calls[0].GetInjectRequest().GetData())
+	if !strings.HasPrefix(got, "\x1b7\x1b[3g\x1b[5G\x1bH\x1b[9G\x1bH") || !strings.HasSuffix(got, "\x1b[1021G\x1bH\x1b8") {
+		t.Errorf("injected %q", got)
+	}
+
+	for _, width := range []int{0, -1, 256} {
+		if err := s.SetTabStopWidth(width); err == nil {
+			t.Errorf("SetTabStopWidth(%d) expected error, got nil", width)
+		}
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("expected invalid widths to make no Calls, got %d", len(mock.calls)-1)
+	}
+}
+
+// TestReset verifies soft and hard resets inject DECSTR and RIS respectively
+func TestReset(t *testing.T) {
+	tests := []struct {
+		hard bool
+		want string
+	}{
+		{false, "\x1b[!p"},
+		{true, "\x1bc"},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+		if err := s.Reset(tt.hard); err != nil {
+			t.Fatalf("Reset(%v) error = %v", tt.hard, err)
+		}
+		if got := string(mock.calls[0].GetInjectRequest().GetData()); got != tt.want {
+			t.Errorf("Reset(%v) injected %q, want %q", tt.hard, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package iterm2
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// ScreenContents is a snapshot of lines of a session's buffer.
+type ScreenContents struct {
+	// Lines holds the text of each line, without the blank cells at its
+	// end that were never written to.
+	Lines []string
+	// FirstLine is the number of the first line in Lines, counted from the
+	// start of the scrollback history like Coord.Y.
+	FirstLine int
+	// Cursor is the position of the cursor.
+	Cursor Coord
+}
+
+// String returns the lines joined by newlines.
+func (c *ScreenContents) String() string {
+	return strings.Join(c.Lines, "\n")
+}
+
+// GetScreenContents returns the lines currently shown on the session's
+// screen, not including scrollback history.
+func (s *session) GetScreenContents() (*ScreenContents, error) {
+	return s.getBuffer(&api.LineRange{ScreenContentsOnly: b(true)})
+}
+
+// SaveScreenContents writes the lines on the session's screen to the file
+// at path as UTF-8 text, one line per line, replacing the file if it
+// exists. With includeScrollback the scrollback history is written too.
+//
+// Errors reading the session are returned as from GetScreenContents, so
+// they match ErrSessionNotFound and the like. Errors writing the file wrap
+// an *os.PathError, which can be told apart with errors.As.
+func (s *session) SaveScreenContents(path string, includeScrollback bool) error {
+	lines := &api.LineRange{ScreenContentsOnly: b(true)}
+	if includeScrollback {
+		lines = &api.LineRange{TrailingLines: proto.Int32(math.MaxInt32)}
+	}
+	contents, err := s.getBuffer(lines)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(contents.String()+"\n"), 0666); err != nil {
+		return fmt.Errorf("could not save contents of session %q: %w", s.id, err)
+	}
+	return nil
+}
+
+// Token marks how far GetContentsSince has read a session's buffer. The
+// zero Token stands for the end of the buffer at the time of the call.
+type Token struct {
+	// line is the number of the first line not read yet.
+	line  int64
+	valid bool
+}
+
+// GetContentsSince returns the lines written to the session since token was
+// returned, along with the token to pass to the next call, so that output
+// can be followed without fetching the whole buffer every time. Called with
+// the zero Token it returns no lines and a token for the current end of the
+// buffer.
+//
+// Only finished lines are returned: the line the cursor is on, and any
+// line that wraps onto it, is held back until output moves past it. Long
+// lines that were wrapped to the width of the session are joined back
+// together. Lines that dropped out of the scrollback history before they
+// were read are skipped.
+func (s *session) GetContentsSince(token Token) ([]string, Token, error) {
+	last, err := s.getBufferResponse(&api.LineRange{TrailingLines: proto.Int32(1)})
+	if err != nil {
+		return nil, token, err
+	}
+	cursor := last.GetCursor().GetY()
+	if !token.valid {
+		return []string{}, Token{line: cursor, valid: true}, nil
+	}
+	if cursor <= token.line {
+		return []string{}, token, nil
+	}
+	gbr, err := s.getBufferResponse(&api.LineRange{
+		WindowedCoordRange: &api.WindowedCoordRange{
+			CoordRange: GridRange{Start: Coord{Y: int(token.line)}, End: Coord{Y: int(cursor)}}.Proto(),
+		},
+	})
+	if err != nil {
+		return nil, token, err
+	}
+	first := gbr.GetWindowedCoordRange().GetCoordRange().GetStart().GetY()
+	next := token.line
+	if first > next {
+		next = first
+	}
+	lines := []string{}
+	var line strings.Builder
+	for i, l := range gbr.GetContents() {
+		if first+int64(i) >= cursor {
+			break
+		}
+		line.WriteString(l.GetText())
+		if l.GetContinuation() == api.LineContents_CONTINUATION_SOFT_EOL {
+			continue
+		}
+		lines = append(lines, line.String())
+		line.Reset()
+		next = first + int64(i) + 1
+	}
+	return lines, Token{line: next, valid: true}, nil
+}
+
+// SubscribeScreenUpdate signals on the returned channel when the session's
+// screen changes, so that its contents only need to be fetched again when
+// there is something new. Updates are coalesced: while a signal is waiting
+// to be received, further updates do not queue up behind it. Call the
+// returned function to stop the subscription; the channel is closed once it
+// returns.
+func (s *session) SubscribeScreenUpdate() (<-chan struct{}, func(), error) {
+	ch := make(chan struct{}, 1)
+	cancel, err := subscribe(s.c, &api.NotificationRequest{
+		Session:          &s.id,
+		NotificationType: api.NotificationType_NOTIFY_ON_SCREEN_UPDATE.Enum(),
+	}, func(n *api.Notification) {
+		su := n.GetScreenUpdateNotification()
+		if su == nil || su.GetSession() != s.id {
+			return
+		}
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not watch screen of session %q: %w", s.id, err)
+	}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cancel()
+			close(ch)
+		})
+	}, nil
+}
+
+func (s *session) getBuffer(lines *api.LineRange) (*ScreenContents, error) {
+	gbr, err := s.getBufferResponse(lines)
+	if err != nil {
+		return nil, err
+	}
+	contents := &ScreenContents{
+		Lines:     make([]string, 0, len(gbr.GetContents())),
+		FirstLine: int(gbr.GetWindowedCoordRange().GetCoordRange().GetStart().GetY()),
+		Cursor:    CoordFromProto(gbr.GetCursor()),
+	}
+	for _, line := range gbr.GetContents() {
+		contents.Lines = append(contents.Lines, line.GetText())
+	}
+	return contents, nil
+}
+
+// getBufferResponse fetches lines of the session's buffer as iTerm2 sends
+// them, including how code points map to cells.
+func (s *session) getBufferResponse(lines *api.LineRange) (*api.GetBufferResponse, error) {
+	resp, err := s.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_GetBufferRequest{
+			GetBufferRequest: &api.GetBufferRequest{
+				Session:   &s.id,
+				LineRange: lines,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get contents of session %q: %w", s.id, err)
+	}
+	gbr := resp.GetGetBufferResponse()
+	switch status := gbr.GetStatus(); status {
+	case api.GetBufferResponse_OK:
+	case api.GetBufferResponse_SESSION_NOT_FOUND:
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
+		return nil, fmt.Errorf("unexpected status getting contents of session %q: %s", s.id, status)
+	}
+	return gbr, nil
+}
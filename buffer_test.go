@@ -0,0 +1,229 @@
+package iterm2
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// bufferResponse is a canned GetBufferResponse starting at line first
+func bufferResponse(first int64, lines ...string) *api.ServerOriginatedMessage {
+	contents := make([]*api.LineContents, 0, len(lines))
+	for _, l := range lines {
+		contents = append(contents, &api.LineContents{Text: str(l)})
+	}
+	x, y := int32(2), first+int64(len(lines))-1
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_GetBufferResponse{
+			GetBufferResponse: &api.GetBufferResponse{
+				Status:   api.GetBufferResponse_OK.Enum(),
+				Contents: contents,
+				Cursor:   &api.Coord{X: &x, Y: &y},
+				WindowedCoordRange: &api.WindowedCoordRange{
+					CoordRange: GridRange{Start: Coord{Y: int(first)}, End: Coord{Y: int(first) + len(lines)}}.Proto(),
+				},
+			},
+		},
+	}
+}
+
+// TestGetScreenContents verifies the visible screen is requested and its lines returned
+func TestGetScreenContents(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{bufferResponse(100, "$ ls", "a.txt  b.txt", "$ ")},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	got, err := s.GetScreenContents()
+	if err != nil {
+		t.Fatalf("GetScreenContents() error = %v", err)
+	}
+	req := mock.calls[0].GetGetBufferRequest()
+	if req.GetSession() != "sess-1" || !req.GetLineRange().GetScreenContentsOnly() {
+		t.Errorf("unexpected GetBufferRequest %v", req)
+	}
+	if got.String() != "$ ls\na.txt  b.txt\n$ " {
+		t.Errorf("contents = %q", got.String())
+	}
+	if got.FirstLine != 100 || got.Cursor != (Coord{X: 2, Y: 102}) {
+		t.Errorf("FirstLine = %d, Cursor = %v; want 100 and (2, 102)", got.FirstLine, got.Cursor)
+	}
+}
+
+// TestGetScreenContents_SessionGone verifies a closed session reports ErrSessionNotFound
+func TestGetScreenContents_SessionGone(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{{
+			Submessage: &api.ServerOriginatedMessage_GetBufferResponse{
+				GetBufferResponse: &api.GetBufferResponse{Status: api.GetBufferResponse_SESSION_NOT_FOUND.Enum()},
+			},
+		}},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	if _, err := s.GetScreenContents(); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("GetScreenContents() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestSaveScreenContents verifies the requested range and the text written to the file
+func TestSaveScreenContents(t *testing.T) {
+	for _, scrollback := range []bool{false, true} {
+		mock := &mockClient{responses: []*api.ServerOriginatedMessage{bufferResponse(0, "$ echo é", "é")}}
+		s := &session{c: mock, id: "sess-1"}
+		path := filepath.Join(t.TempDir(), "screen.txt")
+
+		if err := s.SaveScreenContents(path, scrollback); err != nil {
+			t.Fatalf("SaveScreenContents(%v) error = %v", scrollback, err)
+		}
+		lines := mock.calls[0].GetGetBufferRequest().GetLineRange()
+		if lines.GetScreenContentsOnly() == scrollback || (lines.GetTrailingLines() > 0) != scrollback {
+			t.Errorf("SaveScreenContents(%v) requested %v", scrollback, lines)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "$ echo é\né\n" {
+			t.Errorf("file contents = %q", data)
+		}
+	}
+}
+
+// TestSaveScreenContents_Errors verifies file errors can be told apart from session errors
+func TestSaveScreenContents_Errors(t *testing.T) {
+	s := &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{bufferResponse(0, "x")}}, id: "sess-1"}
+	var pathErr *os.PathError
+	err := s.SaveScreenContents(filepath.Join(t.TempDir(), "missing", "screen.txt"), false)
+	if !errors.As(err, &pathErr) {
+		t.Errorf("SaveScreenContents() to a missing directory error = %v, want *os.PathError", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "screen.txt")
+	s = &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{{
+		Submessage: &api.ServerOriginatedMessage_GetBufferResponse{
+			GetBufferResponse: &api.GetBufferResponse{Status: api.GetBufferResponse_SESSION_NOT_FOUND.Enum()},
+		},
+	}}}, id: "sess-1"}
+	err = s.SaveScreenContents(path, false)
+	if !errors.Is(err, ErrSessionNotFound) || errors.As(err, &pathErr) {
+		t.Errorf("SaveScreenContents() of a closed session error = %v, want ErrSessionNotFound", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file was created for a failed read: %v", err)
+	}
+}
+
+func screenUpdate(sessionID string) *api.Notification {
+	return &api.Notification{
+		ScreenUpdateNotification: &api.ScreenUpdateNotification{Session: str(sessionID)},
+	}
+}
+
+// TestSubscribeScreenUpdate verifies updates for the session are coalesced and the subscription is cancelled
+func TestSubscribeScreenUpdate(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{notificationOK(), notificationOK()},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	ch, cancel, err := s.SubscribeScreenUpdate()
+	if err != nil {
+		t.Fatalf("SubscribeScreenUpdate() error = %v", err)
+	}
+	req := mock.calls[0].GetNotificationRequest()
+	if req.GetSession() != "sess-1" || req.GetNotificationType() != api.NotificationType_NOTIFY_ON_SCREEN_UPDATE {
+		t.Errorf("unexpected request %v", req)
+	}
+
+	mock.notify(screenUpdate("sess-2"))
+	select {
+	case <-ch:
+		t.Fatal("received an update for another session")
+	default:
+	}
+	// A burst of updates must neither block the caller nor queue up.
+	for i := 0; i < 10; i++ {
+		mock.notify(screenUpdate("sess-1"))
+	}
+	<-ch
+	select {
+	case <-ch:
+		t.Fatal("updates were not coalesced")
+	default:
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if len(mock.calls) != 2 || mock.calls[1].GetNotificationRequest().GetSubscribe() {
+		t.Errorf("expected a single unsubscribe request, got %d calls", len(mock.calls))
+	}
+}
+
+// TestSubscribeScreenUpdate_SessionGone verifies subscribing to a closed session reports ErrSessionNotFound
+func TestSubscribeScreenUpdate_SessionGone(t *testing.T) {
+	s := &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{{
+		Submessage: &api.ServerOriginatedMessage_NotificationResponse{
+			NotificationResponse: &api.NotificationResponse{Status: api.NotificationResponse_SESSION_NOT_FOUND.Enum()},
+		},
+	}}}, id: "sess-1"}
+	if _, _, err := s.SubscribeScreenUpdate(); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("SubscribeScreenUpdate() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestGetContentsSince verifies only finished lines after the token are returned, with wrapped lines joined
+func TestGetContentsSince(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{bufferResponse(100, "$ tail -f log")}}
+	s := &session{c: mock, id: "sess-1"}
+
+	lines, token, err := s.GetContentsSince(Token{})
+	if err != nil {
+		t.Fatalf("GetContentsSince() error = %v", err)
+	}
+	if len(lines) != 0 || token != (Token{line: 100, valid: true}) {
+		t.Fatalf("GetContentsSince(zero) = %q, %+v; want no lines and line 100", lines, token)
+	}
+	if got := mock.calls[0].GetGetBufferRequest().GetLineRange().GetTrailingLines(); got != 1 {
+		t.Errorf("trailing lines = %d, want 1", got)
+	}
+
+	output := bufferResponse(100, "a", "long ", "line", "b", "wrapped ", "")
+	contents := output.GetGetBufferResponse().GetContents()
+	contents[1].Continuation = api.LineContents_CONTINUATION_SOFT_EOL.Enum()
+	contents[4].Continuation = api.LineContents_CONTINUATION_SOFT_EOL.Enum()
+	mock = &mockClient{responses: []*api.ServerOriginatedMessage{bufferResponse(105, "tail"), output}}
+	s = &session{c: mock, id: "sess-1"}
+
+	lines, token, err = s.GetContentsSince(token)
+	if err != nil {
+		t.Fatalf("GetContentsSince() error = %v", err)
+	}
+	want := []string{"a", "long line", "b"}
+	if len(lines) != len(want) || lines[0] != want[0] || lines[1] != want[1] || lines[2] != want[2] {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if token.line != 104 {
+		t.Errorf("next line = %d, want 104 so the line wrapping onto the cursor is read again", token.line)
+	}
+	r := GridRangeFromProto(mock.calls[1].GetGetBufferRequest().GetLineRange().GetWindowedCoordRange().GetCoordRange())
+	if r != (GridRange{Start: Coord{Y: 100}, End: Coord{Y: 105}}) {
+		t.Errorf("requested %v, want lines 100 to 104", r)
+	}
+
+	mock = &mockClient{responses: []*api.ServerOriginatedMessage{bufferResponse(104, "wrapped ")}}
+	s = &session{c: mock, id: "sess-1"}
+	lines, next, err := s.GetContentsSince(token)
+	if err != nil {
+		t.Fatalf("GetContentsSince() error = %v", err)
+	}
+	if len(lines) != 0 || next != token || len(mock.calls) != 1 {
+		t.Errorf("without new lines got %q, %+v after %d calls; want none, the same token, 1 call", lines, next, len(mock.calls))
+	}
+}
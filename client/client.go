@@ -2,15 +2,17 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
+	"log"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Tombar/iterm2/api"
@@ -19,30 +21,96 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// defaultMaxMessageSize caps messages when Options.MaxMessageSize is zero.
+// It leaves plenty of room for the contents of large scrollback buffers.
+const defaultMaxMessageSize = 64 << 20
+
+// ErrConnectionClosed is matched by the errors Call returns once the
+// connection to iTerm2 is gone, whether because Close was called or because
+// the socket failed, typically because iTerm2 quit or restarted. Callers can
+// check for it with errors.Is and reconnect. A Client whose context ended
+// returns the context's error instead.
+var ErrConnectionClosed = errors.New("connection to iTerm2 was closed")
+
+// lostError is the reason a connection failed. It matches both
+// ErrConnectionClosed and the underlying error.
+type lostError struct {
+	err error
+}
+
+func (e *lostError) Error() string {
+	return "connection to iTerm2 lost: " + e.err.Error()
+}
+
+func (e *lostError) Unwrap() error {
+	return e.err
+}
+
+func (e *lostError) Is(target error) bool {
+	return target == ErrConnectionClosed
+}
+
 // New returns a new websocket connection that talks to the iTerm2
 // application.New Callers must call the Close() method when done. The cookie
 // parameter is optional. If provided, it will bypass script authentication
 // prompts.
 func New(appName string) (*Client, error) {
+	return NewContext(context.Background(), appName)
+}
+
+// NewContext is like New, but ties the connection to ctx. Once ctx is done
+// the connection is closed, pending calls are woken up, and every call
+// fails with ctx.Err(). Callers must still call Close to free resources.
+func NewContext(ctx context.Context, appName string) (*Client, error) {
+	return NewWithOptions(ctx, appName, Options{})
+}
+
+// defaultTimeout bounds connecting to iTerm2 when Options.Timeout is zero.
+const defaultTimeout = 45 * time.Second
+
+// Options customizes the connection NewWithOptions makes. The zero value
+// gives the behavior of New.
+type Options struct {
+	// SocketPath is the path of iTerm2's API socket. Empty means the
+	// default, ~/Library/Application Support/iTerm2/private/socket.
+	SocketPath string
+	// Timeout bounds how long connecting to the socket and the
+	// handshake with iTerm2 may take. Zero means 45 seconds.
+	Timeout time.Duration
+	// Logger receives reports of unexpected messages from iTerm2. Nil
+	// means standard error.
+	Logger *log.Logger
+	// MaxMessageSize caps the size in bytes of a single message read
+	// from iTerm2. The limit is checked against the length announced in
+	// each frame header before any payload is buffered, so a corrupt or
+	// hostile length never turns into a huge allocation: the connection
+	// is closed with an error wrapping websocket.ErrReadLimit instead.
+	// Zero means 64 MiB.
+	MaxMessageSize int64
+}
+
+// NewWithOptions is like NewContext, with the connection customized by
+// opts.
+func NewWithOptions(ctx context.Context, appName string, opts Options) (*Client, error) {
 	// ITERM2_COOKIE is an an environment variable that's set on each terminal
 	// session. But it only seems to work the first time, then it gets
 	// invalidated. Therefore, we keep trying until it returns an error, then we
 	// try to generate a new cookie instead. See
 	// https://github.com/marwan-at-work/iterm2/issues/4
 	if cookie := os.Getenv("ITERM2_COOKIE"); cookie != "" {
-		client, err := newClient(appName, cookie)
+		client, err := newClient(ctx, appName, cookie, opts)
 		if err == nil {
 			return client, nil
 		}
 	}
-	client, err := newClient(appName, "")
+	client, err := newClient(ctx, appName, "", opts)
 	if err != nil {
 		return nil, err
 	}
 	return client, err
 }
 
-func newClient(appName, cookie string) (*Client, error) {
+func newClient(ctx context.Context, appName, cookie string, opts Options) (*Client, error) {
 	h := http.Header{}
 	h.Set("origin", "ws://localhost/")
 	h.Set("x-iterm2-library-version", "go 3.6")
@@ -60,18 +128,29 @@ func newClient(appName, cookie string) (*Client, error) {
 		h.Set("x-iterm2-key", fields[1])
 	}
 	h.Set("x-iterm2-cookie", cookie)
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("os.UserHomeDir: %w", err)
+	socketPath := opts.SocketPath
+	if socketPath == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("os.UserHomeDir: %w", err)
+		}
+		socketPath = filepath.Join(homeDir, "/Library/Application Support/iTerm2/private/socket")
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
 	}
 	d := &websocket.Dialer{
-		NetDial: func(network, addr string) (net.Conn, error) {
-			return net.Dial("unix", filepath.Join(homeDir, "/Library/Application Support/iTerm2/private/socket"))
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var nd net.Dialer
+			return nd.DialContext(ctx, "unix", socketPath)
 		},
-		HandshakeTimeout: 45 * time.Second,
+		HandshakeTimeout: timeout,
 		Subprotocols:     []string{"api.iterm2.com"},
 	}
-	c, resp, err := d.Dial("ws://localhost", h)
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	c, resp, err := d.DialContext(dialCtx, "ws://localhost", h)
 	if err != nil && resp != nil {
 		b, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("error connecting to iTerm2: %v - body: %s", err, b)
@@ -79,26 +158,76 @@ func newClient(appName, cookie string) (*Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to iTerm2: %v", err)
 	}
+	cl := newConnClient(ctx, c, opts)
+	cl.protocolVersion = resp.Header.Get("X-iTerm2-Protocol-Version")
+	return cl, nil
+}
+
+// newConnClient starts the workers serving an established connection,
+// using the Logger and MaxMessageSize of opts.
+func newConnClient(parent context.Context, c *websocket.Conn, opts Options) *Client {
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.New(os.Stderr, "", 0)
+	}
 	cl := &Client{
 		c:       c,
-		rpcs:    make(map[int64]chan<- *api.ServerOriginatedMessage),
+		parent:  parent,
+		rpcs:    make(map[int64]chan *api.ServerOriginatedMessage),
 		writeCh: make(chan writeReq),
+		done:    make(chan struct{}),
+		logger:  logger,
+	}
+	cl.maxMessageSize = opts.MaxMessageSize
+	if cl.maxMessageSize <= 0 {
+		cl.maxMessageSize = defaultMaxMessageSize
 	}
-	ctx, cancel := context.WithCancel(context.Background())
+	c.SetReadLimit(cl.maxMessageSize)
+	ctx, cancel := context.WithCancel(parent)
 	cl.cancel = cancel
+	go func() {
+		// Unblock the read worker as soon as ctx is done, whether
+		// because of Close or because the parent context ended.
+		<-ctx.Done()
+		cl.closeConn()
+	}()
+	cl.workers.Add(2)
 	go cl.readWorker(ctx)
 	go cl.writeWorker()
-	return cl, nil
+	return cl
 }
 
 // Client wraps a websocket client connection to iTerm2.
-// Must be instantiated with NewClient.
+// Must be instantiated with NewClient. A Client is safe for concurrent use:
+// Call, AddNotificationHandler and Close may be called from any goroutine.
 type Client struct {
+	// nextID is accessed atomically and must stay first in the struct
+	// to be 64-bit aligned on 32-bit platforms.
+	nextID int64
+
 	c       *websocket.Conn
-	rpcs    map[int64]chan<- *api.ServerOriginatedMessage
+	rpcs    map[int64]chan *api.ServerOriginatedMessage
 	mu      sync.Mutex
+	parent  context.Context
 	cancel  context.CancelFunc
 	writeCh chan writeReq
+	done    chan struct{}
+	err     error
+	workers sync.WaitGroup
+
+	maxMessageSize int64
+	logger         *log.Logger
+	// metrics holds the MetricsFunc set with SetMetrics, if any.
+	metrics atomic.Value
+	// protocolVersion is set before the Client is handed out and never
+	// changes afterwards.
+	protocolVersion string
+
+	closeOnce sync.Once
+	connOnce  sync.Once
+	connErr   error
+
+	subs subscriptionManager
 }
 
 type writeReq struct {
@@ -107,26 +236,50 @@ type writeReq struct {
 }
 
 func (c *Client) writeWorker() {
-	for req := range c.writeCh {
-		err := c.c.WriteMessage(websocket.BinaryMessage, req.msg)
-		req.resp <- err
+	defer c.workers.Done()
+	for {
+		select {
+		case req := <-c.writeCh:
+			req.resp <- c.c.WriteMessage(websocket.BinaryMessage, req.msg)
+		case <-c.done:
+			return
+		}
 	}
 }
 
 func (c *Client) readWorker(ctx context.Context) {
+	defer c.workers.Done()
 	for {
+		// ReadMessage returns only once the whole message has arrived, no
+		// matter how many frames it was sent in or how many reads of the
+		// socket that took, so msg is never a partial response.
 		_, msg, err := c.c.ReadMessage()
 		if ctx.Err() != nil {
+			if err := c.parent.Err(); err != nil {
+				c.shutdown(err)
+			} else {
+				c.shutdown(ErrConnectionClosed)
+			}
+			return
+		}
+		if errors.Is(err, websocket.ErrReadLimit) {
+			c.closeConn()
+			c.shutdown(&lostError{fmt.Errorf("message larger than %d bytes: %w", c.maxMessageSize, err)})
 			return
 		}
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			continue
+			// Read errors are permanent: the connection is gone.
+			c.shutdown(&lostError{err})
+			return
 		}
 		var resp api.ServerOriginatedMessage
 		err = proto.Unmarshal(msg, &resp)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			c.logger.Println(err)
+			continue
+		}
+		if n := resp.GetNotification(); n != nil {
+			c.subs.dispatch(n)
 			continue
 		}
 		c.mu.Lock()
@@ -134,44 +287,174 @@ func (c *Client) readWorker(ctx context.Context) {
 		delete(c.rpcs, resp.GetId())
 		c.mu.Unlock()
 		if !ok {
-			fmt.Fprintf(os.Stderr, "could not find call for %d: %v\n", resp.GetId(), &resp)
+			c.logger.Printf("could not find call for %d: %v", resp.GetId(), &resp)
 			continue
 		}
 		ch <- &resp
 	}
 }
 
-// Call sends a request to the iTerm2 server
+// AddNotificationHandler registers h to be called with every notification
+// iTerm2 sends, and returns a function that unregisters it. Notifications are
+// only sent for subscriptions made with a NotificationRequest.
+//
+// Handlers run on the goroutine reading from the connection: while one is
+// running no responses are processed, so a handler must not call Call or its
+// own remove function.
+func (c *Client) AddNotificationHandler(h func(*api.Notification)) (remove func()) {
+	return c.subs.add(h)
+}
+
+// shutdown records why the connection is gone, wakes up every pending Call
+// and closes Done. It must only be called by readWorker.
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	c.err = err
+	rpcs := c.rpcs
+	c.rpcs = nil
+	c.mu.Unlock()
+	close(c.done)
+	for _, ch := range rpcs {
+		close(ch)
+	}
+}
+
+// ProtocolVersion returns the version of the API protocol iTerm2 announced
+// when the connection was made, such as "1.9", or "" if it did not announce
+// one.
+func (c *Client) ProtocolVersion() string {
+	return c.protocolVersion
+}
+
+// Done returns a channel that is closed once the connection to iTerm2 is
+// gone, either because Close was called or because reading from it failed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection is gone, or nil while Done is still
+// open.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// MetricsFunc receives the measurements of a single Call: the type of the
+// request, such as "GetBufferRequest", how long the call took, and the
+// encoded sizes of the request and of the response, which is 0 if the call
+// failed.
+type MetricsFunc func(reqType string, dur time.Duration, reqBytes, respBytes int)
+
+// SetMetrics makes every Call report its measurements to f once it
+// returns, whether or not it succeeded. f runs on the goroutine that made
+// the call, so it must be safe for concurrent use. Passing nil turns
+// metrics off again; while they are off Call measures nothing.
+func (c *Client) SetMetrics(f MetricsFunc) {
+	c.metrics.Store(f)
+}
+
+// Call sends a request to the iTerm2 server and waits for its response. It
+// is safe to call from several goroutines at once: every request gets a
+// unique id, which iTerm2 echoes back in the response, and the read loop
+// hands each response to the caller waiting for that id.
 func (c *Client) Call(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
-	req.Id = id(rand.Int63())
+	f, _ := c.metrics.Load().(MetricsFunc)
+	if f == nil {
+		return c.call(req)
+	}
+	start := time.Now()
+	resp, err := c.call(req)
+	f(requestType(req), time.Since(start), proto.Size(req), proto.Size(resp))
+	return resp, err
+}
+
+// requestType returns the name of the request message inside req.
+func requestType(req *api.ClientOriginatedMessage) string {
+	m := req.ProtoReflect()
+	fd := m.WhichOneof(m.Descriptor().Oneofs().ByName("submessage"))
+	if fd == nil {
+		return ""
+	}
+	return string(fd.Message().Name())
+}
+
+func (c *Client) call(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+	if err := c.parent.Err(); err != nil {
+		return nil, err
+	}
+	req.Id = id(atomic.AddInt64(&c.nextID, 1))
 	ch := make(chan *api.ServerOriginatedMessage, 1)
 	c.mu.Lock()
+	if c.rpcs == nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
 	c.rpcs[req.GetId()] = ch
 	c.mu.Unlock()
 	msg, err := proto.Marshal(req)
 	if err != nil {
+		c.forget(req.GetId())
 		return nil, err
 	}
 	wr := writeReq{msg: msg, resp: make(chan error, 1)}
-	c.writeCh <- wr
+	select {
+	case c.writeCh <- wr:
+	case <-c.done:
+		return nil, c.Err()
+	}
 	err = <-wr.resp
 	if err != nil {
-		return nil, fmt.Errorf("error writing to websocket: %w", err)
+		// A failed write leaves the websocket unusable. Tear it down so
+		// that Done is closed by the time the caller sees the error.
+		c.forget(req.GetId())
+		c.closeConn()
+		<-c.done
+		return nil, &lostError{fmt.Errorf("error writing to websocket: %w", err)}
+	}
+	resp, ok := <-ch
+	if !ok {
+		return nil, c.Err()
 	}
-	resp := <-ch
 	if resp.GetError() != "" {
 		return nil, fmt.Errorf("error from server: %v", resp.GetError())
 	}
 	return resp, nil
 }
 
+func (c *Client) forget(id int64) {
+	c.mu.Lock()
+	delete(c.rpcs, id)
+	c.mu.Unlock()
+}
+
 // Close closes the websocket connection
-// and frees any goroutine resources
+// and frees any goroutine resources. Calls in flight return an error
+// instead of waiting for their response. Close is safe to call more than
+// once and from several goroutines; every call returns the error from
+// closing the socket, if any.
 func (c *Client) Close() error {
-	// TODO: if a *Client.Call is in flight, this will cause it to panic
-	close(c.writeCh)
-	c.cancel()
-	return c.c.Close()
+	c.closeOnce.Do(func() {
+		// Say goodbye so iTerm2 doesn't log an abnormal closure. This
+		// fails harmlessly if the connection is already gone.
+		c.c.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+		c.cancel()
+		c.closeConn()
+		c.workers.Wait()
+	})
+	return c.closeConn()
+}
+
+// closeConn closes the underlying socket exactly once and remembers the
+// error.
+func (c *Client) closeConn() error {
+	c.connOnce.Do(func() {
+		c.connErr = c.c.Close()
+	})
+	return c.connErr
 }
 
 func id(i int64) *int64 {
@@ -0,0 +1,532 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tombar/iterm2/api"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// serve starts a fake iTerm2 that answers each request with the response
+// returned by handle, or not at all when handle returns nil
+func serve(t *testing.T, handle func(*api.ClientOriginatedMessage) *api.ServerOriginatedMessage) *websocket.Conn {
+	t.Helper()
+	return serveWithDialer(t, websocket.DefaultDialer, handle)
+}
+
+// serveWithDialer is serve with the connection to the fake iTerm2 made by d
+func serveWithDialer(t *testing.T, d *websocket.Dialer, handle func(*api.ClientOriginatedMessage) *api.ServerOriginatedMessage) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req api.ClientOriginatedMessage
+			if err := proto.Unmarshal(msg, &req); err != nil {
+				return
+			}
+			resp := handle(&req)
+			if resp == nil {
+				continue
+			}
+			resp.Id = req.Id
+			data, _ := proto.Marshal(resp)
+			if err := ws.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	ws, _, err := d.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("could not dial test server: %v", err)
+	}
+	return ws
+}
+
+// chunkedConn is a net.Conn that returns at most n bytes from each Read
+type chunkedConn struct {
+	net.Conn
+	n int
+}
+
+func (c chunkedConn) Read(p []byte) (int, error) {
+	if len(p) > c.n {
+		p = p[:c.n]
+	}
+	return c.Conn.Read(p)
+}
+
+func listSessions() *api.ClientOriginatedMessage {
+	return &api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ListSessionsRequest{ListSessionsRequest: &api.ListSessionsRequest{}},
+	}
+}
+
+// TestCall verifies responses are matched to their requests
+func TestCall(t *testing.T) {
+	ws := serve(t, func(req *api.ClientOriginatedMessage) *api.ServerOriginatedMessage {
+		return &api.ServerOriginatedMessage{
+			Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{ListSessionsResponse: &api.ListSessionsResponse{}},
+		}
+	})
+	c := newConnClient(context.Background(), ws, Options{})
+	defer c.Close()
+
+	resp, err := c.Call(listSessions())
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if resp.GetListSessionsResponse() == nil {
+		t.Errorf("unexpected response %v", resp)
+	}
+}
+
+// TestCall_ChunkedReads verifies a large response is reassembled when the socket delivers it a few bytes at a time
+func TestCall_ChunkedReads(t *testing.T) {
+	var lines []*api.LineContents
+	for i := 0; i < 2000; i++ {
+		lines = append(lines, &api.LineContents{Text: proto.String(strings.Repeat(strconv.Itoa(i%10), 100))})
+	}
+	d := &websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			c, err := net.Dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return chunkedConn{Conn: c, n: 7}, nil
+		},
+	}
+	ws := serveWithDialer(t, d, func(req *api.ClientOriginatedMessage) *api.ServerOriginatedMessage {
+		return &api.ServerOriginatedMessage{
+			Submessage: &api.ServerOriginatedMessage_GetBufferResponse{
+				GetBufferResponse: &api.GetBufferResponse{Status: api.GetBufferResponse_OK.Enum(), Contents: lines},
+			},
+		}
+	})
+	c := newConnClient(context.Background(), ws, Options{})
+	defer c.Close()
+
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_GetBufferRequest{GetBufferRequest: &api.GetBufferRequest{}},
+	})
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	got := resp.GetGetBufferResponse().GetContents()
+	if len(got) != len(lines) {
+		t.Fatalf("got %d lines, want %d", len(got), len(lines))
+	}
+	for i := range got {
+		if got[i].GetText() != lines[i].GetText() {
+			t.Fatalf("line %d = %q, want %q", i, got[i].GetText(), lines[i].GetText())
+		}
+	}
+}
+
+// TestSetMetrics verifies every Call is reported with its request type and sizes until metrics are turned off
+func TestSetMetrics(t *testing.T) {
+	ws := serve(t, func(req *api.ClientOriginatedMessage) *api.ServerOriginatedMessage {
+		return &api.ServerOriginatedMessage{
+			Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{ListSessionsResponse: &api.ListSessionsResponse{}},
+		}
+	})
+	c := newConnClient(context.Background(), ws, Options{})
+	defer c.Close()
+
+	type call struct {
+		reqType             string
+		reqBytes, respBytes int
+	}
+	var got []call
+	c.SetMetrics(func(reqType string, dur time.Duration, reqBytes, respBytes int) {
+		if dur <= 0 {
+			t.Errorf("duration = %v, want > 0", dur)
+		}
+		got = append(got, call{reqType, reqBytes, respBytes})
+	})
+	req := listSessions()
+	resp, err := c.Call(req)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	want := call{"ListSessionsRequest", proto.Size(req), proto.Size(resp)}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("metrics = %+v, want [%+v]", got, want)
+	}
+
+	c.SetMetrics(nil)
+	if _, err := c.Call(listSessions()); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("metrics reported %d calls after SetMetrics(nil), want 1", len(got))
+	}
+}
+
+// TestNewWithOptions verifies the socket path is honored and unexpected messages reach the logger
+func TestNewWithOptions(t *testing.T) {
+	// Not t.TempDir: its paths can exceed the length limit of socket
+	// addresses on macOS.
+	dir, err := os.MkdirTemp("", "iterm2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "socket")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("could not listen on %s: %v", socket, err)
+	}
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req api.ClientOriginatedMessage
+			if err := proto.Unmarshal(msg, &req); err != nil {
+				return
+			}
+			// An answer to nobody, then the real one.
+			stray, _ := proto.Marshal(&api.ServerOriginatedMessage{Id: id(-1)})
+			ws.WriteMessage(websocket.BinaryMessage, stray)
+			resp, _ := proto.Marshal(&api.ServerOriginatedMessage{
+				Id:         req.Id,
+				Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{ListSessionsResponse: &api.ListSessionsResponse{}},
+			})
+			ws.WriteMessage(websocket.BinaryMessage, resp)
+		}
+	}))
+	srv.Listener = l
+	srv.Start()
+	defer srv.Close()
+
+	t.Setenv("ITERM2_COOKIE", "cookie")
+	var logs bytes.Buffer
+	c, err := NewWithOptions(context.Background(), "test", Options{
+		SocketPath: socket,
+		Timeout:    5 * time.Second,
+		Logger:     log.New(&logs, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("NewWithOptions() error = %v", err)
+	}
+	if _, err := c.Call(listSessions()); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	c.Close()
+	if !strings.Contains(logs.String(), "could not find call for -1") {
+		t.Errorf("logged %q, want the stray response", logs.String())
+	}
+}
+
+// TestContextCancel verifies cancelling the context wakes up pending calls and fails later ones
+func TestContextCancel(t *testing.T) {
+	received := make(chan struct{}, 1)
+	ws := serve(t, func(*api.ClientOriginatedMessage) *api.ServerOriginatedMessage {
+		received <- struct{}{}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	c := newConnClient(ctx, ws, Options{})
+	defer c.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Call(listSessions())
+		errc <- err
+	}()
+	<-received
+	cancel()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("pending Call() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending Call() did not return after cancel")
+	}
+	if _, err := c.Call(listSessions()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Call() after cancel error = %v, want context.Canceled", err)
+	}
+	select {
+	case <-c.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done() not closed after cancel")
+	}
+}
+
+// TestClose verifies Close wakes up pending calls, fails later ones and can be called repeatedly
+func TestClose(t *testing.T) {
+	received := make(chan struct{}, 1)
+	ws := serve(t, func(*api.ClientOriginatedMessage) *api.ServerOriginatedMessage {
+		received <- struct{}{}
+		return nil
+	})
+	c := newConnClient(context.Background(), ws, Options{})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := c.Call(listSessions())
+		errc <- err
+	}()
+	<-received
+
+	closed := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { closed <- c.Close() }()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close() did not return")
+		}
+	}
+	if err := <-errc; !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("pending Call() error = %v, want ErrConnectionClosed", err)
+	}
+	if _, err := c.Call(listSessions()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Call() after Close error = %v, want ErrConnectionClosed", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("repeated Close() error = %v", err)
+	}
+}
+
+// TestConnectionLost verifies calls on a connection iTerm2 dropped fail with ErrConnectionClosed
+func TestConnectionLost(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		ws.ReadMessage()
+		ws.UnderlyingConn().Close()
+	}))
+	defer srv.Close()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("could not dial test server: %v", err)
+	}
+	c := newConnClient(context.Background(), ws, Options{})
+	defer c.Close()
+
+	if _, err := c.Call(listSessions()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Call() error = %v, want ErrConnectionClosed", err)
+	}
+	<-c.Done()
+	if _, err := c.Call(listSessions()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Call() after the connection was lost error = %v, want ErrConnectionClosed", err)
+	}
+}
+
+// TestMaxMessageSize verifies a frame announcing an oversized payload closes the connection with a clear error
+func TestMaxMessageSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		frame []byte
+	}{
+		{
+			// A final binary frame whose 64-bit length claims 1 TiB,
+			// with no payload following it.
+			name:  "default limit",
+			frame: []byte{0x82, 127, 0, 0, 1, 0, 0, 0, 0, 0},
+		},
+		{
+			// A final binary frame whose 16-bit length claims 4 KiB.
+			name:  "custom limit",
+			opts:  Options{MaxMessageSize: 1024},
+			frame: []byte{0x82, 126, 0x10, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upgrader := websocket.Upgrader{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ws, err := upgrader.Upgrade(w, r, nil)
+				if err != nil {
+					return
+				}
+				defer ws.Close()
+				if _, _, err := ws.ReadMessage(); err != nil {
+					return
+				}
+				ws.UnderlyingConn().Write(tt.frame)
+				ws.ReadMessage()
+			}))
+			defer srv.Close()
+			ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+			if err != nil {
+				t.Fatalf("could not dial test server: %v", err)
+			}
+			c := newConnClient(context.Background(), ws, tt.opts)
+			defer c.Close()
+
+			_, err = c.Call(listSessions())
+			if !errors.Is(err, websocket.ErrReadLimit) || !errors.Is(err, ErrConnectionClosed) {
+				t.Errorf("Call() error = %v, want websocket.ErrReadLimit and ErrConnectionClosed", err)
+			}
+			select {
+			case <-c.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("Done() not closed after oversized message")
+			}
+		})
+	}
+}
+
+// TestCall_Concurrent verifies parallel calls each get their own response even when iTerm2 answers out of order
+func TestCall_Concurrent(t *testing.T) {
+	const n = 50
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		// Collect every request, then answer them in reverse order.
+		var reqs []*api.ClientOriginatedMessage
+		for len(reqs) < n {
+			_, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req api.ClientOriginatedMessage
+			if err := proto.Unmarshal(msg, &req); err != nil {
+				return
+			}
+			reqs = append(reqs, &req)
+		}
+		for i := len(reqs) - 1; i >= 0; i-- {
+			// Echo the session id so each caller can tell whether
+			// it got its own response.
+			resp := &api.ServerOriginatedMessage{
+				Id: reqs[i].Id,
+				Submessage: &api.ServerOriginatedMessage_VariableResponse{
+					VariableResponse: &api.VariableResponse{Values: []string{reqs[i].GetVariableRequest().GetSessionId()}},
+				},
+			}
+			data, _ := proto.Marshal(resp)
+			if err := ws.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+		}
+		ws.ReadMessage()
+	}))
+	defer srv.Close()
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("could not dial test server: %v", err)
+	}
+	c := newConnClient(context.Background(), ws, Options{})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			want := strconv.Itoa(i)
+			resp, err := c.Call(&api.ClientOriginatedMessage{
+				Submessage: &api.ClientOriginatedMessage_VariableRequest{
+					VariableRequest: &api.VariableRequest{
+						Scope: &api.VariableRequest_SessionId{SessionId: want},
+						Get:   []string{"name"},
+					},
+				},
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := resp.GetVariableResponse().GetValues(); len(got) != 1 || got[0] != want {
+				errs <- fmt.Errorf("call %s got response %v", want, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// TestClient_Race hammers one client from several goroutines at once; run it with -race
+func TestClient_Race(t *testing.T) {
+	ws := serve(t, func(req *api.ClientOriginatedMessage) *api.ServerOriginatedMessage {
+		return &api.ServerOriginatedMessage{
+			Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{ListSessionsResponse: &api.ListSessionsResponse{}},
+		}
+	})
+	c := newConnClient(context.Background(), ws, Options{})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, err := c.Call(listSessions()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				remove := c.AddNotificationHandler(func(*api.Notification) {})
+				remove()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Close()
+		}()
+	}
+	wg.Wait()
+}
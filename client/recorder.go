@@ -0,0 +1,59 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Recorder is a dry-run client: instead of sending requests to iTerm2 it
+// writes each of them to w as a single line of JSON, in the protobuf JSON
+// mapping. It is meant for debugging and tests, to see exactly what a
+// program would ask iTerm2 to do.
+//
+// Since nothing is sent, every Call succeeds with an empty response, which
+// the library reads as a successful status without any data. Use it with
+// iterm2.NewAppWithClient. A Recorder is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	nextID int64
+	closed bool
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// Call records req and returns an empty response. Requests are numbered
+// the way a connection to iTerm2 would number them.
+func (r *Recorder) Call(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, ErrConnectionClosed
+	}
+	r.nextID++
+	req.Id = id(r.nextID)
+	data, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode request: %w", err)
+	}
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("could not record request: %w", err)
+	}
+	return &api.ServerOriginatedMessage{Id: req.Id}, nil
+}
+
+// Close makes later calls fail with ErrConnectionClosed. It does not close
+// the writer, and is safe to call more than once.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
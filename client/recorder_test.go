@@ -0,0 +1,51 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TestRecorder verifies every request is written as one numbered JSON line and answered with an empty response
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRecorder(&buf)
+	for i := 0; i < 2; i++ {
+		resp, err := r.Call(listSessions())
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+		if resp.GetId() != int64(i+1) || resp.GetSubmessage() != nil {
+			t.Errorf("response = %v, want an empty response with id %d", resp, i+1)
+		}
+	}
+
+	var got []*api.ClientOriginatedMessage
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var req api.ClientOriginatedMessage
+		if err := protojson.Unmarshal(sc.Bytes(), &req); err != nil {
+			t.Fatalf("line %q is not a request: %v", sc.Text(), err)
+		}
+		got = append(got, &req)
+	}
+	if len(got) != 2 {
+		t.Fatalf("recorded %d requests, want 2", len(got))
+	}
+	for i, req := range got {
+		if req.GetId() != int64(i+1) || req.GetListSessionsRequest() == nil {
+			t.Errorf("request %d = %v, want ListSessionsRequest with id %d", i, req, i+1)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := r.Call(listSessions()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Call() after Close error = %v, want ErrConnectionClosed", err)
+	}
+}
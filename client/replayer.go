@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Replayer is a client that answers calls with pre-recorded responses
+// instead of talking to iTerm2, so code using the library can be tested
+// deterministically. The responses are returned in order, one per Call,
+// whatever the request; a response with its error field set makes the
+// call fail the way an error from iTerm2 would. Use it with
+// iterm2.NewAppWithClient. A Replayer is safe for concurrent use.
+type Replayer struct {
+	mu        sync.Mutex
+	responses []*api.ServerOriginatedMessage
+	calls     int
+	closed    bool
+}
+
+// NewReplayer reads the responses to replay from r: one
+// ServerOriginatedMessage per line, in the protobuf JSON mapping, the format
+// Recorder uses for requests. Blank lines are skipped.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	rp := &Replayer{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, defaultMaxMessageSize)
+	for line := 1; sc.Scan(); line++ {
+		data := bytes.TrimSpace(sc.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		resp := &api.ServerOriginatedMessage{}
+		if err := protojson.Unmarshal(data, resp); err != nil {
+			return nil, fmt.Errorf("could not decode response on line %d: %w", line, err)
+		}
+		rp.responses = append(rp.responses, resp)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("could not read responses: %w", err)
+	}
+	return rp, nil
+}
+
+// Call returns the next recorded response, with the id of req. It fails
+// once every response has been used.
+func (rp *Replayer) Call(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.closed {
+		return nil, ErrConnectionClosed
+	}
+	rp.calls++
+	if len(rp.responses) == 0 {
+		return nil, fmt.Errorf("no recorded response left for call %d (%s)", rp.calls, requestType(req))
+	}
+	resp := proto.Clone(rp.responses[0]).(*api.ServerOriginatedMessage)
+	rp.responses = rp.responses[1:]
+	resp.Id = req.Id
+	if resp.GetError() != "" {
+		return nil, fmt.Errorf("error from server: %v", resp.GetError())
+	}
+	return resp, nil
+}
+
+// Remaining returns the number of recorded responses no call has used yet,
+// so tests can check that the code under test made every expected call.
+func (rp *Replayer) Remaining() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return len(rp.responses)
+}
+
+// Close makes later calls fail with ErrConnectionClosed. It is safe to call
+// more than once.
+func (rp *Replayer) Close() error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.closed = true
+	return nil
+}
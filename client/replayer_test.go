@@ -0,0 +1,48 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestReplayer verifies responses are replayed in order with the caller's id and running out is an error
+func TestReplayer(t *testing.T) {
+	fixture := `{"listSessionsResponse": {"windows": [{"windowId": "win-1"}]}}
+
+{"error": "boom"}
+`
+	rp, err := NewReplayer(strings.NewReader(fixture))
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	if rp.Remaining() != 2 {
+		t.Fatalf("Remaining() = %d, want 2", rp.Remaining())
+	}
+
+	req := listSessions()
+	req.Id = id(7)
+	resp, err := rp.Call(req)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if resp.GetId() != 7 || resp.GetListSessionsResponse().GetWindows()[0].GetWindowId() != "win-1" {
+		t.Errorf("response = %v, want the first recorded one with id 7", resp)
+	}
+	if _, err := rp.Call(listSessions()); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Call() error = %v, want the recorded server error", err)
+	}
+	if _, err := rp.Call(listSessions()); err == nil {
+		t.Error("Call() past the last response expected error, got nil")
+	}
+	if rp.Remaining() != 0 {
+		t.Errorf("Remaining() = %d, want 0", rp.Remaining())
+	}
+}
+
+// TestNewReplayer_Malformed verifies a bad fixture is reported with its line number
+func TestNewReplayer_Malformed(t *testing.T) {
+	_, err := NewReplayer(strings.NewReader("{}\n{\"noSuchField\": 1}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("NewReplayer() error = %v, want a decode error on line 2", err)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// subscriptionManager is the registry of a Client's notification handlers.
+// Handlers may be added and removed from any goroutine, including while
+// notifications are being dispatched.
+type subscriptionManager struct {
+	// mu guards handlers. dispatch holds the read lock while handlers
+	// run, so once a remove function returns its handler is never called
+	// again.
+	mu       sync.RWMutex
+	handlers map[int]func(*api.Notification)
+	next     int
+}
+
+// add registers h and returns a function that unregisters it. The function
+// is safe to call more than once, but not from within a handler: it waits
+// for dispatch to finish.
+func (m *subscriptionManager) add(h func(*api.Notification)) (remove func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.handlers == nil {
+		m.handlers = make(map[int]func(*api.Notification))
+	}
+	id := m.next
+	m.next++
+	m.handlers[id] = h
+	return func() {
+		m.mu.Lock()
+		delete(m.handlers, id)
+		m.mu.Unlock()
+	}
+}
+
+// dispatch calls every registered handler with n.
+func (m *subscriptionManager) dispatch(n *api.Notification) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, h := range m.handlers {
+		h(n)
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestSubscriptionManager verifies notifications reach registered handlers until they are removed
+func TestSubscriptionManager(t *testing.T) {
+	var m subscriptionManager
+	var a, b int
+	removeA := m.add(func(*api.Notification) { a++ })
+	m.add(func(*api.Notification) { b++ })
+
+	m.dispatch(&api.Notification{})
+	removeA()
+	removeA()
+	m.dispatch(&api.Notification{})
+
+	if a != 1 || b != 2 {
+		t.Errorf("handlers called %d and %d times, want 1 and 2", a, b)
+	}
+}
+
+// TestSubscriptionManager_Race verifies concurrent add, remove and dispatch are safe and removed handlers stay silent
+func TestSubscriptionManager_Race(t *testing.T) {
+	var m subscriptionManager
+	stop := make(chan struct{})
+	var dispatchers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		dispatchers.Add(1)
+		go func() {
+			defer dispatchers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					m.dispatch(&api.Notification{})
+				}
+			}
+		}()
+	}
+
+	var subscribers sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		subscribers.Add(1)
+		go func() {
+			defer subscribers.Done()
+			var removed int32
+			remove := m.add(func(*api.Notification) {
+				if atomic.LoadInt32(&removed) == 1 {
+					t.Error("handler called after its remove function returned")
+				}
+			})
+			remove()
+			atomic.StoreInt32(&removed, 1)
+		}()
+	}
+	subscribers.Wait()
+	close(stop)
+	dispatchers.Wait()
+
+	if len(m.handlers) != 0 {
+		t.Errorf("%d handlers left after every remove", len(m.handlers))
+	}
+}
@@ -0,0 +1,55 @@
+package iterm2
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// The clipboard is the system's, shared by iTerm2 with every other app, and
+// iTerm2's API has no request for it, so it is accessed with the pbcopy and
+// pbpaste tools.
+var (
+	writeClipboard = func(text string) error {
+		cmd := clipboardCommand("pbcopy")
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+	readClipboard = func() (string, error) {
+		out, err := clipboardCommand("pbpaste").Output()
+		return string(out), err
+	}
+)
+
+// clipboardCommand returns a command running the clipboard tool name. The
+// tools convert text using the locale, so UTF-8 is asked for explicitly:
+// programs started by launchd or cron often have no locale set, and
+// non-ASCII text would be mangled.
+func clipboardCommand(name string) *exec.Cmd {
+	cmd := exec.Command(name)
+	cmd.Env = append(os.Environ(), "LC_CTYPE=UTF-8")
+	return cmd
+}
+
+// SetClipboard puts text on the clipboard, for the user to paste into any
+// session or app. Going through the system clipboard rather than the
+// terminal, it does not trigger iTerm2's prompt for programs that access
+// the clipboard with escape sequences.
+func (a *app) SetClipboard(text string) error {
+	if err := writeClipboard(text); err != nil {
+		return fmt.Errorf("could not set clipboard: %w", err)
+	}
+	return nil
+}
+
+// GetClipboard returns the text on the clipboard, or "" if it holds none.
+// Recent versions of macOS may ask the user to allow the program to read
+// the clipboard.
+func (a *app) GetClipboard() (string, error) {
+	text, err := readClipboard()
+	if err != nil {
+		return "", fmt.Errorf("could not read clipboard: %w", err)
+	}
+	return text, nil
+}
@@ -0,0 +1,48 @@
+package iterm2
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestClipboard verifies text round-trips through the clipboard tools and their failures are reported
+func TestClipboard(t *testing.T) {
+	defer func(w func(string) error, r func() (string, error)) {
+		writeClipboard, readClipboard = w, r
+	}(writeClipboard, readClipboard)
+
+	var clipboard string
+	writeClipboard = func(text string) error { clipboard = text; return nil }
+	readClipboard = func() (string, error) { return clipboard, nil }
+
+	a := &app{c: &mockClient{}}
+	if err := a.SetClipboard("🚀 line one\nline two"); err != nil {
+		t.Fatalf("SetClipboard() error = %v", err)
+	}
+	if got, err := a.GetClipboard(); err != nil || got != "🚀 line one\nline two" {
+		t.Errorf("GetClipboard() = %q, %v", got, err)
+	}
+
+	errBroken := errors.New("exit status 1")
+	writeClipboard = func(string) error { return errBroken }
+	readClipboard = func() (string, error) { return "", errBroken }
+	if err := a.SetClipboard("x"); !errors.Is(err, errBroken) {
+		t.Errorf("SetClipboard() error = %v, want %v", err, errBroken)
+	}
+	if _, err := a.GetClipboard(); !errors.Is(err, errBroken) {
+		t.Errorf("GetClipboard() error = %v, want %v", err, errBroken)
+	}
+}
+
+// TestClipboardCommand verifies the clipboard tools are run with a UTF-8 locale
+func TestClipboardCommand(t *testing.T) {
+	t.Setenv("LC_CTYPE", "C")
+	cmd := clipboardCommand("pbpaste")
+	if filepath.Base(cmd.Path) != "pbpaste" {
+		t.Errorf("command = %q, want pbpaste", cmd.Path)
+	}
+	if got := cmd.Env[len(cmd.Env)-1]; got != "LC_CTYPE=UTF-8" {
+		t.Errorf("last environment variable = %q, want LC_CTYPE=UTF-8 overriding the inherited locale", got)
+	}
+}
@@ -0,0 +1,120 @@
+package iterm2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Color is an sRGB color with 8 bits per channel.
+type Color struct {
+	R, G, B uint8
+}
+
+// namedColors are the colors ParseColor accepts by name: the sixteen basic
+// CSS colors plus orange.
+var namedColors = map[string]Color{
+	"black":   {0, 0, 0},
+	"silver":  {192, 192, 192},
+	"gray":    {128, 128, 128},
+	"grey":    {128, 128, 128},
+	"white":   {255, 255, 255},
+	"maroon":  {128, 0, 0},
+	"red":     {255, 0, 0},
+	"purple":  {128, 0, 128},
+	"fuchsia": {255, 0, 255},
+	"magenta": {255, 0, 255},
+	"green":   {0, 128, 0},
+	"lime":    {0, 255, 0},
+	"olive":   {128, 128, 0},
+	"yellow":  {255, 255, 0},
+	"navy":    {0, 0, 128},
+	"blue":    {0, 0, 255},
+	"teal":    {0, 128, 128},
+	"aqua":    {0, 255, 255},
+	"cyan":    {0, 255, 255},
+	"orange":  {255, 165, 0},
+}
+
+// ParseColor parses a color written in one of the forms CSS uses:
+//
+//	#f80, #ff8800        hex, with 1 or 2 digits per channel
+//	#f80c, #ff8800cc     hex with an alpha channel
+//	rgb(255, 136, 0)     decimal channels from 0 to 255
+//	rgba(255, 136, 0, 0.8)
+//	orange               one of the basic CSS color names
+//
+// Case and surrounding whitespace are ignored. Color has no alpha channel,
+// so an alpha value is checked but then dropped.
+func ParseColor(s string) (Color, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	var c Color
+	var ok bool
+	switch {
+	case strings.HasPrefix(t, "#"):
+		c, ok = parseHexColor(t[1:])
+	case strings.HasPrefix(t, "rgb(") && strings.HasSuffix(t, ")"):
+		c, ok = parseRGBColor(t[len("rgb("):len(t)-1], false)
+	case strings.HasPrefix(t, "rgba(") && strings.HasSuffix(t, ")"):
+		c, ok = parseRGBColor(t[len("rgba("):len(t)-1], true)
+	default:
+		c, ok = namedColors[t]
+	}
+	if !ok {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	return c, nil
+}
+
+// parseHexColor parses the digits of a hex color, with or without alpha.
+func parseHexColor(digits string) (Color, bool) {
+	var width int
+	switch len(digits) {
+	case 3, 4:
+		width = 1
+	case 6, 8:
+		width = 2
+	default:
+		return Color{}, false
+	}
+	var channels [4]uint8
+	for i := 0; i < len(digits)/width; i++ {
+		v, err := strconv.ParseUint(digits[i*width:(i+1)*width], 16, 8)
+		if err != nil {
+			return Color{}, false
+		}
+		if width == 1 {
+			v *= 0x11
+		}
+		channels[i] = uint8(v)
+	}
+	return Color{R: channels[0], G: channels[1], B: channels[2]}, true
+}
+
+// parseRGBColor parses the comma-separated arguments of rgb() or, with
+// alpha, rgba().
+func parseRGBColor(args string, alpha bool) (Color, bool) {
+	fields := strings.Split(args, ",")
+	want := 3
+	if alpha {
+		want = 4
+	}
+	if len(fields) != want {
+		return Color{}, false
+	}
+	var channels [3]uint8
+	for i := range channels {
+		v, err := strconv.ParseUint(strings.TrimSpace(fields[i]), 10, 8)
+		if err != nil {
+			return Color{}, false
+		}
+		channels[i] = uint8(v)
+	}
+	if alpha {
+		a, err := strconv.ParseFloat(strings.TrimSpace(fields[3]), 64)
+		if err != nil || !(a >= 0 && a <= 1) {
+			return Color{}, false
+		}
+	}
+	return Color{R: channels[0], G: channels[1], B: channels[2]}, true
+}
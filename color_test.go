@@ -0,0 +1,56 @@
+package iterm2
+
+import "testing"
+
+// TestParseColor verifies the hex, rgb() and named forms are parsed and malformed colors rejected
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Color
+		wantErr bool
+	}{
+		{in: "#ff8800", want: Color{255, 136, 0}},
+		{in: "#FF8800", want: Color{255, 136, 0}},
+		{in: "#f80", want: Color{255, 136, 0}},
+		{in: "#F80", want: Color{255, 136, 0}},
+		{in: "#ff8800cc", want: Color{255, 136, 0}},
+		{in: "#f80c", want: Color{255, 136, 0}},
+		{in: "  #000000\n", want: Color{0, 0, 0}},
+		{in: "rgb(255, 136, 0)", want: Color{255, 136, 0}},
+		{in: "RGB( 1,2 ,3 )", want: Color{1, 2, 3}},
+		{in: "rgba(255, 136, 0, 0.5)", want: Color{255, 136, 0}},
+		{in: "orange", want: Color{255, 165, 0}},
+		{in: " Navy ", want: Color{0, 0, 128}},
+		{in: "", wantErr: true},
+		{in: "#", wantErr: true},
+		{in: "#ff88", want: Color{255, 255, 136}},
+		{in: "#ff880", wantErr: true},
+		{in: "#gg8800", wantErr: true},
+		{in: "#+f+f+f", wantErr: true},
+		{in: "ff8800", wantErr: true},
+		{in: "rgb(256, 0, 0)", wantErr: true},
+		{in: "rgb(-1, 0, 0)", wantErr: true},
+		{in: "rgb(1, 2)", wantErr: true},
+		{in: "rgb(1, 2, 3, 0.5)", wantErr: true},
+		{in: "rgba(1, 2, 3)", wantErr: true},
+		{in: "rgba(1, 2, 3, 1.5)", wantErr: true},
+		{in: "rgb(1, 2, 3", wantErr: true},
+		{in: "chartreuse", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseColor(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseColor(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
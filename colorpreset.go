@@ -0,0 +1,200 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// builtinColorPresets are used when iTerm2 does not know a preset by the
+// requested name, for example because the user deleted it.
+var builtinColorPresets = map[string]map[string]Color{
+	"Solarized Dark":  solarized(true),
+	"Solarized Light": solarized(false),
+	"Dracula": ansiColors([16]Color{
+		{0x21, 0x22, 0x2c}, {0xff, 0x55, 0x55}, {0x50, 0xfa, 0x7b}, {0xf1, 0xfa, 0x8c},
+		{0xbd, 0x93, 0xf9}, {0xff, 0x79, 0xc6}, {0x8b, 0xe9, 0xfd}, {0xf8, 0xf8, 0xf2},
+		{0x62, 0x72, 0xa4}, {0xff, 0x6e, 0x6e}, {0x69, 0xff, 0x94}, {0xff, 0xff, 0xa5},
+		{0xd6, 0xac, 0xff}, {0xff, 0x92, 0xdf}, {0xa4, 0xff, 0xff}, {0xff, 0xff, 0xff},
+	}, map[string]Color{
+		"Background Color":    {0x28, 0x2a, 0x36},
+		"Foreground Color":    {0xf8, 0xf8, 0xf2},
+		"Bold Color":          {0xff, 0xff, 0xff},
+		"Cursor Color":        {0xf8, 0xf8, 0xf2},
+		"Cursor Text Color":   {0x28, 0x2a, 0x36},
+		"Selection Color":     {0x44, 0x47, 0x5a},
+		"Selected Text Color": {0xf8, 0xf8, 0xf2},
+	}),
+}
+
+// solarized returns Ethan Schoonover's Solarized palette in its dark or
+// light variant.
+func solarized(dark bool) map[string]Color {
+	var (
+		base03  = Color{0x00, 0x2b, 0x36}
+		base02  = Color{0x07, 0x36, 0x42}
+		base01  = Color{0x58, 0x6e, 0x75}
+		base00  = Color{0x65, 0x7b, 0x83}
+		base0   = Color{0x83, 0x94, 0x96}
+		base1   = Color{0x93, 0xa1, 0xa1}
+		base2   = Color{0xee, 0xe8, 0xd5}
+		base3   = Color{0xfd, 0xf6, 0xe3}
+		yellow  = Color{0xb5, 0x89, 0x00}
+		orange  = Color{0xcb, 0x4b, 0x16}
+		red     = Color{0xdc, 0x32, 0x2f}
+		magenta = Color{0xd3, 0x36, 0x82}
+		violet  = Color{0x6c, 0x71, 0xc4}
+		blue    = Color{0x26, 0x8b, 0xd2}
+		cyan    = Color{0x2a, 0xa1, 0x98}
+		green   = Color{0x85, 0x99, 0x00}
+	)
+	ansi := [16]Color{
+		base02, red, green, yellow, blue, magenta, cyan, base2,
+		base03, orange, base01, base00, base0, violet, base1, base3,
+	}
+	if dark {
+		return ansiColors(ansi, map[string]Color{
+			"Background Color":    base03,
+			"Foreground Color":    base0,
+			"Bold Color":          base1,
+			"Cursor Color":        base1,
+			"Cursor Text Color":   base03,
+			"Selection Color":     base02,
+			"Selected Text Color": base1,
+		})
+	}
+	return ansiColors(ansi, map[string]Color{
+		"Background Color":    base3,
+		"Foreground Color":    base00,
+		"Bold Color":          base01,
+		"Cursor Color":        base00,
+		"Cursor Text Color":   base3,
+		"Selection Color":     base2,
+		"Selected Text Color": base01,
+	})
+}
+
+// ansiColors adds the 16 ANSI colors to the profile keys in other.
+func ansiColors(ansi [16]Color, other map[string]Color) map[string]Color {
+	for i, c := range ansi {
+		other[fmt.Sprintf("Ansi %d Color", i)] = c
+	}
+	return other
+}
+
+// colorValue is the JSON encoding of a color in a profile.
+func colorValue(r, g, b, alpha float64, colorSpace string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"Red Component":   r,
+		"Green Component": g,
+		"Blue Component":  b,
+		"Alpha Component": alpha,
+		"Color Space":     colorSpace,
+	})
+	return string(data)
+}
+
+// ApplyColorPreset sets all colors of the session's profile from the named
+// color preset, such as "Solarized Dark". Presets known to iTerm2, including
+// ones the user imported, are used as is. Otherwise the library falls back
+// to its own copies of "Solarized Dark", "Solarized Light" and "Dracula".
+// Other names return an error matching ErrColorPresetNotFound.
+//
+// Like the other profile setters, this only changes the session, not the
+// profile it was created from.
+func (s *session) ApplyColorPreset(name string) error {
+	assignments, err := getColorPreset(s.c, name)
+	if errors.Is(err, ErrColorPresetNotFound) {
+		colors, ok := builtinColorPresets[name]
+		if !ok {
+			return fmt.Errorf("could not apply color preset %q to session %q: %w", name, s.id, err)
+		}
+		keys := make([]string, 0, len(colors))
+		for key := range colors {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		assignments = nil
+		for _, key := range keys {
+			c := colors[key]
+			assignments = append(assignments, &api.SetProfilePropertyRequest_Assignment{
+				Key:       str(key),
+				JsonValue: str(colorValue(float64(c.R)/255, float64(c.G)/255, float64(c.B)/255, 1, "sRGB")),
+			})
+		}
+	} else if err != nil {
+		return fmt.Errorf("could not apply color preset %q to session %q: %w", name, s.id, err)
+	}
+	if err := setProfileProperties(s.c, s.id, assignments...); err != nil {
+		return fmt.Errorf("could not apply color preset %q to session %q: %w", name, s.id, err)
+	}
+	return nil
+}
+
+// ListColorPresets returns the names of the color presets iTerm2 knows
+// about, built-in and imported alike, in the order iTerm2 lists them. Any of
+// them can be passed to Session.ApplyColorPreset.
+func (a *app) ListColorPresets() ([]string, error) {
+	resp, err := a.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ColorPresetRequest{
+			ColorPresetRequest: &api.ColorPresetRequest{
+				Request: &api.ColorPresetRequest_ListPresets_{
+					ListPresets: &api.ColorPresetRequest_ListPresets{},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list color presets: %w", err)
+	}
+	cpr := resp.GetColorPresetResponse()
+	if status := cpr.GetStatus(); status != api.ColorPresetResponse_OK {
+		return nil, fmt.Errorf("unexpected status listing color presets: %s", status)
+	}
+	names := cpr.GetListPresets().GetName()
+	if names == nil {
+		names = []string{}
+	}
+	return names, nil
+}
+
+// getColorPreset asks iTerm2 for the colors of the named preset and returns
+// them as profile property assignments.
+func getColorPreset(c ClientInterface, name string) ([]*api.SetProfilePropertyRequest_Assignment, error) {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ColorPresetRequest{
+			ColorPresetRequest: &api.ColorPresetRequest{
+				Request: &api.ColorPresetRequest_GetPreset_{
+					GetPreset: &api.ColorPresetRequest_GetPreset{Name: &name},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get color preset %q: %w", name, err)
+	}
+	cpr := resp.GetColorPresetResponse()
+	switch status := cpr.GetStatus(); status {
+	case api.ColorPresetResponse_OK:
+	case api.ColorPresetResponse_PRESET_NOT_FOUND:
+		return nil, fmt.Errorf("%w: %q", ErrColorPresetNotFound, name)
+	default:
+		return nil, fmt.Errorf("unexpected status getting color preset %q: %s", name, status)
+	}
+	var assignments []*api.SetProfilePropertyRequest_Assignment
+	for _, s := range cpr.GetGetPreset().GetColorSettings() {
+		space := s.GetColorSpace()
+		if space == "" {
+			space = "sRGB"
+		}
+		assignments = append(assignments, &api.SetProfilePropertyRequest_Assignment{
+			Key: str(s.GetKey()),
+			JsonValue: str(colorValue(float64(s.GetRed()), float64(s.GetGreen()), float64(s.GetBlue()),
+				float64(s.GetAlpha()), space)),
+		})
+	}
+	return assignments, nil
+}
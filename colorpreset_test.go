@@ -0,0 +1,153 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+func colorPresetResponse(status api.ColorPresetResponse_Status, settings ...*api.ColorPresetResponse_GetPreset_ColorSetting) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ColorPresetResponse{
+			ColorPresetResponse: &api.ColorPresetResponse{
+				Status: status.Enum(),
+				Response: &api.ColorPresetResponse_GetPreset_{
+					GetPreset: &api.ColorPresetResponse_GetPreset{ColorSettings: settings},
+				},
+			},
+		},
+	}
+}
+
+func setProfilePropertyOK() *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_SetProfilePropertyResponse{
+			SetProfilePropertyResponse: &api.SetProfilePropertyResponse{Status: api.SetProfilePropertyResponse_OK.Enum()},
+		},
+	}
+}
+
+// TestApplyColorPreset verifies iTerm2's presets are preferred and the built-in ones used as a fallback
+func TestApplyColorPreset(t *testing.T) {
+	f := func(v float32) *float32 { return &v }
+	tests := []struct {
+		name      string
+		preset    string
+		responses []*api.ServerOriginatedMessage
+		wantKeys  int
+		wantKey   string
+		wantRed   float64
+	}{
+		{
+			name:   "from iTerm2",
+			preset: "Tango Dark",
+			responses: []*api.ServerOriginatedMessage{
+				colorPresetResponse(api.ColorPresetResponse_OK, &api.ColorPresetResponse_GetPreset_ColorSetting{
+					Key: str("Background Color"), Red: f(0.5), Green: f(0), Blue: f(0), Alpha: f(1), ColorSpace: str("P3"),
+				}),
+				setProfilePropertyOK(),
+			},
+			wantKeys: 1,
+			wantKey:  "Background Color",
+			wantRed:  0.5,
+		},
+		{
+			name:   "built-in",
+			preset: "Solarized Dark",
+			responses: []*api.ServerOriginatedMessage{
+				colorPresetResponse(api.ColorPresetResponse_PRESET_NOT_FOUND),
+				setProfilePropertyOK(),
+			},
+			wantKeys: 23,
+			wantKey:  "Ansi 0 Color",
+			wantRed:  7.0 / 255,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: tt.responses}
+			s := &session{c: mock, id: "sess-1"}
+			if err := s.ApplyColorPreset(tt.preset); err != nil {
+				t.Fatalf("ApplyColorPreset() error = %v", err)
+			}
+			if got := mock.calls[0].GetColorPresetRequest().GetGetPreset().GetName(); got != tt.preset {
+				t.Errorf("requested preset %q, want %q", got, tt.preset)
+			}
+			assignments := mock.calls[1].GetSetProfilePropertyRequest().GetAssignments()
+			if len(assignments) != tt.wantKeys {
+				t.Fatalf("got %d assignments, want %d", len(assignments), tt.wantKeys)
+			}
+			if assignments[0].GetKey() != tt.wantKey {
+				t.Fatalf("first key = %q, want %q", assignments[0].GetKey(), tt.wantKey)
+			}
+			var color map[string]interface{}
+			if err := json.Unmarshal([]byte(assignments[0].GetJsonValue()), &color); err != nil {
+				t.Fatalf("color is not JSON: %v", err)
+			}
+			if color["Red Component"] != tt.wantRed {
+				t.Errorf("Red Component = %v, want %v", color["Red Component"], tt.wantRed)
+			}
+		})
+	}
+}
+
+// TestApplyColorPreset_Unknown verifies unknown presets are rejected without changing the profile
+func TestApplyColorPreset_Unknown(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		colorPresetResponse(api.ColorPresetResponse_PRESET_NOT_FOUND),
+	}}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.ApplyColorPreset("Nope"); !errors.Is(err, ErrColorPresetNotFound) {
+		t.Errorf("ApplyColorPreset() error = %v, want ErrColorPresetNotFound", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("expected 1 Call, got %d", len(mock.calls))
+	}
+}
+
+// TestListColorPresets verifies preset names are returned as listed and an empty list is not nil
+func TestListColorPresets(t *testing.T) {
+	list := func(names ...string) *api.ServerOriginatedMessage {
+		return &api.ServerOriginatedMessage{
+			Submessage: &api.ServerOriginatedMessage_ColorPresetResponse{
+				ColorPresetResponse: &api.ColorPresetResponse{
+					Status: api.ColorPresetResponse_OK.Enum(),
+					Response: &api.ColorPresetResponse_ListPresets_{
+						ListPresets: &api.ColorPresetResponse_ListPresets{Name: names},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		resp *api.ServerOriginatedMessage
+		want []string
+	}{
+		{"presets", list("Tango Dark", "Solarized Dark"), []string{"Tango Dark", "Solarized Dark"}},
+		{"none", list(), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{tt.resp}}
+			a := &app{c: mock}
+			got, err := a.ListColorPresets()
+			if err != nil {
+				t.Fatalf("ListColorPresets() error = %v", err)
+			}
+			if got == nil || len(got) != len(tt.want) {
+				t.Fatalf("ListColorPresets() = %#v, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ListColorPresets()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+			if mock.calls[0].GetColorPresetRequest().GetListPresets() == nil {
+				t.Errorf("expected a ListPresets request, got %v", mock.calls[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package iterm2
+
+import (
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// Coord addresses a cell on a session's screen. X is the column and Y the
+// line, both zero-based. Lines are numbered from the start of the scrollback
+// history, and the numbering stays stable when old history is discarded.
+type Coord struct {
+	X, Y int
+}
+
+// String returns the coordinate as "(x, y)".
+func (c Coord) String() string {
+	return fmt.Sprintf("(%d, %d)", c.X, c.Y)
+}
+
+// Proto converts c to the API's coordinate message.
+func (c Coord) Proto() *api.Coord {
+	x, y := int32(c.X), int64(c.Y)
+	return &api.Coord{X: &x, Y: &y}
+}
+
+// CoordFromProto converts an API coordinate message to a Coord. A nil
+// message yields the zero Coord.
+func CoordFromProto(c *api.Coord) Coord {
+	return Coord{X: int(c.GetX()), Y: int(c.GetY())}
+}
+
+// GridRange is a range of cells running from Start up to, but not
+// including, End in reading order.
+type GridRange struct {
+	Start, End Coord
+}
+
+// String returns the range as "(x, y)-(x, y)".
+func (r GridRange) String() string {
+	return r.Start.String() + "-" + r.End.String()
+}
+
+// Proto converts r to the API's coordinate range message.
+func (r GridRange) Proto() *api.CoordRange {
+	return &api.CoordRange{Start: r.Start.Proto(), End: r.End.Proto()}
+}
+
+// GridRangeFromProto converts an API coordinate range message to a
+// GridRange. A nil message yields the zero GridRange.
+func GridRangeFromProto(r *api.CoordRange) GridRange {
+	return GridRange{Start: CoordFromProto(r.GetStart()), End: CoordFromProto(r.GetEnd())}
+}
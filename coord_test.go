@@ -0,0 +1,41 @@
+package iterm2
+
+import (
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestGridRangeProto verifies ranges survive a round trip through the API messages
+func TestGridRangeProto(t *testing.T) {
+	tests := []struct {
+		name string
+		r    GridRange
+	}{
+		{name: "zero", r: GridRange{}},
+		{name: "single line", r: GridRange{Start: Coord{X: 2, Y: 10}, End: Coord{X: 8, Y: 10}}},
+		{name: "deep scrollback", r: GridRange{Start: Coord{X: 0, Y: 1 << 33}, End: Coord{X: 80, Y: 1<<33 + 24}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.r.Proto()
+			if msg.GetStart().GetX() != int32(tt.r.Start.X) || msg.GetEnd().GetY() != int64(tt.r.End.Y) {
+				t.Errorf("Proto() = %v, want %v", msg, tt.r)
+			}
+			if got := GridRangeFromProto(msg); got != tt.r {
+				t.Errorf("GridRangeFromProto() = %v, want %v", got, tt.r)
+			}
+		})
+	}
+}
+
+// TestCoordFromProto_Nil verifies missing messages convert to the zero value
+func TestCoordFromProto_Nil(t *testing.T) {
+	if got := CoordFromProto(nil); got != (Coord{}) {
+		t.Errorf("CoordFromProto(nil) = %v, want zero", got)
+	}
+	if got := GridRangeFromProto(&api.CoordRange{End: Coord{X: 1, Y: 2}.Proto()}); got != (GridRange{End: Coord{X: 1, Y: 2}}) {
+		t.Errorf("GridRangeFromProto() = %v", got)
+	}
+}
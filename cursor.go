@@ -0,0 +1,43 @@
+package iterm2
+
+import "fmt"
+
+// CursorShape is the shape of a session's cursor. The values are the ones
+// iTerm2 stores in the "Cursor Type" profile key.
+type CursorShape int
+
+// Cursor shapes.
+const (
+	CursorUnderline   CursorShape = 0
+	CursorVerticalBar CursorShape = 1
+	CursorBox         CursorShape = 2
+)
+
+// String returns the name of the shape.
+func (c CursorShape) String() string {
+	switch c {
+	case CursorUnderline:
+		return "underline"
+	case CursorVerticalBar:
+		return "vertical bar"
+	case CursorBox:
+		return "box"
+	default:
+		return fmt.Sprintf("CursorShape(%d)", int(c))
+	}
+}
+
+// SetCursorShape changes the shape of the session's cursor.
+func (s *session) SetCursorShape(shape CursorShape) error {
+	switch shape {
+	case CursorUnderline, CursorVerticalBar, CursorBox:
+	default:
+		return fmt.Errorf("could not set cursor of session %q: invalid shape %d", s.id, int(shape))
+	}
+	return s.setProfileProperty("Cursor Type", int(shape))
+}
+
+// SetCursorBlink turns blinking of the session's cursor on or off.
+func (s *session) SetCursorBlink(enabled bool) error {
+	return s.setProfileProperty("Blinking Cursor", enabled)
+}
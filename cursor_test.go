@@ -0,0 +1,52 @@
+package iterm2
+
+import "testing"
+
+// TestSetCursorShape verifies shapes are written as iTerm2's Cursor Type values and unknown ones rejected
+func TestSetCursorShape(t *testing.T) {
+	tests := []struct {
+		shape   CursorShape
+		want    string
+		wantErr bool
+	}{
+		{shape: CursorUnderline, want: "0"},
+		{shape: CursorVerticalBar, want: "1"},
+		{shape: CursorBox, want: "2"},
+		{shape: CursorShape(3), wantErr: true},
+		{shape: CursorShape(-1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+
+		err := s.SetCursorShape(tt.shape)
+		if tt.wantErr {
+			if err == nil || len(mock.calls) != 0 {
+				t.Errorf("SetCursorShape(%v) expected error without Calls, got %v and %d calls", tt.shape, err, len(mock.calls))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("SetCursorShape(%v) error = %v", tt.shape, err)
+		}
+		a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+		if len(a) != 1 || a[0].GetKey() != "Cursor Type" || a[0].GetJsonValue() != tt.want {
+			t.Errorf("SetCursorShape(%v) assignments = %v, want Cursor Type=%s", tt.shape, a, tt.want)
+		}
+	}
+}
+
+// TestSetCursorBlink verifies the Blinking Cursor key is set
+func TestSetCursorBlink(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetCursorBlink(false); err != nil {
+		t.Fatalf("SetCursorBlink() error = %v", err)
+	}
+	a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+	if len(a) != 1 || a[0].GetKey() != "Blinking Cursor" || a[0].GetJsonValue() != "false" {
+		t.Errorf("assignments = %v, want Blinking Cursor=false", a)
+	}
+}
@@ -0,0 +1,65 @@
+package iterm2
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The default App backs the package-level convenience functions. It
+// connects on first use and is shared for the lifetime of the process.
+var (
+	defaultMu      sync.Mutex
+	defaultAppName = filepath.Base(os.Args[0])
+	defaultApp     *app
+)
+
+// SetDefaultAppName sets the name the default App registers with iTerm2,
+// which defaults to the name of the running program. It only has an effect
+// before the default App connects, that is before the first call to
+// DefaultApp or a package-level convenience function.
+func SetDefaultAppName(name string) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultAppName = name
+}
+
+// DefaultApp returns the App shared by the package-level convenience
+// functions, connecting to iTerm2 on first use. If connecting fails, the
+// next call tries again. The App must not be closed while the package-level
+// functions are still in use.
+func DefaultApp() (App, error) {
+	a, err := getDefaultApp()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func getDefaultApp() (*app, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultApp == nil {
+		a, err := NewApp(defaultAppName)
+		if err != nil {
+			return nil, err
+		}
+		defaultApp = a.(*app)
+	}
+	return defaultApp, nil
+}
+
+// SendToCurrentSession sends text to the session that has keyboard focus in
+// iTerm2, using the default App. As with Session.SendText, end text with
+// "\r" to run it as a command.
+func SendToCurrentSession(text string) error {
+	a, err := getDefaultApp()
+	if err != nil {
+		return err
+	}
+	s, err := a.currentSession()
+	if err != nil {
+		return err
+	}
+	return s.SendText(text)
+}
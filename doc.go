@@ -0,0 +1,37 @@
+// Package iterm2 automates iTerm2 through its WebSocket API, the same API
+// used by iTerm2's Python scripts.
+//
+// Connect with NewApp, then navigate from the App to its windows, tabs and
+// sessions. Window, Tab and Session values are lightweight handles holding
+// little more than an id; they stay valid for as long as iTerm2 keeps the
+// object they refer to, and report ErrWindowNotFound, ErrTabNotFound or
+// ErrSessionNotFound once it is gone.
+//
+// # Profiles
+//
+// Setters that change how a session looks or behaves, such as
+// SetTransparency, SetCursorShape or ApplyColorPreset, write keys of the
+// session's profile. They never modify the profile in iTerm2's settings:
+// the first change "divorces" the session, giving it a private copy of its
+// profile, and that and all later changes only affect the copy. Other
+// sessions created from the same profile are left alone, and new sessions
+// still start from the unchanged profile. Session.IsProfileDivorced reports
+// whether a session has such a copy.
+//
+// # Concurrency
+//
+// An App and every Window, Tab and Session obtained from it are safe for
+// concurrent use by multiple goroutines. All of them share the App's single
+// connection: requests are written to it one at a time, and each response
+// is matched to the call that is waiting for it by request id, so calls from
+// different goroutines may be in flight at the same time and complete in any
+// order.
+//
+// iTerm2 itself applies requests in the order it receives them. When the
+// order of two calls matters, make them from the same goroutine.
+//
+// Closing the App makes calls in flight return an error, and Close may be
+// called more than once. Subscriptions deliver their events on channels
+// that must be drained; the returned cancel functions are safe to call from
+// any goroutine.
+package iterm2
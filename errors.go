@@ -26,6 +26,105 @@ var (
 	ErrPermissionDenied = errors.New("iTerm2 permission denied for this application")
 )
 
+// Sentinel errors for objects iTerm2 no longer knows about, typically because
+// the user closed them. Callers keeping track of windows, tabs or sessions can
+// check for them with errors.Is() and drop their handle.
+var (
+	// ErrWindowNotFound indicates the window does not exist (anymore).
+	ErrWindowNotFound = errors.New("iTerm2 window not found")
+
+	// ErrTabNotFound indicates the tab does not exist (anymore).
+	ErrTabNotFound = errors.New("iTerm2 tab not found")
+
+	// ErrSessionNotFound indicates the session does not exist (anymore).
+	ErrSessionNotFound = errors.New("iTerm2 session not found")
+)
+
+// Sentinel errors for requests to create a window or tab that iTerm2 turned
+// down.
+var (
+	// ErrProfileNotFound indicates there is no profile with the requested
+	// name.
+	ErrProfileNotFound = errors.New("iTerm2 profile not found")
+
+	// ErrMissingSubstitution indicates the profile's command refers to a
+	// $$VARIABLE$$ no value was provided for.
+	ErrMissingSubstitution = errors.New("missing value for profile command substitution")
+
+	// ErrInvalidTabIndex indicates the requested tab position does not
+	// exist. iTerm2 creates the tab at the end of the window regardless;
+	// it is closed again before the error is returned.
+	ErrInvalidTabIndex = errors.New("invalid tab index")
+)
+
+// Sentinel errors for asking what has focus when there is nothing to
+// focus. Callers can respond by creating a window.
+var (
+	// ErrNoWindows indicates no terminal window is open.
+	ErrNoWindows = errors.New("no iTerm2 window is open")
+
+	// ErrNoSessions indicates the current window has no active session.
+	ErrNoSessions = errors.New("no active iTerm2 session")
+)
+
+// ErrWaitTimeout is matched by the *WaitTimeoutError returned when a
+// session does not show the expected output in time.
+var ErrWaitTimeout = errors.New("timed out waiting for screen contents")
+
+// ErrShellIntegrationUnavailable is returned for information only iTerm2's
+// shell integration provides, when it is not installed in the session's
+// shell. See https://iterm2.com/documentation-shell-integration.html.
+var ErrShellIntegrationUnavailable = errors.New("shell integration is not available")
+
+// ErrNoScrollback is returned when scrolling a session whose lines all fit
+// on its screen.
+var ErrNoScrollback = errors.New("session has no scrollback")
+
+// ErrNotificationsDisabled is returned by PostNotification when the profile
+// of the current session has notifications turned off.
+var ErrNotificationsDisabled = errors.New("notifications are disabled")
+
+// ErrUserDeclined is returned when the user turned down a confirmation
+// dialog iTerm2 showed for the request.
+var ErrUserDeclined = errors.New("declined by the user")
+
+// ErrCannotSplit is returned when a session is too small to be split any
+// further.
+var ErrCannotSplit = errors.New("session is too small to split")
+
+// ErrSessionInSplitTab is returned by MoveSession for a session that shares
+// its tab with other panes: iTerm2 can only move whole tabs between windows.
+var ErrSessionInSplitTab = errors.New("session shares its tab with other panes")
+
+// ErrColorPresetNotFound is returned for color preset names that neither
+// iTerm2 nor the library knows.
+var ErrColorPresetNotFound = errors.New("color preset not found")
+
+// ErrInvalidImage is returned for image files that are not PNG or JPEG
+// images, or that are damaged.
+var ErrInvalidImage = errors.New("invalid image")
+
+// ErrMalformedResponse is returned when iTerm2 answers with data the
+// library cannot make sense of. Functions that can still return partial
+// results document so.
+var ErrMalformedResponse = errors.New("malformed response from iTerm2")
+
+// ErrUnsupportedByServer is returned when the running version of iTerm2 does
+// not offer what was asked for through its API.
+var ErrUnsupportedByServer = errors.New("not supported by this version of iTerm2")
+
+// ErrConnectionClosed is matched by errors from calls made after the
+// connection to iTerm2 is gone, for example because iTerm2 quit or the App
+// was closed. Long-lived programs can check for it and connect again, or use
+// NewReconnectingApp.
+var ErrConnectionClosed = client.ErrConnectionClosed
+
+// ErrStaleReference is returned by Apps created with NewReconnectingApp when
+// the first call on a fresh connection addresses a window, tab or session
+// iTerm2 no longer knows. Ids do not survive an iTerm2 restart,
+// so handles obtained before the reconnect must be looked up again.
+var ErrStaleReference = errors.New("iTerm2 object reference is stale after reconnect")
+
 // CheckPrerequisites verifies that iTerm2 is running and the Python API is enabled.
 // It does NOT check permissions (use RequestPermission for that).
 //
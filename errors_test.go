@@ -3,8 +3,11 @@ package iterm2
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/Tombar/iterm2/api"
 )
 
 // verifySocketPath is a test helper that verifies a socket path has the correct format.
@@ -215,3 +218,156 @@ func TestEnhanceConnectionError_UnknownError(t *testing.T) {
 		t.Errorf("enhanceConnectionError() incorrectly wrapped unknown error")
 	}
 }
+
+// TestNotFoundErrors verifies iTerm2 statuses for missing targets map to the not-found sentinels
+func TestNotFoundErrors(t *testing.T) {
+	emptyList := &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ListSessionsResponse{
+			ListSessionsResponse: &api.ListSessionsResponse{},
+		},
+	}
+	tests := []struct {
+		name     string
+		response *api.ServerOriginatedMessage
+		call     func(c ClientInterface) error
+		want     error
+	}{
+		{
+			name: "send text to closed session",
+			response: &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_SendTextResponse{
+					SendTextResponse: &api.SendTextResponse{Status: api.SendTextResponse_SESSION_NOT_FOUND.Enum()},
+				},
+			},
+			call: func(c ClientInterface) error { return (&session{c: c, id: "sess-1"}).SendText("ls") },
+			want: ErrSessionNotFound,
+		},
+		{
+			name: "activate closed session",
+			response: &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_ActivateResponse{
+					ActivateResponse: &api.ActivateResponse{Status: api.ActivateResponse_BAD_IDENTIFIER.Enum()},
+				},
+			},
+			call: func(c ClientInterface) error { return (&session{c: c, id: "sess-1"}).Activate(true, true) },
+			want: ErrSessionNotFound,
+		},
+		{
+			name: "split closed session",
+			response: &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_SplitPaneResponse{
+					SplitPaneResponse: &api.SplitPaneResponse{Status: api.SplitPaneResponse_SESSION_NOT_FOUND.Enum()},
+				},
+			},
+			call: func(c ClientInterface) error {
+				_, err := (&session{c: c, id: "sess-1"}).SplitPane(SplitPaneOptions{})
+				return err
+			},
+			want: ErrSessionNotFound,
+		},
+		{
+			name: "close closed tab",
+			response: &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_CloseResponse{
+					CloseResponse: &api.CloseResponse{Statuses: []api.CloseResponse_Status{api.CloseResponse_NOT_FOUND}},
+				},
+			},
+			call: func(c ClientInterface) error { return (&tab{c: c, id: "tab-1"}).Close() },
+			want: ErrTabNotFound,
+		},
+		{
+			name:     "list sessions of closed tab",
+			response: emptyList,
+			call: func(c ClientInterface) error {
+				_, err := (&tab{c: c, id: "tab-1", windowID: "win-1"}).ListSessions()
+				return err
+			},
+			want: ErrTabNotFound,
+		},
+		{
+			name:     "list tabs of closed window",
+			response: emptyList,
+			call: func(c ClientInterface) error {
+				_, err := (&window{c: c, id: "win-1"}).ListTabs()
+				return err
+			},
+			want: ErrWindowNotFound,
+		},
+		{
+			name: "create tab in closed window",
+			response: &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_CreateTabResponse{
+					CreateTabResponse: &api.CreateTabResponse{Status: api.CreateTabResponse_INVALID_WINDOW_ID.Enum()},
+				},
+			},
+			call: func(c ClientInterface) error {
+				_, err := (&window{c: c, id: "win-1"}).CreateTab()
+				return err
+			},
+			want: ErrWindowNotFound,
+		},
+		{
+			name: "activate closed window",
+			response: &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_ActivateResponse{
+					ActivateResponse: &api.ActivateResponse{Status: api.ActivateResponse_BAD_IDENTIFIER.Enum()},
+				},
+			},
+			call: func(c ClientInterface) error { return (&window{c: c, id: "win-1"}).Activate() },
+			want: ErrWindowNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{tt.response}}
+			err := tt.call(mock)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestErrorsNameObject verifies every method of a window, tab or session
+// names the object in its errors, whether iTerm2 fails or the arguments are
+// rejected
+func TestErrorsNameObject(t *testing.T) {
+	fail := &mockClient{callFunc: func(*api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		return nil, errors.New("connection lost")
+	}}
+	objects := []struct {
+		id  string
+		obj interface{}
+	}{
+		{"sess-1", &session{c: fail, id: "sess-1"}},
+		{"tab-1", &tab{c: fail, id: "tab-1"}},
+		{"win-1", &window{c: fail, id: "win-1"}},
+	}
+	for _, o := range objects {
+		v := reflect.ValueOf(o.obj)
+		for i := 0; i < v.NumMethod(); i++ {
+			name := v.Type().Method(i).Name
+			fn := v.Method(i)
+			t.Run(o.id+"."+name, func(t *testing.T) {
+				// Every argument is its zero value: empty strings, nil
+				// handles and out-of-range numbers.
+				args := make([]reflect.Value, fn.Type().NumIn())
+				for j := range args {
+					args[j] = reflect.Zero(fn.Type().In(j))
+				}
+				var out []reflect.Value
+				if fn.Type().IsVariadic() {
+					out = fn.CallSlice(args)
+				} else {
+					out = fn.Call(args)
+				}
+				for _, r := range out {
+					if err, ok := r.Interface().(error); ok && err != nil && !strings.Contains(err.Error(), o.id) {
+						t.Errorf("%s() error = %q, want it to name %q", name, err, o.id)
+					}
+				}
+			})
+		}
+	}
+}
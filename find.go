@@ -0,0 +1,92 @@
+package iterm2
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// FindOptions for customizing how FindText matches.
+type FindOptions struct {
+	// IgnoreCase matches letters regardless of case.
+	IgnoreCase bool
+	// Regexp treats the search string as a regular expression in the
+	// syntax of the regexp package rather than as literal text.
+	Regexp bool
+}
+
+// FindText searches the session's scrollback history and screen for substr
+// and returns the cells of every match, in reading order. Matches do not
+// overlap and do not continue from one line to the next, even where a long
+// line was wrapped. Columns count cells, so a wide character takes two and a
+// letter with combining marks one; a match ends where the cell of the next
+// code point starts.
+func (s *session) FindText(substr string, opts FindOptions) ([]GridRange, error) {
+	if substr == "" {
+		return nil, fmt.Errorf("could not search session %q: empty search string", s.id)
+	}
+	pattern := substr
+	if !opts.Regexp {
+		pattern = regexp.QuoteMeta(substr)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("could not search session %q: %w", s.id, err)
+	}
+	gbr, err := s.getBufferResponse(&api.LineRange{TrailingLines: proto.Int32(math.MaxInt32)})
+	if err != nil {
+		return nil, err
+	}
+	first := int(gbr.GetWindowedCoordRange().GetCoordRange().GetStart().GetY())
+	matches := []GridRange{}
+	for i, line := range gbr.GetContents() {
+		text := line.GetText()
+		cells := codePointCells(line)
+		for _, m := range re.FindAllStringIndex(text, -1) {
+			if m[0] == m[1] {
+				continue
+			}
+			start := utf8.RuneCountInString(text[:m[0]])
+			end := start + utf8.RuneCountInString(text[m[0]:m[1]])
+			matches = append(matches, GridRange{
+				Start: Coord{X: cellOf(cells, start), Y: first + i},
+				End:   Coord{X: cellOf(cells, end), Y: first + i},
+			})
+		}
+	}
+	return matches, nil
+}
+
+// codePointCells returns the column of the cell holding each code point of
+// the line's text, followed by the column just past the line's last cell.
+func codePointCells(line *api.LineContents) []int {
+	var cells []int
+	col := 0
+	for _, run := range line.GetCodePointsPerCell() {
+		for r := 0; r < int(run.GetRepeats()); r++ {
+			for n := 0; n < int(run.GetNumCodePoints()); n++ {
+				cells = append(cells, col)
+			}
+			col++
+		}
+	}
+	return append(cells, col)
+}
+
+// cellOf returns the column of the code point at index i. Code points that
+// cells does not account for, as when iTerm2 sent no mapping, are taken to
+// take one cell each.
+func cellOf(cells []int, i int) int {
+	last := len(cells) - 1
+	if i < last {
+		return cells[i]
+	}
+	return cells[last] + i - last
+}
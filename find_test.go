@@ -0,0 +1,96 @@
+package iterm2
+
+import (
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestFindText verifies matches are reported per line in cells, honoring the search options
+func TestFindText(t *testing.T) {
+	tests := []struct {
+		name   string
+		substr string
+		opts   FindOptions
+		want   []GridRange
+	}{
+		{
+			name:   "literal",
+			substr: "error",
+			want:   []GridRange{{Coord{8, 51}, Coord{13, 51}}},
+		},
+		{
+			name:   "ignore case",
+			substr: "error",
+			opts:   FindOptions{IgnoreCase: true},
+			want:   []GridRange{{Coord{0, 50}, Coord{5, 50}}, {Coord{8, 51}, Coord{13, 51}}},
+		},
+		{
+			name:   "literal is not a pattern",
+			substr: "a.c",
+			want:   []GridRange{},
+		},
+		{
+			name:   "regexp",
+			substr: `\d+ms`,
+			opts:   FindOptions{Regexp: true},
+			want:   []GridRange{{Coord{14, 51}, Coord{19, 51}}},
+		},
+		{
+			name:   "combining marks and wide characters",
+			substr: "cafe\u0301 日本",
+			want:   []GridRange{{Coord{0, 52}, Coord{9, 52}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := bufferResponse(50, "ERROR: disk full", "request error 250ms", "cafe\u0301 日本!")
+			// The combining accent shares the e's cell and each ideograph
+			// takes a cell with its code point and an empty one after it.
+			resp.GetGetBufferResponse().GetContents()[2].CodePointsPerCell = []*api.CodePointsPerCell{
+				{NumCodePoints: proto.Int32(1), Repeats: proto.Int32(3)},
+				{NumCodePoints: proto.Int32(2), Repeats: proto.Int32(1)},
+				{NumCodePoints: proto.Int32(1), Repeats: proto.Int32(1)},
+				{NumCodePoints: proto.Int32(1), Repeats: proto.Int32(1)},
+				{NumCodePoints: proto.Int32(0), Repeats: proto.Int32(1)},
+				{NumCodePoints: proto.Int32(1), Repeats: proto.Int32(1)},
+				{NumCodePoints: proto.Int32(0), Repeats: proto.Int32(1)},
+				{NumCodePoints: proto.Int32(1), Repeats: proto.Int32(1)},
+			}
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{resp}}
+			s := &session{c: mock, id: "sess-1"}
+
+			got, err := s.FindText(tt.substr, tt.opts)
+			if err != nil {
+				t.Fatalf("FindText() error = %v", err)
+			}
+			if got == nil || len(got) != len(tt.want) {
+				t.Fatalf("FindText() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("match %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+			if mock.calls[0].GetGetBufferRequest().GetLineRange().GetScreenContentsOnly() {
+				t.Error("expected the scrollback history to be searched too")
+			}
+		})
+	}
+}
+
+// TestFindText_Invalid verifies bad search strings are rejected before calling iTerm2
+func TestFindText_Invalid(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	if _, err := s.FindText("", FindOptions{}); err == nil {
+		t.Error("FindText() of an empty string expected error, got nil")
+	}
+	if _, err := s.FindText("(", FindOptions{Regexp: true}); err == nil {
+		t.Error("FindText() of an invalid regexp expected error, got nil")
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected no Calls, got %d", len(mock.calls))
+	}
+}
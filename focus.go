@@ -0,0 +1,278 @@
+package iterm2
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// focus is iTerm2's view of what has keyboard focus.
+type focus struct {
+	// window is the key terminal window, or the current one when some
+	// other kind of window such as Preferences is key. It is empty when
+	// no terminal window is open.
+	window string
+	// selectedTabs holds the selected tab of every window.
+	selectedTabs map[string]bool
+	// activeSessions holds the active session of every tab.
+	activeSessions map[string]bool
+}
+
+func getFocus(c ClientInterface) (*focus, error) {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_FocusRequest{
+			FocusRequest: &api.FocusRequest{},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get focus: %w", err)
+	}
+	f := &focus{
+		selectedTabs:   map[string]bool{},
+		activeSessions: map[string]bool{},
+	}
+	current := ""
+	for _, n := range resp.GetFocusResponse().GetNotifications() {
+		switch {
+		case n.GetWindow() != nil:
+			switch n.GetWindow().GetWindowStatus() {
+			case api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY:
+				f.window = n.GetWindow().GetWindowId()
+			case api.FocusChangedNotification_Window_TERMINAL_WINDOW_IS_CURRENT:
+				current = n.GetWindow().GetWindowId()
+			}
+		case n.GetSelectedTab() != "":
+			f.selectedTabs[n.GetSelectedTab()] = true
+		case n.GetSession() != "":
+			f.activeSessions[n.GetSession()] = true
+		}
+	}
+	if f.window == "" {
+		f.window = current
+	}
+	return f, nil
+}
+
+// GetActiveWindow returns the key terminal window, or the one that was key
+// last when some other window such as Preferences has focus. It returns
+// ErrNoWindows if no terminal window is open.
+func (a *app) GetActiveWindow() (Window, error) {
+	f, err := getFocus(a.c)
+	if err != nil {
+		return nil, err
+	}
+	if f.window == "" {
+		return nil, ErrNoWindows
+	}
+	return &window{c: a.c, id: f.window}, nil
+}
+
+// ListWindowsOrdered returns the open windows like ListWindows, but with the
+// active window, as GetActiveWindow reports it, first. The others follow in
+// the order iTerm2 lists them, which is the order they were opened in.
+//
+// iTerm2's API reports neither the stacking order of windows nor which
+// window had focus before the active one, so this is as close to "most
+// recently used" as a single call gets. To switch to the previous window,
+// keep a history of what GetActiveWindow returns over time.
+func (a *app) ListWindowsOrdered() ([]Window, error) {
+	f, err := getFocus(a.c)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := a.ListWindows()
+	if windows == nil {
+		return nil, err
+	}
+	for i, w := range windows {
+		if w.GetID() == f.window {
+			copy(windows[1:i+1], windows[:i])
+			windows[0] = w
+			break
+		}
+	}
+	return windows, err
+}
+
+// GetCurrentSession returns the session keystrokes go to: the active session
+// of the selected tab of the active window. It returns ErrNoWindows if no
+// terminal window is open.
+func (a *app) GetCurrentSession() (Session, error) {
+	s, err := a.currentSession()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (a *app) currentSession() (*session, error) {
+	f, err := getFocus(a.c)
+	if err != nil {
+		return nil, err
+	}
+	if f.window == "" {
+		return nil, ErrNoWindows
+	}
+	return a.activeSession(f)
+}
+
+// activeSession returns the active session of the selected tab of the
+// window f reports as current, which must not be empty.
+func (a *app) activeSession(f *focus) (*session, error) {
+	lsr, err := listSessions(a.c)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range lsr.GetWindows() {
+		if w.GetWindowId() != f.window {
+			continue
+		}
+		if t := f.selectedTab(w); t != nil {
+			for _, id := range sessionIDs(t.GetRoot()) {
+				if f.activeSessions[id] {
+					return &session{c: a.c, id: id, windowID: w.GetWindowId(), tabID: t.GetTabId()}, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: iTerm2 reported none for window %q", ErrNoSessions, f.window)
+}
+
+// selectedTab returns the selected tab of w, or nil if iTerm2 did not
+// report one.
+func (f *focus) selectedTab(w *api.ListSessionsResponse_Window) *api.ListSessionsResponse_Tab {
+	for _, t := range w.GetTabs() {
+		if f.selectedTabs[t.GetTabId()] {
+			return t
+		}
+	}
+	return nil
+}
+
+// GetCurrentTab returns the tab selected in the window, the one it shows.
+func (w *window) GetCurrentTab() (Tab, error) {
+	f, err := getFocus(w.c)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current tab of window %q: %w", w.id, err)
+	}
+	lsr, err := listSessions(w.c)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current tab of window %q: %w", w.id, err)
+	}
+	for _, lw := range lsr.GetWindows() {
+		if lw.GetWindowId() != w.id {
+			continue
+		}
+		t := f.selectedTab(lw)
+		if t == nil {
+			return nil, fmt.Errorf("iTerm2 reported no selected tab for window %q", w.id)
+		}
+		return &tab{c: w.c, id: t.GetTabId(), windowID: w.id}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+}
+
+// FocusSession brings the session with the given id in front of the user:
+// it selects the session's tab, orders its window front and activates
+// iTerm2, all in one request. It returns ErrSessionNotFound if the session
+// is gone.
+func (a *app) FocusSession(id string) error {
+	resp, err := a.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ActivateRequest{ActivateRequest: &api.ActivateRequest{
+			Identifier:       &api.ActivateRequest_SessionId{SessionId: id},
+			SelectTab:        b(true),
+			OrderWindowFront: b(true),
+			ActivateApp: &api.ActivateRequest_App{
+				RaiseAllWindows:   b(false),
+				IgnoringOtherApps: b(true),
+			},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("error focusing session %q: %w", id, err)
+	}
+	switch status := resp.GetActivateResponse().GetStatus(); status {
+	case api.ActivateResponse_OK:
+	case api.ActivateResponse_BAD_IDENTIFIER:
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, id)
+	default:
+		return fmt.Errorf("unexpected status focusing session %q: %s", id, status)
+	}
+	return nil
+}
+
+// FocusSnapshot records which window, tab and session had focus, so it can
+// be given back to them with RestoreFocus. The zero value means no iTerm2
+// window was open.
+type FocusSnapshot struct {
+	WindowID  string
+	TabID     string
+	SessionID string
+}
+
+// GetFocusSnapshot returns the current window along with its selected tab
+// and active session.
+func (a *app) GetFocusSnapshot() (FocusSnapshot, error) {
+	f, err := getFocus(a.c)
+	if err != nil {
+		return FocusSnapshot{}, err
+	}
+	if f.window == "" {
+		return FocusSnapshot{}, nil
+	}
+	s, err := a.activeSession(f)
+	if err != nil {
+		return FocusSnapshot{}, err
+	}
+	windowID, tabID := s.location()
+	return FocusSnapshot{WindowID: windowID, TabID: tabID, SessionID: s.id}, nil
+}
+
+// RestoreFocus makes the session recorded in snap active again, selecting
+// its tab and ordering its window front. Whatever no longer exists is
+// skipped: if the session is gone its tab is selected instead, and if the
+// tab is gone too its window is ordered front. When the window is gone as
+// well, the error matches ErrWindowNotFound. Restoring the zero
+// FocusSnapshot does nothing.
+func (a *app) RestoreFocus(snap FocusSnapshot) error {
+	if snap.SessionID != "" {
+		err := (&session{c: a.c, id: snap.SessionID}).Activate(true, true)
+		if !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	}
+	if snap.TabID != "" {
+		err := activateTab(a.c, snap.TabID)
+		if !errors.Is(err, ErrTabNotFound) {
+			return err
+		}
+	}
+	if snap.WindowID != "" {
+		return (&window{c: a.c, id: snap.WindowID}).Activate()
+	}
+	return nil
+}
+
+// activateTab selects the tab with the given id and orders its window
+// front.
+func activateTab(c ClientInterface, id string) error {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ActivateRequest{ActivateRequest: &api.ActivateRequest{
+			Identifier:       &api.ActivateRequest_TabId{TabId: id},
+			SelectTab:        b(true),
+			OrderWindowFront: b(true),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("error activating tab %q: %w", id, err)
+	}
+	switch status := resp.GetActivateResponse().GetStatus(); status {
+	case api.ActivateResponse_OK:
+	case api.ActivateResponse_BAD_IDENTIFIER:
+		return fmt.Errorf("%w: %q", ErrTabNotFound, id)
+	default:
+		return fmt.Errorf("unexpected status activating tab %q: %s", id, status)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package iterm2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// focusResponse is a FocusResponse with the given window status, selected
+// tabs and active sessions
+func focusResponse(status api.FocusChangedNotification_Window_WindowStatus, windowID string, tabs, sessions []string) *api.ServerOriginatedMessage {
+	var ns []*api.FocusChangedNotification
+	if windowID != "" {
+		ns = append(ns, &api.FocusChangedNotification{
+			Event: &api.FocusChangedNotification_Window_{
+				Window: &api.FocusChangedNotification_Window{
+					WindowStatus: status.Enum(),
+					WindowId:     str(windowID),
+				},
+			},
+		})
+	}
+	for _, id := range tabs {
+		ns = append(ns, &api.FocusChangedNotification{
+			Event: &api.FocusChangedNotification_SelectedTab{SelectedTab: id},
+		})
+	}
+	for _, id := range sessions {
+		ns = append(ns, &api.FocusChangedNotification{
+			Event: &api.FocusChangedNotification_Session{Session: id},
+		})
+	}
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_FocusResponse{
+			FocusResponse: &api.FocusResponse{Notifications: ns},
+		},
+	}
+}
+
+// TestCurrentSession verifies the active session of the selected tab of the key window is found
+func TestCurrentSession(t *testing.T) {
+	tests := []struct {
+		name    string
+		focus   *api.ServerOriginatedMessage
+		want    string
+		wantErr error
+	}{
+		{
+			name: "key window",
+			focus: focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, "win-1",
+				[]string{"2", "3"}, []string{"sess-1", "sess-3", "sess-5"}),
+			want: "sess-3",
+		},
+		{
+			name: "current window",
+			focus: focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_IS_CURRENT, "win-2",
+				[]string{"2", "3"}, []string{"sess-1", "sess-3", "sess-5"}),
+			want: "sess-5",
+		},
+		{
+			name:    "no window",
+			focus:   focusResponse(0, "", nil, nil),
+			wantErr: ErrNoWindows,
+		},
+		{
+			name: "no active session",
+			focus: focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, "win-1",
+				[]string{"2"}, nil),
+			wantErr: ErrNoSessions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{tt.focus, layout()}}}
+			got, err := a.GetCurrentSession()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetCurrentSession() error = %v, want %v", err, tt.wantErr)
+				}
+				if got != nil {
+					t.Errorf("GetCurrentSession() = %v, want nil", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCurrentSession() error = %v", err)
+			}
+			if got.GetSessionID() != tt.want {
+				t.Errorf("GetCurrentSession() = %q, want %q", got.GetSessionID(), tt.want)
+			}
+		})
+	}
+}
+
+// TestGetActiveWindow verifies the key window is returned and its absence reported as ErrNoWindows
+func TestGetActiveWindow(t *testing.T) {
+	a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{
+		focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_IS_CURRENT, "win-2", nil, nil),
+	}}}
+	w, err := a.GetActiveWindow()
+	if err != nil {
+		t.Fatalf("GetActiveWindow() error = %v", err)
+	}
+	if w.GetID() != "win-2" {
+		t.Errorf("GetActiveWindow() = %q, want win-2", w.GetID())
+	}
+
+	a = &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{focusResponse(0, "", nil, nil)}}}
+	if w, err := a.GetActiveWindow(); !errors.Is(err, ErrNoWindows) || w != nil {
+		t.Errorf("GetActiveWindow() = %v, %v; want nil, ErrNoWindows", w, err)
+	}
+}
+
+// TestListWindowsOrdered verifies the active window is moved to the front and the rest keep their order
+func TestListWindowsOrdered(t *testing.T) {
+	lsr := layout()
+	lsr.GetListSessionsResponse().Windows = append(lsr.GetListSessionsResponse().Windows,
+		&api.ListSessionsResponse_Window{WindowId: str("win-3")})
+	tests := []struct {
+		active string
+		want   string
+	}{
+		{active: "win-2", want: "win-2 win-1 win-3"},
+		{active: "win-1", want: "win-1 win-2 win-3"},
+		{active: "win-3", want: "win-3 win-1 win-2"},
+		{active: "", want: "win-1 win-2 win-3"},
+	}
+	for _, tt := range tests {
+		a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{
+			focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, tt.active, nil, nil),
+			lsr,
+		}}}
+		windows, err := a.ListWindowsOrdered()
+		if err != nil {
+			t.Fatalf("ListWindowsOrdered() error = %v", err)
+		}
+		var ids []string
+		for _, w := range windows {
+			ids = append(ids, w.GetID())
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("ListWindowsOrdered() with %q active = %s, want %s", tt.active, got, tt.want)
+		}
+	}
+}
+
+// TestGetCurrentTab verifies the window's selected tab is returned with its window set
+func TestGetCurrentTab(t *testing.T) {
+	focus := focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, "win-2",
+		[]string{"2", "3"}, nil)
+	w := &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{focus, layout()}}, id: "win-1"}
+	got, err := w.GetCurrentTab()
+	if err != nil {
+		t.Fatalf("GetCurrentTab() error = %v", err)
+	}
+	if got.GetID() != "2" || got.(*tab).windowID != "win-1" {
+		t.Errorf("GetCurrentTab() = %+v, want tab 2 in win-1", got)
+	}
+
+	w = &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{focus, layout()}}, id: "win-9"}
+	if _, err := w.GetCurrentTab(); !errors.Is(err, ErrWindowNotFound) {
+		t.Errorf("GetCurrentTab() of a closed window error = %v, want ErrWindowNotFound", err)
+	}
+}
+
+// activateResponse is a canned ActivateResponse
+func activateResponse(status api.ActivateResponse_Status) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ActivateResponse{
+			ActivateResponse: &api.ActivateResponse{Status: status.Enum()},
+		},
+	}
+}
+
+// TestFocusSession verifies a single request selects the session's tab, raises its window and activates iTerm2
+func TestFocusSession(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{activateResponse(api.ActivateResponse_OK)}}
+	a := &app{c: mock}
+	if err := a.FocusSession("sess-2"); err != nil {
+		t.Fatalf("FocusSession() error = %v", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected a single Call, got %d", len(mock.calls))
+	}
+	req := mock.calls[0].GetActivateRequest()
+	if req.GetSessionId() != "sess-2" || !req.GetSelectTab() || !req.GetOrderWindowFront() || !req.GetActivateApp().GetIgnoringOtherApps() {
+		t.Errorf("unexpected request %v", req)
+	}
+
+	a = &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{activateResponse(api.ActivateResponse_BAD_IDENTIFIER)}}}
+	if err := a.FocusSession("gone"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("FocusSession() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestGetFocusSnapshot verifies the current window, tab and session are recorded, and nothing when no window is open
+func TestGetFocusSnapshot(t *testing.T) {
+	focus := focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, "win-1",
+		[]string{"2", "3"}, []string{"sess-1", "sess-4", "sess-5"})
+	a := &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{focus, layout()}}}
+	got, err := a.GetFocusSnapshot()
+	if err != nil {
+		t.Fatalf("GetFocusSnapshot() error = %v", err)
+	}
+	if want := (FocusSnapshot{WindowID: "win-1", TabID: "2", SessionID: "sess-4"}); got != want {
+		t.Errorf("GetFocusSnapshot() = %+v, want %+v", got, want)
+	}
+
+	a = &app{c: &mockClient{responses: []*api.ServerOriginatedMessage{focusResponse(0, "", nil, nil)}}}
+	if got, err := a.GetFocusSnapshot(); err != nil || got != (FocusSnapshot{}) {
+		t.Errorf("GetFocusSnapshot() without windows = %+v, %v; want the zero snapshot", got, err)
+	}
+}
+
+// TestRestoreFocus verifies focus falls back from the session to its tab and window when they are gone
+func TestRestoreFocus(t *testing.T) {
+	ok := activateResponse(api.ActivateResponse_OK)
+	gone := activateResponse(api.ActivateResponse_BAD_IDENTIFIER)
+	snap := FocusSnapshot{WindowID: "win-1", TabID: "2", SessionID: "sess-4"}
+	tests := []struct {
+		name      string
+		responses []*api.ServerOriginatedMessage
+		want      []string
+		wantErr   error
+	}{
+		{name: "session", responses: []*api.ServerOriginatedMessage{ok}, want: []string{"sess-4"}},
+		{name: "tab", responses: []*api.ServerOriginatedMessage{gone, ok}, want: []string{"sess-4", "2"}},
+		{name: "window", responses: []*api.ServerOriginatedMessage{gone, gone, ok}, want: []string{"sess-4", "2", "win-1"}},
+		{
+			name:      "nothing left",
+			responses: []*api.ServerOriginatedMessage{gone, gone, gone},
+			want:      []string{"sess-4", "2", "win-1"},
+			wantErr:   ErrWindowNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: tt.responses}
+			a := &app{c: mock}
+			if err := a.RestoreFocus(snap); !errors.Is(err, tt.wantErr) {
+				t.Errorf("RestoreFocus() error = %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, c := range mock.calls {
+				req := c.GetActivateRequest()
+				got = append(got, req.GetSessionId()+req.GetTabId()+req.GetWindowId())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("activated %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package iterm2
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// frameDebounce is how long a window has to stay put before
+// SubscribeFrameChange reports its new frame.
+var frameDebounce = 250 * time.Millisecond
+
+// GetFrame returns the window's position and size.
+func (w *window) GetFrame() (Frame, error) {
+	var f jsonFrame
+	if err := w.getProperty("frame", &f); err != nil {
+		return Frame{}, err
+	}
+	return f.frame(), nil
+}
+
+// SubscribeFrameChange delivers the window's frame every time the user
+// moves or resizes it. While the window is being dragged iTerm2 reports
+// many intermediate frames; only the frame the window settles on, once it
+// has not changed for a moment, is delivered. Call the returned function to
+// stop the subscription; the channel is closed once it returns.
+func (w *window) SubscribeFrameChange() (<-chan Frame, func(), error) {
+	changes, cancel, err := (&app{c: w.c}).SubscribeVariableChange(WindowScope(w.id), "frame", WithBufferSize(1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not watch frame of window %q: %w", w.id, err)
+	}
+
+	out := make(chan Frame)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var last Frame
+		var settled <-chan time.Time
+		for {
+			select {
+			case <-changes:
+				settled = time.After(frameDebounce)
+			case <-settled:
+				settled = nil
+				// The notification only says the frame changed; it
+				// is read back as a property, in the documented
+				// format.
+				f, err := w.GetFrame()
+				if err != nil || f == last {
+					continue
+				}
+				last = f
+				select {
+				case out <- f:
+				case <-stop:
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			cancel()
+			close(out)
+		})
+	}, nil
+}
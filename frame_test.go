@@ -0,0 +1,49 @@
+package iterm2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestSubscribeFrameChange verifies a burst of frame changes is reported once, with the frame the window settled on
+func TestSubscribeFrameChange(t *testing.T) {
+	defer func(d time.Duration) { frameDebounce = d }(frameDebounce)
+	frameDebounce = 50 * time.Millisecond
+
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		notificationOK(),
+		windowProperty(api.GetPropertyResponse_OK, `{"origin":{"x":10,"y":20},"size":{"width":800,"height":600.4}}`),
+		notificationOK(),
+	}}
+	w := &window{c: mock, id: "win-1"}
+
+	frames, cancel, err := w.SubscribeFrameChange()
+	if err != nil {
+		t.Fatalf("SubscribeFrameChange() error = %v", err)
+	}
+	mon := mock.calls[0].GetNotificationRequest().GetVariableMonitorRequest()
+	if mon.GetName() != "frame" || mon.GetScope() != api.VariableScope_WINDOW || mon.GetIdentifier() != "win-1" {
+		t.Errorf("unexpected monitor request %v", mon)
+	}
+	for i := 0; i < 5; i++ {
+		mock.notify(variableChanged(api.VariableScope_WINDOW, "win-1", "frame", "null"))
+	}
+
+	select {
+	case f := <-frames:
+		if want := (Frame{X: 10, Y: 20, Width: 800, Height: 600}); f != want {
+			t.Errorf("frame = %+v, want %+v", f, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame delivered")
+	}
+	cancel()
+	if _, ok := <-frames; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if len(mock.calls) != 3 || mock.calls[1].GetGetPropertyRequest().GetName() != "frame" {
+		t.Errorf("expected the burst to read the frame once, got calls %v", mock.calls)
+	}
+}
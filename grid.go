@@ -0,0 +1,62 @@
+package iterm2
+
+import (
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// CreateGrid opens a new tab in the window and splits it into rows by cols
+// panes, all using the named profile, or the default profile if it is
+// empty. The sessions are returned row by row, so grid[0][0] is the top
+// left pane and grid[rows-1][cols-1] the bottom right one.
+//
+// Every split halves the pane being split, so with more than two rows or
+// columns the panes differ in size. If a pane gets too small to split, the
+// error matches ErrCannotSplit; on any error the new tab is closed again
+// without asking the user.
+func (w *window) CreateGrid(rows, cols int, profile string) ([][]Session, error) {
+	if rows < 1 || cols < 1 {
+		return nil, fmt.Errorf("could not create grid in window %q: invalid size %dx%d, need at least one row and column", w.id, rows, cols)
+	}
+	req := &api.CreateTabRequest{}
+	if profile != "" {
+		req.ProfileName = &profile
+	}
+	t, first, err := w.createTabSession(req)
+	if err != nil {
+		return nil, err
+	}
+	grid, err := splitGrid(first, rows, cols, profile)
+	if err != nil {
+		t.close()
+		return nil, fmt.Errorf("could not create %dx%d grid in window %q: %w", rows, cols, w.id, err)
+	}
+	return grid, nil
+}
+
+// splitGrid splits s into columns, then splits each column into rows.
+func splitGrid(s Session, rows, cols int, profile string) ([][]Session, error) {
+	grid := make([][]Session, rows)
+	for r := range grid {
+		grid[r] = make([]Session, cols)
+	}
+	grid[0][0] = s
+	for c := 1; c < cols; c++ {
+		next, err := grid[0][c-1].SplitPane(SplitPaneOptions{Vertical: true, Profile: profile})
+		if err != nil {
+			return nil, err
+		}
+		grid[0][c] = next
+	}
+	for c := 0; c < cols; c++ {
+		for r := 1; r < rows; r++ {
+			next, err := grid[r-1][c].SplitPane(SplitPaneOptions{Profile: profile})
+			if err != nil {
+				return nil, err
+			}
+			grid[r][c] = next
+		}
+	}
+	return grid, nil
+}
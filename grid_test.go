@@ -0,0 +1,104 @@
+package iterm2
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// gridClient creates a tab and answers splits with new session ids until
+// maxSplits is reached, after which splits fail with CANNOT_SPLIT
+func gridClient(maxSplits int) *mockClient {
+	splits := 0
+	return &mockClient{callFunc: func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		switch {
+		case req.GetCreateTabRequest() != nil:
+			return createTabOK(4), nil
+		case req.GetSplitPaneRequest() != nil:
+			status := api.SplitPaneResponse_OK
+			var ids []string
+			if splits < maxSplits {
+				splits++
+				ids = []string{fmt.Sprintf("split-%d", splits)}
+			} else {
+				status = api.SplitPaneResponse_CANNOT_SPLIT
+			}
+			return &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_SplitPaneResponse{
+					SplitPaneResponse: &api.SplitPaneResponse{Status: status.Enum(), SessionId: ids},
+				},
+			}, nil
+		default:
+			return &api.ServerOriginatedMessage{}, nil
+		}
+	}}
+}
+
+// TestCreateGrid verifies the splits made for a grid and the layout of the returned sessions
+func TestCreateGrid(t *testing.T) {
+	mock := gridClient(100)
+	w := &window{c: mock, id: "win-1"}
+
+	grid, err := w.CreateGrid(2, 3, "Dashboard")
+	if err != nil {
+		t.Fatalf("CreateGrid() error = %v", err)
+	}
+	var got [][]string
+	for _, row := range grid {
+		var ids []string
+		for _, s := range row {
+			ids = append(ids, s.GetSessionID())
+		}
+		got = append(got, ids)
+	}
+	want := [][]string{
+		{"sess-1", "split-1", "split-2"},
+		{"split-3", "split-4", "split-5"},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("grid = %v, want %v", got, want)
+	}
+	if p := mock.calls[0].GetCreateTabRequest().GetProfileName(); p != "Dashboard" {
+		t.Errorf("tab profile = %q, want Dashboard", p)
+	}
+	for i, call := range mock.calls[1:] {
+		req := call.GetSplitPaneRequest()
+		wantDir := api.SplitPaneRequest_HORIZONTAL
+		if i < 2 {
+			wantDir = api.SplitPaneRequest_VERTICAL
+		}
+		if req.GetSplitDirection() != wantDir || req.GetProfileName() != "Dashboard" {
+			t.Errorf("split %d = %v", i, req)
+		}
+	}
+}
+
+// TestCreateGrid_TooSmall verifies a grid that does not fit reports ErrCannotSplit and closes the tab
+func TestCreateGrid_TooSmall(t *testing.T) {
+	mock := gridClient(3)
+	w := &window{c: mock, id: "win-1"}
+
+	if _, err := w.CreateGrid(4, 4, ""); !errors.Is(err, ErrCannotSplit) {
+		t.Fatalf("CreateGrid() error = %v, want ErrCannotSplit", err)
+	}
+	last := mock.calls[len(mock.calls)-1].GetCloseRequest()
+	if ids := last.GetTabs().GetTabIds(); len(ids) != 1 || ids[0] != "4" || !last.GetForce() {
+		t.Errorf("expected the tab to be force closed, last call %v", last)
+	}
+}
+
+// TestCreateGrid_InvalidSize verifies empty grids are rejected before calling iTerm2
+func TestCreateGrid_InvalidSize(t *testing.T) {
+	mock := &mockClient{}
+	w := &window{c: mock, id: "win-1"}
+	for _, size := range [][2]int{{0, 1}, {1, 0}, {-1, 2}} {
+		if _, err := w.CreateGrid(size[0], size[1], ""); err == nil {
+			t.Errorf("CreateGrid(%d, %d) expected error, got nil", size[0], size[1])
+		}
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected no Calls, got %d", len(mock.calls))
+	}
+}
@@ -0,0 +1,86 @@
+package iterm2
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// errInvalidID is returned by invokeFunction when iTerm2 does not know the
+// object a function was invoked on. Callers map it to the not-found error
+// for the kind of object they addressed.
+var errInvalidID = errors.New("invalid id")
+
+// InvokeFunction calls an iTerm2 script function, such as
+// `iterm2.get_string(title: "Name", subtitle: "", placeholder: "", defaultValue: "")`,
+// in the context of the app and returns its result as JSON. This gives
+// access to functions the library does not wrap yet; see iTerm2's scripting
+// documentation for the functions available. Arguments must be written as
+// JSON values in the invocation.
+func (a *app) InvokeFunction(invocation string) (string, error) {
+	return invokeFunction(a.c, &api.InvokeFunctionRequest{
+		Invocation: &invocation,
+		Context:    &api.InvokeFunctionRequest_App_{App: &api.InvokeFunctionRequest_App{}},
+	})
+}
+
+// InvokeFunction calls a method of the session, such as
+// `iterm2.set_name(name: "build")`, and returns its result as JSON. See
+// App.InvokeFunction.
+func (s *session) InvokeFunction(invocation string) (string, error) {
+	result, err := invokeMethod(s.c, s.id, invocation)
+	if errors.Is(err, errInvalidID) {
+		return "", fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	}
+	return result, err
+}
+
+// invokeMethod calls a method of the window, tab or session with the given
+// id.
+func invokeMethod(c ClientInterface, receiver, invocation string) (string, error) {
+	return invokeFunction(c, &api.InvokeFunctionRequest{
+		Invocation: &invocation,
+		Context: &api.InvokeFunctionRequest_Method_{
+			Method: &api.InvokeFunctionRequest_Method{Receiver: &receiver},
+		},
+	})
+}
+
+// invocationString returns s as a string literal for a function
+// invocation. Quotes, backslashes and control characters are escaped the way
+// JSON escapes them; everything else, including emoji and combining marks,
+// is passed through as UTF-8.
+func invocationString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// Encoding a string cannot fail.
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func invokeFunction(c ClientInterface, req *api.InvokeFunctionRequest) (string, error) {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_InvokeFunctionRequest{InvokeFunctionRequest: req},
+	})
+	// Methods are named along with the object they were invoked on.
+	call := req.GetInvocation()
+	if receiver := req.GetMethod().GetReceiver(); receiver != "" {
+		call = fmt.Sprintf("%s on %q", call, receiver)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not invoke %s: %w", call, err)
+	}
+	ifr := resp.GetInvokeFunctionResponse()
+	if e := ifr.GetError(); e != nil {
+		if e.GetStatus() == api.InvokeFunctionResponse_INVALID_ID {
+			return "", fmt.Errorf("could not invoke %s: %w", call, errInvalidID)
+		}
+		return "", fmt.Errorf("%s failed with status %s: %s", call, e.GetStatus(), e.GetErrorReason())
+	}
+	return ifr.GetSuccess().GetJsonResult(), nil
+}
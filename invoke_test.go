@@ -0,0 +1,133 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+func invokeSuccess(result string) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_InvokeFunctionResponse{
+			InvokeFunctionResponse: &api.InvokeFunctionResponse{
+				Disposition: &api.InvokeFunctionResponse_Success_{
+					Success: &api.InvokeFunctionResponse_Success{JsonResult: str(result)},
+				},
+			},
+		},
+	}
+}
+
+func invokeError(status api.InvokeFunctionResponse_Status, reason string) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_InvokeFunctionResponse{
+			InvokeFunctionResponse: &api.InvokeFunctionResponse{
+				Disposition: &api.InvokeFunctionResponse_Error_{
+					Error: &api.InvokeFunctionResponse_Error{Status: status.Enum(), ErrorReason: str(reason)},
+				},
+			},
+		},
+	}
+}
+
+// TestInvokeFunction verifies app functions run in the app context and their JSON result is returned
+func TestInvokeFunction(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		invokeSuccess(`"hello"`),
+		invokeError(api.InvokeFunctionResponse_FAILED, "no such function"),
+	}}
+	a := &app{c: mock}
+
+	got, err := a.InvokeFunction(`iterm2.echo(text: "hello")`)
+	if err != nil {
+		t.Fatalf("InvokeFunction() error = %v", err)
+	}
+	if got != `"hello"` {
+		t.Errorf("InvokeFunction() = %s, want %q", got, `"hello"`)
+	}
+	req := mock.calls[0].GetInvokeFunctionRequest()
+	if req.GetApp() == nil || req.GetInvocation() != `iterm2.echo(text: "hello")` {
+		t.Errorf("unexpected InvokeFunctionRequest %v", req)
+	}
+
+	if _, err := a.InvokeFunction("bogus()"); err == nil {
+		t.Error("InvokeFunction() of a failing function expected error, got nil")
+	}
+}
+
+// TestSessionInvokeFunction verifies session functions are invoked as methods and unknown sessions report ErrSessionNotFound
+func TestSessionInvokeFunction(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		invokeSuccess(`null`),
+		invokeError(api.InvokeFunctionResponse_INVALID_ID, "no such session"),
+	}}
+	s := &session{c: mock, id: "sess-1"}
+
+	if _, err := s.InvokeFunction(`iterm2.set_name(name: "build")`); err != nil {
+		t.Fatalf("InvokeFunction() error = %v", err)
+	}
+	if got := mock.calls[0].GetInvokeFunctionRequest().GetMethod().GetReceiver(); got != "sess-1" {
+		t.Errorf("receiver = %q, want sess-1", got)
+	}
+	if _, err := s.InvokeFunction(`iterm2.set_name(name: "build")`); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("InvokeFunction() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestSetTitle_Unicode verifies titles reach iTerm2 intact, whatever characters they contain
+func TestSetTitle_Unicode(t *testing.T) {
+	titles := []string{
+		"🚀 deploy",
+		"👩‍💻 dev",
+		"cafe\u0301",
+		"日本語",
+		`say "hi" \o/`,
+		"tab\there",
+		"<b>&amp;</b>",
+	}
+	targets := []struct {
+		name string
+		set  func(c ClientInterface, title string) error
+	}{
+		{"tab", func(c ClientInterface, title string) error { return (&tab{c: c, id: "1"}).SetTitle(title) }},
+		{"window", func(c ClientInterface, title string) error { return (&window{c: c, id: "win-1"}).SetTitle(title) }},
+	}
+	for _, target := range targets {
+		for _, title := range titles {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{invokeSuccess("null")}}
+			if err := target.set(mock, title); err != nil {
+				t.Fatalf("%s SetTitle(%q) error = %v", target.name, title, err)
+			}
+			invocation := mock.calls[0].GetInvokeFunctionRequest().GetInvocation()
+			const prefix, suffix = "iterm2.set_title(title: ", ")"
+			if !strings.HasPrefix(invocation, prefix) || !strings.HasSuffix(invocation, suffix) {
+				t.Fatalf("%s invocation = %q", target.name, invocation)
+			}
+			var got string
+			literal := strings.TrimSuffix(strings.TrimPrefix(invocation, prefix), suffix)
+			if err := json.Unmarshal([]byte(literal), &got); err != nil || got != title {
+				t.Errorf("%s invocation %q carries %q (%v), want %q", target.name, invocation, got, err, title)
+			}
+			if strings.Contains(literal, `\u`) {
+				t.Errorf("%s invocation %q escapes printable characters", target.name, invocation)
+			}
+		}
+	}
+}
+
+// TestSetName verifies the session name is written as JSON, keeping emoji and combining marks intact
+func TestSetName(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetName("🚀 cafe\u0301"); err != nil {
+		t.Fatalf("SetName() error = %v", err)
+	}
+	a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+	if len(a) != 1 || a[0].GetKey() != "Name" || a[0].GetJsonValue() != "\"🚀 cafe\u0301\"" {
+		t.Errorf("assignments = %v, want Name=\"🚀 cafe\u0301\"", a)
+	}
+}
@@ -0,0 +1,142 @@
+package iterm2
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Key identifies a special key that can be sent with Session.SendKeyEvent.
+type Key int
+
+// Keys supported by Session.SendKeyEvent.
+const (
+	KeyUp Key = iota + 1
+	KeyDown
+	KeyRight
+	KeyLeft
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyInsert
+	KeyDelete
+	KeyEscape
+	KeyTab
+	KeyEnter
+	KeyBackspace
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+// Modifiers is a set of modifier keys held down during a key event.
+type Modifiers uint8
+
+// Modifier keys that can be combined with a Key.
+const (
+	ModShift Modifiers = 1 << iota
+	ModOption
+	ModControl
+	// ModCommand is reported as the xterm Meta modifier, since the
+	// Command key itself never reaches programs running in a terminal.
+	ModCommand
+)
+
+const allModifiers = ModShift | ModOption | ModControl | ModCommand
+
+// cursorKeys end in a final letter: ESC [ A, or ESC [ 1 ; mod A.
+var cursorKeys = map[Key]byte{
+	KeyUp:    'A',
+	KeyDown:  'B',
+	KeyRight: 'C',
+	KeyLeft:  'D',
+	KeyHome:  'H',
+	KeyEnd:   'F',
+}
+
+// functionKeys F1-F4 are SS3 sequences unless modified.
+var functionKeys = map[Key]byte{
+	KeyF1: 'P',
+	KeyF2: 'Q',
+	KeyF3: 'R',
+	KeyF4: 'S',
+}
+
+// tildeKeys end in a tilde: ESC [ 5 ~, or ESC [ 5 ; mod ~.
+var tildeKeys = map[Key]int{
+	KeyInsert:   2,
+	KeyDelete:   3,
+	KeyPageUp:   5,
+	KeyPageDown: 6,
+	KeyF5:       15,
+	KeyF6:       17,
+	KeyF7:       18,
+	KeyF8:       19,
+	KeyF9:       20,
+	KeyF10:      21,
+	KeyF11:      23,
+	KeyF12:      24,
+}
+
+var plainKeys = map[Key]string{
+	KeyEscape:    "\x1b",
+	KeyTab:       "\t",
+	KeyEnter:     "\r",
+	KeyBackspace: "\x7f",
+}
+
+// keySequence returns the bytes an xterm-compatible terminal sends for key
+// while mods are held down.
+func keySequence(key Key, mods Modifiers) (string, error) {
+	if mods&^allModifiers != 0 {
+		return "", fmt.Errorf("unknown modifiers %#x", uint8(mods))
+	}
+	// xterm encodes modifiers as 1 + shift(1) + alt(2) + ctrl(4) + meta(8).
+	param := ""
+	if mods != 0 {
+		param = strconv.Itoa(1 + int(mods&ModShift) + int(mods&ModOption) + int(mods&ModControl) + int(mods&ModCommand))
+	}
+	if final, ok := cursorKeys[key]; ok {
+		if param == "" {
+			return "\x1b[" + string(final), nil
+		}
+		return "\x1b[1;" + param + string(final), nil
+	}
+	if final, ok := functionKeys[key]; ok {
+		if param == "" {
+			return "\x1bO" + string(final), nil
+		}
+		return "\x1b[1;" + param + string(final), nil
+	}
+	if code, ok := tildeKeys[key]; ok {
+		if param == "" {
+			return "\x1b[" + strconv.Itoa(code) + "~", nil
+		}
+		return "\x1b[" + strconv.Itoa(code) + ";" + param + "~", nil
+	}
+	if seq, ok := plainKeys[key]; ok {
+		prefix := ""
+		if mods&ModOption != 0 {
+			// Option sends an ESC prefix, as with "Esc+" in iTerm2's profile settings.
+			prefix = "\x1b"
+			mods &^= ModOption
+		}
+		switch {
+		case mods == 0:
+			return prefix + seq, nil
+		case mods == ModShift && key == KeyTab:
+			return prefix + "\x1b[Z", nil
+		}
+		return "", fmt.Errorf("key %d cannot be combined with modifiers %#x", key, uint8(mods))
+	}
+	return "", fmt.Errorf("unknown key %d", key)
+}
@@ -0,0 +1,81 @@
+package iterm2
+
+import (
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestKeySequence verifies keys and modifiers are translated into xterm escape sequences
+func TestKeySequence(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+		mods Modifiers
+		want string
+	}{
+		{name: "up", key: KeyUp, want: "\x1b[A"},
+		{name: "shift left", key: KeyLeft, mods: ModShift, want: "\x1b[1;2D"},
+		{name: "ctrl right", key: KeyRight, mods: ModControl, want: "\x1b[1;5C"},
+		{name: "option ctrl end", key: KeyEnd, mods: ModOption | ModControl, want: "\x1b[1;7F"},
+		{name: "command home", key: KeyHome, mods: ModCommand, want: "\x1b[1;9H"},
+		{name: "F1", key: KeyF1, want: "\x1bOP"},
+		{name: "shift F4", key: KeyF4, mods: ModShift, want: "\x1b[1;2S"},
+		{name: "F5", key: KeyF5, want: "\x1b[15~"},
+		{name: "ctrl F12", key: KeyF12, mods: ModControl, want: "\x1b[24;5~"},
+		{name: "page down", key: KeyPageDown, want: "\x1b[6~"},
+		{name: "shift delete", key: KeyDelete, mods: ModShift, want: "\x1b[3;2~"},
+		{name: "enter", key: KeyEnter, want: "\r"},
+		{name: "escape", key: KeyEscape, want: "\x1b"},
+		{name: "shift tab", key: KeyTab, mods: ModShift, want: "\x1b[Z"},
+		{name: "option backspace", key: KeyBackspace, mods: ModOption, want: "\x1b\x7f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := keySequence(tt.key, tt.mods)
+			if err != nil {
+				t.Fatalf("keySequence() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("keySequence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestKeySequence_Invalid verifies unsupported combinations are rejected
+func TestKeySequence_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+		mods Modifiers
+	}{
+		{name: "unknown key", key: Key(999)},
+		{name: "unknown modifier", key: KeyUp, mods: Modifiers(1 << 7)},
+		{name: "ctrl enter", key: KeyEnter, mods: ModControl},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := keySequence(tt.key, tt.mods); err == nil {
+				t.Error("keySequence() expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestSendKeyEvent verifies the escape sequence is sent as text to the session
+func TestSendKeyEvent(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{sendTextOK()},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SendKeyEvent(KeyUp, ModControl); err != nil {
+		t.Fatalf("SendKeyEvent() error = %v", err)
+	}
+	if got := mock.calls[0].GetSendTextRequest().GetText(); got != "\x1b[1;5A" {
+		t.Errorf("sent %q, want %q", got, "\x1b[1;5A")
+	}
+}
@@ -0,0 +1,69 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// SetLogging turns automatic logging of the session's output on or off.
+// While it is on, iTerm2 writes everything the session shows to a file in
+// the directory dir, which must exist. A leading "~" in dir is expanded to
+// the home directory and relative paths are made absolute. dir is ignored
+// when turning logging off.
+func (s *session) SetLogging(enabled bool, dir string) error {
+	assignments := []*api.SetProfilePropertyRequest_Assignment{{
+		Key:       str("Automatically Log"),
+		JsonValue: str(strconv.FormatBool(enabled)),
+	}}
+	if enabled {
+		dir, err := logDirectory(dir)
+		if err != nil {
+			return fmt.Errorf("could not enable logging for session %q: %w", s.id, err)
+		}
+		value, err := json.Marshal(dir)
+		if err != nil {
+			return fmt.Errorf("could not encode log directory for session %q: %w", s.id, err)
+		}
+		assignments = append(assignments, &api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Log Directory"),
+			JsonValue: str(string(value)),
+		})
+	}
+	if err := setProfileProperties(s.c, s.id, assignments...); err != nil {
+		return fmt.Errorf("could not set logging for session %q: %w", s.id, err)
+	}
+	return nil
+}
+
+// logDirectory expands and checks the directory SetLogging is given.
+func logDirectory(dir string) (string, error) {
+	if dir == "" {
+		return "", errors.New("a log directory is required")
+	}
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not expand %q: %w", dir, err)
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("log directory %q is not a directory", dir)
+	}
+	return dir, nil
+}
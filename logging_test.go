@@ -0,0 +1,80 @@
+package iterm2
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestSetLogging verifies the logging keys are written with the expanded directory and bad directories rejected
+func TestSetLogging(t *testing.T) {
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, "logs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(home, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		name    string
+		enabled bool
+		dir     string
+		want    map[string]string
+		wantErr error
+	}{
+		{
+			name:    "home relative",
+			enabled: true,
+			dir:     "~/logs",
+			want: map[string]string{
+				"Automatically Log": "true",
+				"Log Directory":     strconv.Quote(filepath.Join(home, "logs")),
+			},
+		},
+		{
+			name: "disable ignores the directory",
+			dir:  "/does/not/exist",
+			want: map[string]string{"Automatically Log": "false"},
+		},
+		{name: "missing directory", enabled: true, dir: "~/nope", wantErr: os.ErrNotExist},
+		{name: "not a directory", enabled: true, dir: file},
+		{name: "no directory", enabled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{}
+			s := &session{c: mock, id: "sess-1"}
+
+			err := s.SetLogging(tt.enabled, tt.dir)
+			if tt.want == nil {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Errorf("SetLogging() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(mock.calls) != 0 {
+					t.Errorf("expected no Calls, got %d", len(mock.calls))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetLogging() error = %v", err)
+			}
+			got := map[string]string{}
+			for _, a := range mock.calls[0].GetSetProfilePropertyRequest().GetAssignments() {
+				got[a.GetKey()] = a.GetJsonValue()
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("assignments = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
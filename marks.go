@@ -0,0 +1,144 @@
+package iterm2
+
+import (
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// setMarkSequence is iTerm2's proprietary escape sequence for setting a mark
+// at the cursor position.
+const setMarkSequence = "\x1b]1337;SetMark\x07"
+
+// Mark is a command mark. iTerm2 records one at every prompt when shell
+// integration is installed in the session's shell, see
+// https://iterm2.com/documentation-shell-integration.html.
+type Mark struct {
+	// ID identifies the mark within its session.
+	ID string
+	// Command is the command entered at the prompt, if any.
+	Command string
+	// WorkingDirectory is the directory the command was entered in, if
+	// known.
+	WorkingDirectory string
+	// Prompt covers the prompt itself and Output what the command printed.
+	Prompt, Output GridRange
+}
+
+// SetMark sets a mark at the session's cursor position, as if the program
+// running in it had printed iTerm2's SetMark escape sequence. The user can
+// jump to it with Edit > Marks and Annotations.
+func (s *session) SetMark() error {
+	return s.inject([]byte(setMarkSequence))
+}
+
+// ListMarks returns the session's command marks, oldest first. Marks only
+// exist for prompts shown while shell integration was active, so the list
+// is empty in shells without it. Marks set with SetMark are not included:
+// iTerm2 does not report them through its API. It needs API version 1.8;
+// see App.GetAPIVersion.
+func (s *session) ListMarks() ([]Mark, error) {
+	ids, err := s.listPromptIDs()
+	if err != nil {
+		return nil, err
+	}
+	marks := []Mark{}
+	for _, id := range ids {
+		gpr, err := s.getPrompt(id)
+		if err != nil {
+			return nil, err
+		}
+		if gpr != nil {
+			marks = append(marks, Mark{
+				ID:               id,
+				Command:          gpr.GetCommand(),
+				WorkingDirectory: gpr.GetWorkingDirectory(),
+				Prompt:           GridRangeFromProto(gpr.GetPromptRange()),
+				Output:           GridRangeFromProto(gpr.GetOutputRange()),
+			})
+		}
+	}
+	return marks, nil
+}
+
+// GetLastCommand returns the most recent command that finished running in
+// the session and its exit status. This information comes from shell
+// integration: in sessions without it, the error matches
+// ErrShellIntegrationUnavailable. It needs API version 1.8; see
+// App.GetAPIVersion.
+//
+// iTerm2 also keeps a command history of its own, shown in the toolbelt's
+// Command History tool. The API offers no way to clear it; only the tool
+// itself can.
+func (s *session) GetLastCommand() (command string, exitCode int, err error) {
+	ids, err := s.listPromptIDs()
+	if err != nil {
+		return "", 0, err
+	}
+	if len(ids) == 0 {
+		return "", 0, fmt.Errorf("%w: no prompts recorded in session %q", ErrShellIntegrationUnavailable, s.id)
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		gpr, err := s.getPrompt(ids[i])
+		if err != nil {
+			return "", 0, err
+		}
+		if gpr == nil {
+			// Older prompts have been dropped from the history too.
+			break
+		}
+		if gpr.GetPromptState() == api.GetPromptResponse_FINISHED {
+			return gpr.GetCommand(), int(gpr.GetExitStatus()), nil
+		}
+	}
+	return "", 0, fmt.Errorf("no command has finished in session %q", s.id)
+}
+
+// listPromptIDs returns the ids of the prompts shell integration recorded
+// in the session, oldest first. Prompt ids exist from API version 1.8 on.
+func (s *session) listPromptIDs() ([]string, error) {
+	if err := requireAPIVersion(s.c, 1, 8, "listing prompts"); err != nil {
+		return nil, fmt.Errorf("could not list prompts of session %q: %w", s.id, err)
+	}
+	resp, err := s.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ListPromptsRequest{
+			ListPromptsRequest: &api.ListPromptsRequest{Session: &s.id},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list prompts of session %q: %w", s.id, err)
+	}
+	lpr := resp.GetListPromptsResponse()
+	switch status := lpr.GetStatus(); status {
+	case api.ListPromptsResponse_OK:
+		return lpr.GetUniquePromptId(), nil
+	case api.ListPromptsResponse_SESSION_NOT_FOUND:
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
+		return nil, fmt.Errorf("unexpected status listing prompts of session %q: %s", s.id, status)
+	}
+}
+
+// getPrompt returns the prompt with the given id, or nil if it has been
+// dropped from the scrollback history since it was listed.
+func (s *session) getPrompt(id string) (*api.GetPromptResponse, error) {
+	resp, err := s.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_GetPromptRequest{
+			GetPromptRequest: &api.GetPromptRequest{Session: &s.id, UniquePromptId: &id},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get prompt %q of session %q: %w", id, s.id, err)
+	}
+	gpr := resp.GetGetPromptResponse()
+	switch status := gpr.GetStatus(); status {
+	case api.GetPromptResponse_OK:
+		return gpr, nil
+	case api.GetPromptResponse_PROMPT_UNAVAILABLE:
+		return nil, nil
+	case api.GetPromptResponse_SESSION_NOT_FOUND:
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
+		return nil, fmt.Errorf("unexpected status getting prompt %q of session %q: %s", id, s.id, status)
+	}
+}
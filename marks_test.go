@@ -0,0 +1,123 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// promptResponse is a canned GetPromptResponse for a finished command
+func promptResponse(id, command string, firstLine int) *api.ServerOriginatedMessage {
+	prompt := GridRange{Start: Coord{0, firstLine}, End: Coord{2, firstLine}}
+	output := GridRange{Start: Coord{0, firstLine + 1}, End: Coord{0, firstLine + 3}}
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_GetPromptResponse{
+			GetPromptResponse: &api.GetPromptResponse{
+				Status:           api.GetPromptResponse_OK.Enum(),
+				UniquePromptId:   str(id),
+				Command:          str(command),
+				WorkingDirectory: str("/tmp"),
+				PromptRange:      prompt.Proto(),
+				OutputRange:      output.Proto(),
+			},
+		},
+	}
+}
+
+func listPrompts(status api.ListPromptsResponse_Status, ids ...string) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ListPromptsResponse{
+			ListPromptsResponse: &api.ListPromptsResponse{Status: status.Enum(), UniquePromptId: ids},
+		},
+	}
+}
+
+// TestSetMark verifies the SetMark escape sequence is injected into the session
+func TestSetMark(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{{
+		Submessage: &api.ServerOriginatedMessage_InjectResponse{
+			InjectResponse: &api.InjectResponse{Status: []api.InjectResponse_Status{api.InjectResponse_OK}},
+		},
+	}}}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.SetMark(); err != nil {
+		t.Fatalf("SetMark() error = %v", err)
+	}
+	req := mock.calls[0].GetInjectRequest()
+	if len(req.GetSessionId()) != 1 || req.GetSessionId()[0] != "sess-1" {
+		t.Errorf("session ids = %v, want [sess-1]", req.GetSessionId())
+	}
+	if string(req.GetData()) != "\x1b]1337;SetMark\x07" {
+		t.Errorf("data = %q", req.GetData())
+	}
+}
+
+// TestListMarks verifies marks are returned in order and marks dropped from history are skipped
+func TestListMarks(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		listPrompts(api.ListPromptsResponse_OK, "p1", "p2", "p3"),
+		{Submessage: &api.ServerOriginatedMessage_GetPromptResponse{
+			GetPromptResponse: &api.GetPromptResponse{Status: api.GetPromptResponse_PROMPT_UNAVAILABLE.Enum()},
+		}},
+		promptResponse("p2", "make", 10),
+		promptResponse("p3", "ls", 20),
+	}}
+	s := &session{c: mock, id: "sess-1"}
+
+	marks, err := s.ListMarks()
+	if err != nil {
+		t.Fatalf("ListMarks() error = %v", err)
+	}
+	if len(marks) != 2 || marks[0].ID != "p2" || marks[1].ID != "p3" {
+		t.Fatalf("ListMarks() = %+v, want p2 and p3", marks)
+	}
+	if marks[0].Command != "make" || marks[0].WorkingDirectory != "/tmp" || marks[0].Prompt.Start.Y != 10 || marks[0].Output.End.Y != 13 {
+		t.Errorf("marks[0] = %+v", marks[0])
+	}
+	if got := mock.calls[2].GetGetPromptRequest().GetUniquePromptId(); got != "p2" {
+		t.Errorf("requested prompt %q, want p2", got)
+	}
+}
+
+// TestListMarks_SessionNotFound verifies a missing session is reported with ErrSessionNotFound
+func TestListMarks_SessionNotFound(t *testing.T) {
+	s := &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{
+		listPrompts(api.ListPromptsResponse_SESSION_NOT_FOUND),
+	}}, id: "sess-1"}
+	if _, err := s.ListMarks(); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("ListMarks() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestGetLastCommand verifies the newest finished command is returned and missing shell integration is reported
+func TestGetLastCommand(t *testing.T) {
+	finished := promptResponse("p1", "make test", 10)
+	finished.GetGetPromptResponse().PromptState = api.GetPromptResponse_FINISHED.Enum()
+	finished.GetGetPromptResponse().ExitStatus = proto.Uint32(2)
+	editing := promptResponse("p2", "", 20)
+	editing.GetGetPromptResponse().PromptState = api.GetPromptResponse_EDITING.Enum()
+
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		listPrompts(api.ListPromptsResponse_OK, "p1", "p2"), editing, finished,
+	}}
+	s := &session{c: mock, id: "sess-1"}
+	command, exitCode, err := s.GetLastCommand()
+	if err != nil {
+		t.Fatalf("GetLastCommand() error = %v", err)
+	}
+	if command != "make test" || exitCode != 2 {
+		t.Errorf("GetLastCommand() = %q, %d; want %q, 2", command, exitCode, "make test")
+	}
+	if got := mock.calls[1].GetGetPromptRequest().GetUniquePromptId(); got != "p2" {
+		t.Errorf("first requested prompt %q, want the newest, p2", got)
+	}
+
+	s = &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{
+		listPrompts(api.ListPromptsResponse_OK),
+	}}, id: "sess-1"}
+	if _, _, err := s.GetLastCommand(); !errors.Is(err, ErrShellIntegrationUnavailable) {
+		t.Errorf("GetLastCommand() without prompts error = %v, want ErrShellIntegrationUnavailable", err)
+	}
+}
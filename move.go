@@ -0,0 +1,172 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// MoveSession moves s into the target window, as the window's last tab.
+//
+// iTerm2 can only move whole tabs between windows, so s must be the only
+// session in its tab; moving a split pane fails with ErrSessionInSplitTab.
+// Use Tab.MoveToWindow to move a tab with all of its panes. If the move
+// empties the window s was in, iTerm2 closes that window.
+func (a *app) MoveSession(s Session, target Window) error {
+	id, windowID := s.GetSessionID(), target.GetID()
+	lsr, err := listSessions(a.c)
+	if err != nil {
+		return fmt.Errorf("could not move session %q: %w", id, err)
+	}
+	var tabID string
+	for _, w := range lsr.GetWindows() {
+		for _, t := range w.GetTabs() {
+			ids := sessionIDs(t.GetRoot())
+			for _, sid := range ids {
+				if sid != id {
+					continue
+				}
+				if len(ids) > 1 {
+					return fmt.Errorf("could not move session %q: %w; move the whole tab with Tab.MoveToWindow",
+						id, ErrSessionInSplitTab)
+				}
+				tabID = t.GetTabId()
+			}
+		}
+	}
+	if tabID == "" {
+		for _, b := range lsr.GetBuriedSessions() {
+			if b.GetUniqueIdentifier() == id {
+				return fmt.Errorf("could not move session %q: it is buried", id)
+			}
+		}
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, id)
+	}
+	if err := moveTab(a.c, lsr, tabID, windowID); err != nil {
+		return fmt.Errorf("could not move session %q: %w", id, err)
+	}
+	if ss, ok := s.(*session); ok {
+		ss.setLocation(windowID, tabID)
+	}
+	return nil
+}
+
+// MoveToWindow moves the tab into the target window, as its last tab.
+// Moving a tab into the window it already is in does nothing. If the tab was
+// the only one in its window, iTerm2 closes the emptied window.
+func (t *tab) MoveToWindow(target Window) error {
+	lsr, err := listSessions(t.c)
+	if err != nil {
+		return fmt.Errorf("could not move tab %q: %w", t.id, err)
+	}
+	if tabWindow(lsr, t.id) == nil {
+		return fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+	}
+	if err := moveTab(t.c, lsr, t.id, target.GetID()); err != nil {
+		return fmt.Errorf("could not move tab %q: %w", t.id, err)
+	}
+	t.setWindow(target.GetID())
+	return nil
+}
+
+// MoveToNewWindow moves the tab out of its window into a new one of its own
+// and returns the new window. A tab that already is the only one in its
+// window stays where it is, and that window is returned.
+func (t *tab) MoveToNewWindow() (Window, error) {
+	lsr, err := listSessions(t.c)
+	if err != nil {
+		return nil, fmt.Errorf("could not move tab %q to a new window: %w", t.id, err)
+	}
+	w := tabWindow(lsr, t.id)
+	if w == nil {
+		return nil, fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+	}
+	if len(w.GetTabs()) == 1 {
+		t.setWindow(w.GetWindowId())
+		return newWindow(t.c, w), nil
+	}
+	result, err := invokeMethod(t.c, t.id, "iterm2.move_tab_to_window()")
+	if errors.Is(err, errInvalidID) {
+		return nil, fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not move tab %q to a new window: %w", t.id, err)
+	}
+	var windowID string
+	if err := json.Unmarshal([]byte(result), &windowID); err != nil || windowID == "" {
+		return nil, fmt.Errorf("%w: window id %s for tab %q", ErrMalformedResponse, result, t.id)
+	}
+	t.setWindow(windowID)
+	return &window{c: t.c, id: windowID}, nil
+}
+
+// tabWindow returns the window the tab tabID is in, or nil if there is no
+// such tab.
+func tabWindow(lsr *api.ListSessionsResponse, tabID string) *api.ListSessionsResponse_Window {
+	for _, w := range lsr.GetWindows() {
+		for _, t := range w.GetTabs() {
+			if t.GetTabId() == tabID {
+				return w
+			}
+		}
+	}
+	return nil
+}
+
+// moveTab appends the tab tabID to the tabs of the window windowID, unless
+// it already is one of them. lsr is the current layout.
+func moveTab(c ClientInterface, lsr *api.ListSessionsResponse, tabID, windowID string) error {
+	for _, w := range lsr.GetWindows() {
+		if w.GetWindowId() != windowID {
+			continue
+		}
+		var tabIDs []string
+		for _, t := range w.GetTabs() {
+			tabIDs = append(tabIDs, t.GetTabId())
+		}
+		if containsString(tabIDs, tabID) {
+			return nil
+		}
+		return reorderTabs(c, windowID, append(tabIDs, tabID))
+	}
+	return fmt.Errorf("%w: %q", ErrWindowNotFound, windowID)
+}
+
+// reorderTabs makes tabIDs, in order, the tabs of the window windowID. Tabs
+// that belong to other windows are moved into it.
+func reorderTabs(c ClientInterface, windowID string, tabIDs []string) error {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ReorderTabsRequest{
+			ReorderTabsRequest: &api.ReorderTabsRequest{
+				Assignments: []*api.ReorderTabsRequest_Assignment{{
+					WindowId: &windowID,
+					TabIds:   tabIDs,
+				}},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not reorder tabs of window %q: %w", windowID, err)
+	}
+	switch status := resp.GetReorderTabsResponse().GetStatus(); status {
+	case api.ReorderTabsResponse_OK:
+	case api.ReorderTabsResponse_INVALID_WINDOW_ID:
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, windowID)
+	case api.ReorderTabsResponse_INVALID_TAB_ID:
+		return fmt.Errorf("%w: %q", ErrTabNotFound, tabIDs)
+	default:
+		return fmt.Errorf("unexpected status reordering tabs of window %q: %s", windowID, status)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
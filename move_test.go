@@ -0,0 +1,188 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+func reorderTabsOK() *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ReorderTabsResponse{
+			ReorderTabsResponse: &api.ReorderTabsResponse{Status: api.ReorderTabsResponse_OK.Enum()},
+		},
+	}
+}
+
+// TestMoveSession verifies a single-pane session's tab is appended to the target window
+func TestMoveSession(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout(), reorderTabsOK()}}
+	a := &app{c: mock}
+	s := &session{c: mock, id: "sess-5", windowID: "win-2", tabID: "3"}
+
+	if err := a.MoveSession(s, &window{c: mock, id: "win-1"}); err != nil {
+		t.Fatalf("MoveSession() error = %v", err)
+	}
+	if len(mock.calls) != 2 {
+		t.Fatalf("expected 2 Calls, got %d", len(mock.calls))
+	}
+	assignments := mock.calls[1].GetReorderTabsRequest().GetAssignments()
+	if len(assignments) != 1 || assignments[0].GetWindowId() != "win-1" {
+		t.Fatalf("assignments = %v, want a single one for win-1", assignments)
+	}
+	if got := assignments[0].GetTabIds(); len(got) != 3 || got[0] != "1" || got[1] != "2" || got[2] != "3" {
+		t.Errorf("tab ids = %v, want [1 2 3]", got)
+	}
+	if s.windowID != "win-1" || s.tabID != "3" {
+		t.Errorf("session location = %q/%q, want win-1/3", s.windowID, s.tabID)
+	}
+}
+
+// TestMoveSession_Errors verifies sessions that cannot be moved are rejected before reordering
+func TestMoveSession_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		session string
+		window  string
+		want    error
+	}{
+		{name: "split pane", session: "sess-3", window: "win-2", want: ErrSessionInSplitTab},
+		{name: "missing session", session: "sess-9", window: "win-2", want: ErrSessionNotFound},
+		{name: "missing window", session: "sess-5", window: "win-9", want: ErrWindowNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}
+			a := &app{c: mock}
+			err := a.MoveSession(&session{c: mock, id: tt.session}, &window{c: mock, id: tt.window})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("MoveSession() error = %v, want %v", err, tt.want)
+			}
+			if len(mock.calls) != 1 {
+				t.Errorf("expected only the layout Call, got %d", len(mock.calls))
+			}
+		})
+	}
+}
+
+// TestMoveSession_Concurrent verifies a session can be moved while another goroutine asks for its tab
+func TestMoveSession_Concurrent(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout(), reorderTabsOK()}}
+	a := &app{c: mock}
+	s := &session{c: mock, id: "sess-5", windowID: "win-2", tabID: "3"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if _, err := s.GetTab(); err != nil {
+				t.Errorf("GetTab() error = %v", err)
+				return
+			}
+		}
+	}()
+	if err := a.MoveSession(s, &window{c: mock, id: "win-1"}); err != nil {
+		t.Errorf("MoveSession() error = %v", err)
+	}
+	<-done
+	tb, err := s.GetTab()
+	if err != nil {
+		t.Fatalf("GetTab() error = %v", err)
+	}
+	if w, err := tb.GetWindow(); err != nil || w.GetID() != "win-1" {
+		t.Errorf("GetWindow() = %v, %v after the move, want win-1", w, err)
+	}
+}
+
+// TestTabMoveToWindow verifies a tab with several panes is appended to the target window
+func TestTabMoveToWindow(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout(), reorderTabsOK()}}
+	tb := &tab{c: mock, id: "2", windowID: "win-1"}
+
+	if err := tb.MoveToWindow(&window{c: mock, id: "win-2"}); err != nil {
+		t.Fatalf("MoveToWindow() error = %v", err)
+	}
+	assignments := mock.calls[1].GetReorderTabsRequest().GetAssignments()
+	if len(assignments) != 1 || assignments[0].GetWindowId() != "win-2" {
+		t.Fatalf("assignments = %v, want a single one for win-2", assignments)
+	}
+	if got := assignments[0].GetTabIds(); len(got) != 2 || got[0] != "3" || got[1] != "2" {
+		t.Errorf("tab ids = %v, want [3 2]", got)
+	}
+	if tb.windowID != "win-2" {
+		t.Errorf("windowID = %q, want win-2", tb.windowID)
+	}
+
+	tests := []struct {
+		name   string
+		tab    string
+		window string
+		want   error
+	}{
+		{name: "missing tab", tab: "9", window: "win-2", want: ErrTabNotFound},
+		{name: "missing window", tab: "2", window: "win-9", want: ErrWindowNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}
+			tb := &tab{c: mock, id: tt.tab, windowID: "win-1"}
+			if err := tb.MoveToWindow(&window{c: mock, id: tt.window}); !errors.Is(err, tt.want) {
+				t.Errorf("MoveToWindow() error = %v, want %v", err, tt.want)
+			}
+			if len(mock.calls) != 1 || tb.windowID != "win-1" {
+				t.Errorf("expected only the layout Call and no change, got %d Calls and window %q", len(mock.calls), tb.windowID)
+			}
+		})
+	}
+}
+
+// TestTabMoveToWindow_Concurrent verifies a tab can be moved while another goroutine asks for its window
+func TestTabMoveToWindow_Concurrent(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout(), reorderTabsOK()}}
+	tb := &tab{c: mock, id: "2", windowID: "win-1"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if _, err := tb.GetWindow(); err != nil {
+				t.Errorf("GetWindow() error = %v", err)
+				return
+			}
+		}
+	}()
+	if err := tb.MoveToWindow(&window{c: mock, id: "win-2"}); err != nil {
+		t.Errorf("MoveToWindow() error = %v", err)
+	}
+	<-done
+	if w, err := tb.GetWindow(); err != nil || w.GetID() != "win-2" {
+		t.Errorf("GetWindow() = %v, %v after the move, want win-2", w, err)
+	}
+}
+
+// TestTabMoveToNewWindow verifies the tab is moved with move_tab_to_window unless it is already alone
+func TestTabMoveToNewWindow(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout(), invokeSuccess(`"win-3"`)}}
+	tb := &tab{c: mock, id: "1", windowID: "win-1"}
+	w, err := tb.MoveToNewWindow()
+	if err != nil {
+		t.Fatalf("MoveToNewWindow() error = %v", err)
+	}
+	if w.GetID() != "win-3" || tb.windowID != "win-3" {
+		t.Errorf("window = %q, tab window = %q; want win-3", w.GetID(), tb.windowID)
+	}
+	req := mock.calls[1].GetInvokeFunctionRequest()
+	if req.GetMethod().GetReceiver() != "1" || req.GetInvocation() != "iterm2.move_tab_to_window()" {
+		t.Errorf("unexpected InvokeFunctionRequest %v", req)
+	}
+
+	mock = &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}
+	tb = &tab{c: mock, id: "3", windowID: "win-2"}
+	if w, err = tb.MoveToNewWindow(); err != nil {
+		t.Fatalf("MoveToNewWindow() error = %v", err)
+	}
+	if w.GetID() != "win-2" || len(mock.calls) != 1 {
+		t.Errorf("lone tab: window = %q after %d Calls, want win-2 after 1", w.GetID(), len(mock.calls))
+	}
+}
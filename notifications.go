@@ -0,0 +1,312 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// notificationSource is implemented by clients that can deliver the
+// notifications iTerm2 sends for active subscriptions.
+type notificationSource interface {
+	// AddNotificationHandler registers h and returns a function that
+	// unregisters it. Once that function returns, h is not called again.
+	AddNotificationHandler(h func(*api.Notification)) (remove func())
+}
+
+// subscribe registers handler and then asks iTerm2 to start sending the
+// notifications described by req. The returned function unsubscribes; it is
+// safe to call more than once.
+func subscribe(c ClientInterface, req *api.NotificationRequest, handler func(*api.Notification)) (func(), error) {
+	src, ok := c.(notificationSource)
+	if !ok {
+		return nil, errors.New("client does not support notifications")
+	}
+	remove := src.AddNotificationHandler(handler)
+	req.Subscribe = b(true)
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_NotificationRequest{NotificationRequest: req},
+	})
+	if err != nil {
+		remove()
+		return nil, fmt.Errorf("could not subscribe to %s: %w", req.GetNotificationType(), err)
+	}
+	switch status := resp.GetNotificationResponse().GetStatus(); status {
+	case api.NotificationResponse_OK:
+	case api.NotificationResponse_SESSION_NOT_FOUND:
+		remove()
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, req.GetSession())
+	default:
+		remove()
+		return nil, fmt.Errorf("unexpected status subscribing to %s: %s", req.GetNotificationType(), status)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			unsub := proto.Clone(req).(*api.NotificationRequest)
+			unsub.Subscribe = b(false)
+			// Best effort: the connection may already be gone, in
+			// which case there is nothing left to unsubscribe from.
+			c.Call(&api.ClientOriginatedMessage{
+				Submessage: &api.ClientOriginatedMessage_NotificationRequest{NotificationRequest: unsub},
+			})
+		})
+	}, nil
+}
+
+// defaultBufferSize is the number of undelivered events a subscription
+// holds before it starts dropping the oldest ones.
+const defaultBufferSize = 64
+
+// SubscribeOption customizes a subscription.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	profile    string
+	bufferSize int
+	onDrop     func()
+}
+
+func newSubscribeOptions(opts []SubscribeOption) subscribeOptions {
+	o := subscribeOptions{bufferSize: defaultBufferSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.bufferSize < 1 {
+		o.bufferSize = 1
+	}
+	return o
+}
+
+// WithBufferSize sets how many events a subscription holds for a consumer
+// that is not keeping up. When the buffer is full the oldest event is
+// dropped to make room for the new one, so a stuck consumer never stalls
+// the client. Sizes below 1 are treated as 1. The default is 64.
+func WithBufferSize(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.bufferSize = n
+	}
+}
+
+// WithDropHandler registers f to be called every time an event is dropped
+// because the subscription's buffer is full. f runs on the client's read
+// loop, so it must return quickly and must not call iTerm2.
+func WithDropHandler(f func()) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.onDrop = f
+	}
+}
+
+// send delivers v on ch without blocking. If ch is full, the oldest value is
+// discarded to make room. It must only be called from the subscription's
+// notification handler, which is the only sender on ch.
+func (o subscribeOptions) send(ch chan string, v string) {
+	for {
+		select {
+		case ch <- v:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+			if o.onDrop != nil {
+				o.onDrop()
+			}
+		default:
+		}
+	}
+}
+
+// WithProfile limits a session subscription to sessions using the profile
+// with the given GUID. Sessions whose profile was modified after they were
+// created still match the profile they started from. With
+// SubscribeProfileChange, it limits the subscription to that profile.
+func WithProfile(guid string) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.profile = guid
+	}
+}
+
+// SubscribeNewSession delivers every session created from now on, in any
+// window. Call the returned function to stop the subscription; the channel
+// is closed once it returns. Sessions the consumer has not received yet are
+// buffered as described in WithBufferSize.
+//
+// iTerm2 cannot filter new-session notifications by profile, so with
+// WithProfile each new session's profile is looked up before it is
+// delivered, and sessions that are gone by then are skipped.
+func (a *app) SubscribeNewSession(opts ...SubscribeOption) (<-chan Session, func(), error) {
+	o := newSubscribeOptions(opts)
+	ids := make(chan string, o.bufferSize)
+	stop := make(chan struct{})
+	cancel, err := subscribe(a.c, &api.NotificationRequest{
+		NotificationType: api.NotificationType_NOTIFY_ON_NEW_SESSION.Enum(),
+	}, func(n *api.Notification) {
+		ns := n.GetNewSessionNotification()
+		if ns == nil {
+			return
+		}
+		o.send(ids, ns.GetSessionId())
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Profile lookups are calls to iTerm2, so they can't happen in the
+	// notification handler, which runs on the client's read loop.
+	out := make(chan Session)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var id string
+			select {
+			case id = <-ids:
+			case <-stop:
+				return
+			}
+			if o.profile != "" && !usesProfile(a.c, id, o.profile) {
+				continue
+			}
+			select {
+			case out <- &session{c: a.c, id: id}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			cancel()
+			close(out)
+		})
+	}, nil
+}
+
+// SubscribeSessionEnd delivers the id of every session that ends from now
+// on, in any window, whether its program exited or the user closed it. The
+// subscription belongs to the app, so it keeps working as tabs and windows
+// close. Call the returned function to stop the subscription; the channel
+// is closed once it returns. Ids the consumer has not received yet are
+// buffered as described in WithBufferSize; WithProfile does not apply, since
+// ended sessions can no longer be looked up.
+func (a *app) SubscribeSessionEnd(opts ...SubscribeOption) (<-chan string, func(), error) {
+	o := newSubscribeOptions(opts)
+	ch := make(chan string, o.bufferSize)
+	cancel, err := subscribe(a.c, &api.NotificationRequest{
+		NotificationType: api.NotificationType_NOTIFY_ON_TERMINATE_SESSION.Enum(),
+	}, func(n *api.Notification) {
+		if ts := n.GetTerminateSessionNotification(); ts != nil {
+			o.send(ch, ts.GetSessionId())
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cancel()
+			close(ch)
+		})
+	}, nil
+}
+
+// SubscribeBroadcastDomainChange delivers iTerm2's current broadcast domains,
+// then the domains every time they change. A broadcast domain is a group of sessions that receive
+// the keystrokes typed into any of them; each is delivered as the ids of its
+// sessions, and an empty list means input is not broadcast at all. Call the
+// returned function to stop the subscription; the channel is closed once it
+// returns.
+//
+// Every change carries the complete set of domains, so only the latest one
+// is kept for a consumer that is not keeping up. If onDrop is not nil, it is
+// called whenever an undelivered change is replaced; like a WithDropHandler
+// function, it runs on the client's read loop, so it must return quickly and
+// must not call iTerm2.
+func (a *app) SubscribeBroadcastDomainChange(onDrop func()) (<-chan [][]string, func(), error) {
+	ch := make(chan [][]string, 1)
+	// mu makes replacing the stale value and sending the new one a single
+	// step, so the send cannot block. changed records that a change was
+	// delivered, which is at least as recent as the domains read below.
+	var mu sync.Mutex
+	changed := false
+	deliver := func(domains [][]string) {
+		select {
+		case <-ch:
+			if onDrop != nil {
+				onDrop()
+			}
+		default:
+		}
+		ch <- domains
+	}
+	cancel, err := subscribe(a.c, &api.NotificationRequest{
+		NotificationType: api.NotificationType_NOTIFY_ON_BROADCAST_CHANGE.Enum(),
+	}, func(n *api.Notification) {
+		if bc := n.GetBroadcastDomainsChanged(); bc != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			changed = true
+			deliver(broadcastDomains(bc.GetBroadcastDomains()))
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := a.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_GetBroadcastDomainsRequest{
+			GetBroadcastDomainsRequest: &api.GetBroadcastDomainsRequest{},
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("could not get broadcast domains: %w", err)
+	}
+	mu.Lock()
+	if !changed {
+		deliver(broadcastDomains(resp.GetGetBroadcastDomainsResponse().GetBroadcastDomains()))
+	}
+	mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cancel()
+			close(ch)
+		})
+	}, nil
+}
+
+// broadcastDomains returns the session ids of each domain.
+func broadcastDomains(list []*api.BroadcastDomain) [][]string {
+	domains := make([][]string, 0, len(list))
+	for _, d := range list {
+		domains = append(domains, append([]string{}, d.GetSessionIds()...))
+	}
+	return domains
+}
+
+// usesProfile reports whether the session sessionID was created from the
+// profile with the given GUID.
+func usesProfile(c ClientInterface, sessionID, guid string) bool {
+	props, err := getProfileProperties(c, sessionID, "Guid", "Original Guid")
+	if err != nil {
+		return false
+	}
+	for _, key := range []string{"Guid", "Original Guid"} {
+		var got string
+		if json.Unmarshal([]byte(props[key]), &got) == nil && got == guid {
+			return true
+		}
+	}
+	return false
+}
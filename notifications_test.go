@@ -0,0 +1,211 @@
+package iterm2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+func newSession(id string) *api.Notification {
+	return &api.Notification{
+		NewSessionNotification: &api.NewSessionNotification{SessionId: str(id)},
+	}
+}
+
+// profileClient answers profile lookups with the given Guid per session
+func profileClient(guids map[string]string) *mockClient {
+	return &mockClient{
+		callFunc: func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+			gpr := req.GetGetProfilePropertyRequest()
+			if gpr == nil {
+				return notificationOK(), nil
+			}
+			guid, ok := guids[gpr.GetSession()]
+			if !ok {
+				return &api.ServerOriginatedMessage{
+					Submessage: &api.ServerOriginatedMessage_GetProfilePropertyResponse{
+						GetProfilePropertyResponse: &api.GetProfilePropertyResponse{
+							Status: api.GetProfilePropertyResponse_SESSION_NOT_FOUND.Enum(),
+						},
+					},
+				}, nil
+			}
+			return &api.ServerOriginatedMessage{
+				Submessage: &api.ServerOriginatedMessage_GetProfilePropertyResponse{
+					GetProfilePropertyResponse: &api.GetProfilePropertyResponse{
+						Properties: []*api.ProfileProperty{
+							{Key: str("Guid"), JsonValue: str(`"` + guid + `"`)},
+							{Key: str("Original Guid"), JsonValue: str(`"` + guid + `"`)},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+}
+
+// TestSubscribeNewSession verifies every new session is delivered without a filter
+func TestSubscribeNewSession(t *testing.T) {
+	mock := profileClient(nil)
+	a := &app{c: mock}
+
+	ch, cancel, err := a.SubscribeNewSession()
+	if err != nil {
+		t.Fatalf("SubscribeNewSession() error = %v", err)
+	}
+	go mock.notify(newSession("sess-1"))
+	if s := <-ch; s.GetSessionID() != "sess-1" {
+		t.Errorf("received %q, want %q", s.GetSessionID(), "sess-1")
+	}
+	cancel()
+
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if len(mock.calls) != 2 {
+		t.Fatalf("expected subscribe and unsubscribe only, got %d calls", len(mock.calls))
+	}
+	if req := mock.calls[0].GetNotificationRequest(); req.GetNotificationType() != api.NotificationType_NOTIFY_ON_NEW_SESSION {
+		t.Errorf("unexpected subscription %v", req)
+	}
+}
+
+// TestSubscribeNewSession_WithProfile verifies only sessions using the profile are delivered
+func TestSubscribeNewSession_WithProfile(t *testing.T) {
+	mock := profileClient(map[string]string{
+		"sess-1": "OTHER",
+		"sess-3": "GUID",
+	})
+	a := &app{c: mock}
+
+	ch, cancel, err := a.SubscribeNewSession(WithProfile("GUID"))
+	if err != nil {
+		t.Fatalf("SubscribeNewSession() error = %v", err)
+	}
+	defer cancel()
+	go func() {
+		mock.notify(newSession("sess-1"))
+		mock.notify(newSession("sess-2"))
+		mock.notify(newSession("sess-3"))
+	}()
+	if s := <-ch; s.GetSessionID() != "sess-3" {
+		t.Errorf("received %q, want %q", s.GetSessionID(), "sess-3")
+	}
+}
+
+// broadcastDomainsChanged returns a notification of the given broadcast domains
+func broadcastDomainsChanged(domains ...[]string) *api.Notification {
+	bc := &api.BroadcastDomainsChangedNotification{}
+	for _, ids := range domains {
+		bc.BroadcastDomains = append(bc.BroadcastDomains, &api.BroadcastDomain{SessionIds: ids})
+	}
+	return &api.Notification{BroadcastDomainsChanged: bc}
+}
+
+// TestSubscribeBroadcastDomainChange verifies the current domains and then every change are delivered, keeping only the latest
+func TestSubscribeBroadcastDomainChange(t *testing.T) {
+	current := &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_GetBroadcastDomainsResponse{
+			GetBroadcastDomainsResponse: &api.GetBroadcastDomainsResponse{
+				BroadcastDomains: []*api.BroadcastDomain{{SessionIds: []string{"sess-5", "sess-6"}}},
+			},
+		},
+	}
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{notificationOK(), current, notificationOK()}}
+	a := &app{c: mock}
+
+	dropped := 0
+	ch, cancel, err := a.SubscribeBroadcastDomainChange(func() { dropped++ })
+	if err != nil {
+		t.Fatalf("SubscribeBroadcastDomainChange() error = %v", err)
+	}
+	if req := mock.calls[0].GetNotificationRequest(); req.GetNotificationType() != api.NotificationType_NOTIFY_ON_BROADCAST_CHANGE {
+		t.Errorf("unexpected subscription %v", req)
+	}
+	if mock.calls[1].GetGetBroadcastDomainsRequest() == nil {
+		t.Errorf("expected the current domains to be requested, got %v", mock.calls[1])
+	}
+	if got := <-ch; len(got) != 1 || strings.Join(got[0], ",") != "sess-5,sess-6" {
+		t.Errorf("received %v, want the current domains", got)
+	}
+	mock.notify(newSession("sess-9"))
+	mock.notify(broadcastDomainsChanged([]string{"sess-1", "sess-2"}))
+	mock.notify(broadcastDomainsChanged([]string{"sess-1", "sess-2"}, []string{"sess-3", "sess-4"}))
+	got := <-ch
+	if len(got) != 2 || strings.Join(got[0], ",") != "sess-1,sess-2" || strings.Join(got[1], ",") != "sess-3,sess-4" {
+		t.Errorf("received %v, want the latest domains", got)
+	}
+	if dropped != 1 {
+		t.Errorf("dropped %d changes, want 1", dropped)
+	}
+	mock.notify(broadcastDomainsChanged())
+	if got := <-ch; got == nil || len(got) != 0 {
+		t.Errorf("received %v, want no domains", got)
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if len(mock.calls) != 3 || mock.calls[2].GetNotificationRequest().GetSubscribe() {
+		t.Errorf("expected a single unsubscribe request, got %d calls", len(mock.calls))
+	}
+}
+
+// TestSubscribeBroadcastDomainChange_ChangeBeforeRead verifies a change delivered while the current domains are read is not overwritten by them
+func TestSubscribeBroadcastDomainChange_ChangeBeforeRead(t *testing.T) {
+	mock := &mockClient{}
+	mock.callFunc = func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		if req.GetGetBroadcastDomainsRequest() != nil {
+			mock.notify(broadcastDomainsChanged([]string{"sess-1", "sess-2"}))
+		}
+		return &api.ServerOriginatedMessage{}, nil
+	}
+	a := &app{c: mock}
+
+	ch, cancel, err := a.SubscribeBroadcastDomainChange(nil)
+	if err != nil {
+		t.Fatalf("SubscribeBroadcastDomainChange() error = %v", err)
+	}
+	defer cancel()
+	if got := <-ch; len(got) != 1 || strings.Join(got[0], ",") != "sess-1,sess-2" {
+		t.Errorf("received %v, want the changed domains", got)
+	}
+	select {
+	case got := <-ch:
+		t.Errorf("received %v after the change, want nothing", got)
+	default:
+	}
+}
+
+// TestSubscribeSessionEnd verifies ended session ids are delivered and the subscription is cancelled
+func TestSubscribeSessionEnd(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{notificationOK(), notificationOK()}}
+	a := &app{c: mock}
+
+	ch, cancel, err := a.SubscribeSessionEnd()
+	if err != nil {
+		t.Fatalf("SubscribeSessionEnd() error = %v", err)
+	}
+	if req := mock.calls[0].GetNotificationRequest(); req.GetNotificationType() != api.NotificationType_NOTIFY_ON_TERMINATE_SESSION {
+		t.Errorf("unexpected subscription %v", req)
+	}
+	mock.notify(newSession("sess-1"))
+	mock.notify(&api.Notification{
+		TerminateSessionNotification: &api.TerminateSessionNotification{SessionId: str("sess-2")},
+	})
+	if id := <-ch; id != "sess-2" {
+		t.Errorf("received %q, want %q", id, "sess-2")
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if len(mock.calls) != 2 || mock.calls[1].GetNotificationRequest().GetSubscribe() {
+		t.Errorf("expected a single unsubscribe request, got %d calls", len(mock.calls))
+	}
+}
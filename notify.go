@@ -0,0 +1,61 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// notificationSettings are the profile keys that must not be turned off for
+// iTerm2 to post a notification requested by an escape sequence: "Send
+// notification center alerts" and its "Escape sequence-generated alerts"
+// filter in the profile's Terminal settings.
+var notificationSettings = []string{"BM Growl", "Send Terminal Generated Alerts"}
+
+// PostNotification shows a notification in the macOS Notification Center,
+// as iTerm2 does for a program that writes the OSC 9 escape sequence. The
+// sequence is injected into the current session, so the notification
+// belongs to that session and clicking it brings the session to the front.
+//
+// OSC 9 carries a single message, so title and body are joined as
+// "title: body"; either may be empty, but not both. Neither may contain
+// control characters, which would end the escape sequence early.
+//
+// It returns ErrNotificationsDisabled if the current session's profile has
+// notifications turned off. Whether macOS itself allows iTerm2 to show
+// notifications, or holds them back in Do Not Disturb, cannot be detected
+// through the API, so no error is returned in those cases.
+func (a *app) PostNotification(title, body string) error {
+	message := title
+	if body != "" {
+		if message != "" {
+			message += ": "
+		}
+		message += body
+	}
+	if message == "" {
+		return fmt.Errorf("could not post notification: title and body are empty")
+	}
+	if i := strings.IndexFunc(message, unicode.IsControl); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(message[i:])
+		return fmt.Errorf("could not post notification: control character %q in message", r)
+	}
+	s, err := a.currentSession()
+	if err != nil {
+		return fmt.Errorf("could not post notification: %w", err)
+	}
+	props, err := getProfileProperties(a.c, s.id, notificationSettings...)
+	if err != nil {
+		return fmt.Errorf("could not post notification to session %q: %w", s.id, err)
+	}
+	// Unset keys take iTerm2's defaults, which are on.
+	for _, key := range notificationSettings {
+		var on bool
+		if json.Unmarshal([]byte(props[key]), &on) == nil && !on {
+			return fmt.Errorf("%w: %q is off in the profile of session %q", ErrNotificationsDisabled, key, s.id)
+		}
+	}
+	return s.inject([]byte("\x1b]9;" + message + "\x07"))
+}
@@ -0,0 +1,95 @@
+package iterm2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestPostNotification verifies the message is sent as OSC 9 to the current session unless its profile turns notifications off
+func TestPostNotification(t *testing.T) {
+	tests := []struct {
+		name        string
+		title, body string
+		props       *api.ServerOriginatedMessage
+		want        string
+		wantErr     error
+	}{
+		{
+			name:  "title and body",
+			title: "Build",
+			body:  "done in 42s ✅",
+			props: profileProperties(api.GetProfilePropertyResponse_OK,
+				"BM Growl", "true", "Send Terminal Generated Alerts", "true"),
+			want: "\x1b]9;Build: done in 42s ✅\x07",
+		},
+		{
+			name:  "title only with default settings",
+			title: "Build done",
+			props: profileProperties(api.GetProfilePropertyResponse_OK),
+			want:  "\x1b]9;Build done\x07",
+		},
+		{
+			name:    "notifications off",
+			title:   "Build done",
+			props:   profileProperties(api.GetProfilePropertyResponse_OK, "BM Growl", "false"),
+			wantErr: ErrNotificationsDisabled,
+		},
+		{
+			name:  "escape sequence alerts filtered",
+			title: "Build done",
+			props: profileProperties(api.GetProfilePropertyResponse_OK,
+				"BM Growl", "true", "Send Terminal Generated Alerts", "false"),
+			wantErr: ErrNotificationsDisabled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+				focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, "win-1",
+					[]string{"2"}, []string{"sess-3"}),
+				layout(),
+				tt.props,
+			}}
+			a := &app{c: mock}
+			err := a.PostNotification(tt.title, tt.body)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("PostNotification() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(mock.calls) != 3 {
+					t.Errorf("expected no inject after 3 Calls, got %d Calls", len(mock.calls))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PostNotification() error = %v", err)
+			}
+			if req := mock.calls[2].GetGetProfilePropertyRequest(); req.GetSession() != "sess-3" {
+				t.Errorf("settings read from session %q, want sess-3", req.GetSession())
+			}
+			req := mock.calls[3].GetInjectRequest()
+			if len(req.GetSessionId()) != 1 || req.GetSessionId()[0] != "sess-3" {
+				t.Errorf("session ids = %v, want [sess-3]", req.GetSessionId())
+			}
+			if string(req.GetData()) != tt.want {
+				t.Errorf("data = %q, want %q", req.GetData(), tt.want)
+			}
+		})
+	}
+}
+
+// TestPostNotification_Invalid verifies empty messages and control characters are rejected before calling iTerm2
+func TestPostNotification_Invalid(t *testing.T) {
+	for _, msg := range [][2]string{{"", ""}, {"Build\x07", "done"}, {"Build", "two\nlines"}} {
+		mock := &mockClient{}
+		if err := (&app{c: mock}).PostNotification(msg[0], msg[1]); err == nil || len(mock.calls) != 0 {
+			t.Errorf("PostNotification(%q, %q) expected error without Calls, got %v and %d calls", msg[0], msg[1], err, len(mock.calls))
+		}
+	}
+	if err := (&app{c: &mockClient{}}).PostNotification("Build", "next\u0085line"); err == nil || !strings.Contains(err.Error(), `'\u0085'`) {
+		t.Errorf("PostNotification() error = %v, want it to name U+0085", err)
+	}
+}
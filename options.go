@@ -0,0 +1,64 @@
+package iterm2
+
+import (
+	"log"
+	"time"
+
+	"github.com/Tombar/iterm2/client"
+)
+
+// Option customizes how NewApp connects to iTerm2.
+type Option func(*appOptions)
+
+type appOptions struct {
+	client    client.Options
+	reconnect bool
+}
+
+func newAppOptions(opts []Option) appOptions {
+	var o appOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTimeout bounds how long connecting to iTerm2 may take. The default is
+// 45 seconds. It does not limit the calls made once connected.
+func WithTimeout(d time.Duration) Option {
+	return func(o *appOptions) {
+		o.client.Timeout = d
+	}
+}
+
+// WithSocketPath connects to iTerm2's API socket at path instead of the
+// default location, which GetSocketPath returns.
+func WithSocketPath(path string) Option {
+	return func(o *appOptions) {
+		o.client.SocketPath = path
+	}
+}
+
+// WithLogger sends the library's reports of unexpected messages from iTerm2
+// to l instead of standard error.
+func WithLogger(l *log.Logger) Option {
+	return func(o *appOptions) {
+		o.client.Logger = l
+	}
+}
+
+// WithMaxMessageSize caps the size in bytes of a single message read from
+// iTerm2, as described in client.Options. The default is 64 MiB.
+func WithMaxMessageSize(n int64) Option {
+	return func(o *appOptions) {
+		o.client.MaxMessageSize = n
+	}
+}
+
+// WithReconnect makes the App survive iTerm2 restarts, as described in
+// NewReconnectingApp.
+func WithReconnect() Option {
+	return func(o *appOptions) {
+		o.reconnect = true
+	}
+}
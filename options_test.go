@@ -0,0 +1,27 @@
+package iterm2
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestAppOptions verifies every option lands in the connection settings
+func TestAppOptions(t *testing.T) {
+	logger := log.New(os.Stderr, "iterm2: ", 0)
+	o := newAppOptions([]Option{
+		WithTimeout(3 * time.Second),
+		WithSocketPath("/tmp/iterm2.sock"),
+		WithLogger(logger),
+		WithMaxMessageSize(1 << 20),
+		WithReconnect(),
+	})
+	if o.client.Timeout != 3*time.Second || o.client.SocketPath != "/tmp/iterm2.sock" || o.client.Logger != logger ||
+		o.client.MaxMessageSize != 1<<20 || !o.reconnect {
+		t.Errorf("options = %+v", o)
+	}
+	if o := newAppOptions(nil); o.reconnect || o.client.SocketPath != "" || o.client.Timeout != 0 || o.client.Logger != nil {
+		t.Errorf("default options = %+v, want the zero value", o)
+	}
+}
@@ -0,0 +1,93 @@
+package iterm2
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+)
+
+// PasteText sends text in chunks of pasteChunkSize bytes, pausing for
+// pasteChunkDelay between them.
+var (
+	pasteChunkSize  = 1024
+	pasteChunkDelay = 10 * time.Millisecond
+)
+
+// PasteText types text into the session like SendText, but in chunks of
+// about a kilobyte with a short pause after each one, the way iTerm2 itself
+// pastes. SendText hands all of the text to the terminal at once, and a
+// program that does not read its input quickly enough can lose bytes once
+// the terminal's input buffer is full. Use PasteText for multi-kilobyte
+// content such as a configuration file; it takes about 10ms per kilobyte.
+//
+// As with SendText, the text is sent verbatim: nothing is appended to it
+// and no bracketed paste markers are added.
+func (s *session) PasteText(text string) error {
+	for i, chunk := range splitChunks(text, pasteChunkSize) {
+		if i > 0 {
+			time.Sleep(pasteChunkDelay)
+		}
+		if err := s.SendText(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TypeSlowly types text into the session one character at a time, pausing
+// for perCharDelay between characters, for programs that drop or
+// misinterpret input arriving faster than a person could type it, such as
+// some password prompts. Each character is a separate SendText call, so
+// combining marks arrive separately from the letter they modify.
+//
+// TypeSlowly returns once the last character is sent, or at the first one
+// that fails to be; the characters before it have been typed by then.
+func (s *session) TypeSlowly(text string, perCharDelay time.Duration) error {
+	return s.TypeSlowlyContext(context.Background(), text, perCharDelay)
+}
+
+// TypeSlowlyContext is like TypeSlowly, but stops typing once ctx is done
+// and returns ctx.Err(). It checks ctx before each character, so a long
+// text can be abandoned part way through.
+func (s *session) TypeSlowlyContext(ctx context.Context, text string, perCharDelay time.Duration) error {
+	i := 0
+	for _, r := range text {
+		if i > 0 {
+			t := time.NewTimer(perCharDelay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		i++
+		if err := s.SendText(string(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitChunks splits text into pieces of at most size bytes without
+// breaking up UTF-8 encoded characters.
+func splitChunks(text string, size int) []string {
+	var chunks []string
+	for len(text) > size {
+		n := size
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		if n == 0 {
+			n = size
+		}
+		chunks = append(chunks, text[:n])
+		text = text[n:]
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
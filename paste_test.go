@@ -0,0 +1,140 @@
+package iterm2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestSplitChunks verifies chunks respect the size limit without splitting characters
+func TestSplitChunks(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		size int
+		want []string
+	}{
+		{name: "empty", text: "", size: 4, want: nil},
+		{name: "short", text: "abc", size: 4, want: []string{"abc"}},
+		{name: "exact", text: "abcdefgh", size: 4, want: []string{"abcd", "efgh"}},
+		{name: "multibyte", text: "aé€b", size: 4, want: []string{"aé", "€b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitChunks(tt.text, tt.size)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("splitChunks(%q, %d) = %q, want %q", tt.text, tt.size, got, tt.want)
+			}
+			for _, c := range got {
+				if len(c) > tt.size || !utf8.ValidString(c) {
+					t.Errorf("invalid chunk %q", c)
+				}
+			}
+		})
+	}
+}
+
+// TestPasteText verifies long text is sent in order in several chunks
+func TestPasteText(t *testing.T) {
+	defer func(size int) { pasteChunkSize = size }(pasteChunkSize)
+	pasteChunkSize = 8
+
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	text := strings.Repeat("line é\n", 5)
+	if err := s.PasteText(text); err != nil {
+		t.Fatalf("PasteText() error = %v", err)
+	}
+	if len(mock.calls) < 2 {
+		t.Fatalf("expected several SendText calls, got %d", len(mock.calls))
+	}
+	var sent strings.Builder
+	for _, call := range mock.calls {
+		req := call.GetSendTextRequest()
+		if req.GetSession() != "sess-1" || len(req.GetText()) > 8 {
+			t.Errorf("unexpected SendTextRequest %v", req)
+		}
+		sent.WriteString(req.GetText())
+	}
+	if sent.String() != text {
+		t.Errorf("sent %q, want %q", sent.String(), text)
+	}
+}
+
+// TestPasteText_StopsOnError verifies no further chunks are sent once one fails
+func TestPasteText_StopsOnError(t *testing.T) {
+	defer func(size int) { pasteChunkSize = size }(pasteChunkSize)
+	pasteChunkSize = 2
+
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{{
+		Submessage: &api.ServerOriginatedMessage_SendTextResponse{
+			SendTextResponse: &api.SendTextResponse{Status: api.SendTextResponse_SESSION_NOT_FOUND.Enum()},
+		},
+	}}}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.PasteText("abcdef"); err == nil {
+		t.Fatal("PasteText() expected error, got nil")
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("expected 1 Call, got %d", len(mock.calls))
+	}
+}
+
+// TestTypeSlowly verifies each character is sent on its own with the delay between them
+func TestTypeSlowly(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	start := time.Now()
+	if err := s.TypeSlowly("pé€\r", 5*time.Millisecond); err != nil {
+		t.Fatalf("TypeSlowly() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("TypeSlowly() took %v, want at least 15ms for three pauses", elapsed)
+	}
+	want := []string{"p", "é", "€", "\r"}
+	if len(mock.calls) != len(want) {
+		t.Fatalf("expected %d Calls, got %d", len(want), len(mock.calls))
+	}
+	for i, call := range mock.calls {
+		if got := call.GetSendTextRequest().GetText(); got != want[i] {
+			t.Errorf("call %d sent %q, want %q", i, got, want[i])
+		}
+	}
+
+	mock = &mockClient{responses: []*api.ServerOriginatedMessage{sendTextOK(), {
+		Submessage: &api.ServerOriginatedMessage_SendTextResponse{
+			SendTextResponse: &api.SendTextResponse{Status: api.SendTextResponse_SESSION_NOT_FOUND.Enum()},
+		},
+	}}}
+	s = &session{c: mock, id: "sess-1"}
+	if err := s.TypeSlowly("abc", 0); !errors.Is(err, ErrSessionNotFound) || len(mock.calls) != 2 {
+		t.Errorf("TypeSlowly() error = %v after %d calls, want ErrSessionNotFound after 2", err, len(mock.calls))
+	}
+}
+
+// TestTypeSlowlyContext verifies typing stops between characters once the context is done
+func TestTypeSlowlyContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	mock := &mockClient{callFunc: func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		cancel()
+		return sendTextOK(), nil
+	}}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.TypeSlowlyContext(ctx, "abc", time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("TypeSlowlyContext() error = %v, want context.Canceled", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("expected 1 Call, got %d", len(mock.calls))
+	}
+
+	mock = &mockClient{}
+	s = &session{c: mock, id: "sess-1"}
+	if err := s.TypeSlowlyContext(ctx, "abc", 0); !errors.Is(err, context.Canceled) || len(mock.calls) != 0 {
+		t.Errorf("TypeSlowlyContext() error = %v after %d calls, want context.Canceled after 0", err, len(mock.calls))
+	}
+}
@@ -0,0 +1,197 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// profileDebounce is how long a profile has to stay unchanged before
+// SubscribeProfileChange reports the edits made to it.
+var profileDebounce = 250 * time.Millisecond
+
+// ProfileChange describes edits to a profile in iTerm2's settings.
+type ProfileChange struct {
+	// GUID identifies the profile.
+	GUID string
+	// Keys lists the profile keys whose values changed, such as
+	// "Background Color", in alphabetical order. Keys that were added or
+	// removed are included.
+	Keys []string
+}
+
+// listProfiles returns the properties of the profiles with the given GUIDs,
+// or of all profiles if there are none, keyed by GUID. The values are
+// JSON-encoded.
+func listProfiles(c ClientInterface, guids ...string) (map[string]map[string]string, error) {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ListProfilesRequest{
+			ListProfilesRequest: &api.ListProfilesRequest{Guids: guids},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list profiles: %w", err)
+	}
+	profiles := map[string]map[string]string{}
+	for _, p := range resp.GetListProfilesResponse().GetProfiles() {
+		props := make(map[string]string, len(p.GetProperties()))
+		for _, prop := range p.GetProperties() {
+			props[prop.GetKey()] = prop.GetJsonValue()
+		}
+		var guid string
+		if err := json.Unmarshal([]byte(props["Guid"]), &guid); err != nil || guid == "" {
+			continue
+		}
+		profiles[guid] = props
+	}
+	return profiles, nil
+}
+
+// SubscribeProfileChange delivers a ProfileChange every time the user edits
+// a profile in iTerm2's settings. Edits made in quick succession, as when
+// dragging a color picker, are coalesced into a single change once the
+// profile has stayed unchanged for a moment. Call the returned function to
+// stop the subscription; the channel is closed once it returns.
+//
+// iTerm2 only notifies about profiles it is asked about by GUID, so the
+// subscription covers the profiles that exist when it is made, not ones
+// created later; WithProfile narrows it down to a single profile. Changes
+// to a session's own copy of its profile, as made by the Session setters,
+// are not reported; see IsProfileDivorced.
+//
+// Notifications the subscription has not read the profile for yet are
+// buffered as described in WithBufferSize. If reading a profile fails, its
+// edits are looked for again on the next notification.
+func (a *app) SubscribeProfileChange(opts ...SubscribeOption) (<-chan ProfileChange, func(), error) {
+	o := newSubscribeOptions(opts)
+	var profiles map[string]map[string]string
+	var err error
+	if o.profile != "" {
+		profiles, err = listProfiles(a.c, o.profile)
+	} else {
+		profiles, err = listProfiles(a.c)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	guids := make([]string, 0, len(profiles))
+	for guid := range profiles {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+
+	changed := make(chan string, o.bufferSize)
+	var cancels []func()
+	cancelAll := func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+	for _, guid := range guids {
+		guid := guid
+		cancel, err := subscribe(a.c, &api.NotificationRequest{
+			NotificationType: api.NotificationType_NOTIFY_ON_PROFILE_CHANGE.Enum(),
+			Arguments: &api.NotificationRequest_ProfileChangeRequest{
+				ProfileChangeRequest: &api.ProfileChangeRequest{Guid: &guid},
+			},
+		}, func(n *api.Notification) {
+			if pc := n.GetProfileChangedNotification(); pc != nil && pc.GetGuid() == guid {
+				o.send(changed, guid)
+			}
+		})
+		if err != nil {
+			cancelAll()
+			return nil, nil, fmt.Errorf("could not watch profile %q: %w", guid, err)
+		}
+		cancels = append(cancels, cancel)
+	}
+
+	out := make(chan ProfileChange)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pending := map[string]bool{}
+		var settled <-chan time.Time
+		for {
+			select {
+			case guid := <-changed:
+				pending[guid] = true
+				settled = time.After(profileDebounce)
+			case <-settled:
+				settled = nil
+				// The notification only names the profile; what
+				// changed is found by comparing it with the last
+				// known properties.
+				for _, guid := range sortedKeys(pending) {
+					current, err := listProfiles(a.c, guid)
+					if err != nil {
+						// Left pending for the next
+						// notification.
+						continue
+					}
+					delete(pending, guid)
+					props, ok := current[guid]
+					if !ok {
+						// The profile was deleted.
+						delete(profiles, guid)
+						continue
+					}
+					keys := changedKeys(profiles[guid], props)
+					profiles[guid] = props
+					if len(keys) == 0 {
+						continue
+					}
+					select {
+					case out <- ProfileChange{GUID: guid, Keys: keys}:
+					case <-stop:
+						return
+					}
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return out, func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+			cancelAll()
+			close(out)
+		})
+	}, nil
+}
+
+// changedKeys returns the keys whose values differ between old and cur, in
+// alphabetical order.
+func changedKeys(old, cur map[string]string) []string {
+	var keys []string
+	for k, v := range cur {
+		if ov, ok := old[k]; !ok || ov != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range old {
+		if _, ok := cur[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
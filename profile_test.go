@@ -0,0 +1,147 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// profiles is a canned ListProfilesResponse with one profile per property map
+func profiles(props ...map[string]string) *api.ServerOriginatedMessage {
+	var list []*api.ListProfilesResponse_Profile
+	for _, p := range props {
+		profile := &api.ListProfilesResponse_Profile{}
+		for k, v := range p {
+			profile.Properties = append(profile.Properties, &api.ProfileProperty{Key: str(k), JsonValue: str(v)})
+		}
+		list = append(list, profile)
+	}
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ListProfilesResponse{
+			ListProfilesResponse: &api.ListProfilesResponse{Profiles: list},
+		},
+	}
+}
+
+// TestSubscribeProfileChange verifies a burst of edits is reported once per profile with the keys that changed
+func TestSubscribeProfileChange(t *testing.T) {
+	defer func(d time.Duration) { profileDebounce = d }(profileDebounce)
+	profileDebounce = 50 * time.Millisecond
+
+	a1 := map[string]string{"Guid": `"A"`, "Name": `"Work"`, "Background Color": `"black"`, "Blur": `false`}
+	a2 := map[string]string{"Guid": `"A"`, "Name": `"Work"`, "Background Color": `"navy"`, "Transparency": `0.1`}
+	b := map[string]string{"Guid": `"B"`, "Name": `"Home"`}
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		profiles(a1, b),
+		notificationOK(),
+		notificationOK(),
+		profiles(a2),
+		profiles(b),
+	}}
+	a := &app{c: mock}
+
+	changes, cancel, err := a.SubscribeProfileChange()
+	if err != nil {
+		t.Fatalf("SubscribeProfileChange() error = %v", err)
+	}
+	for i, want := range []string{"A", "B"} {
+		req := mock.calls[i+1].GetNotificationRequest()
+		if req.GetNotificationType() != api.NotificationType_NOTIFY_ON_PROFILE_CHANGE || req.GetProfileChangeRequest().GetGuid() != want {
+			t.Errorf("subscription %d = %v, want profile %s", i, req, want)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		mock.notify(&api.Notification{ProfileChangedNotification: &api.ProfileChangedNotification{Guid: str("A")}})
+	}
+	mock.notify(&api.Notification{ProfileChangedNotification: &api.ProfileChangedNotification{Guid: str("B")}})
+
+	select {
+	case c := <-changes:
+		want := []string{"Background Color", "Blur", "Transparency"}
+		if c.GUID != "A" || len(c.Keys) != len(want) {
+			t.Fatalf("change = %+v, want A with %v", c, want)
+		}
+		for i := range want {
+			if c.Keys[i] != want[i] {
+				t.Errorf("keys = %v, want %v", c.Keys, want)
+				break
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change delivered")
+	}
+	cancel()
+	if c, ok := <-changes; ok {
+		t.Errorf("expected no change for the unchanged profile, got %+v", c)
+	}
+	if len(mock.calls) != 7 {
+		t.Fatalf("expected each profile to be read once after the burst and then unsubscribed, got %d calls", len(mock.calls))
+	}
+	if guids := mock.calls[3].GetListProfilesRequest().GetGuids(); len(guids) != 1 || guids[0] != "A" {
+		t.Errorf("reread profiles %v, want [A]", guids)
+	}
+}
+
+// TestSubscribeProfileChange_RetriesFailedRead verifies an edit whose profile could not be read is reported after the next notification
+func TestSubscribeProfileChange_RetriesFailedRead(t *testing.T) {
+	defer func(d time.Duration) { profileDebounce = d }(profileDebounce)
+	profileDebounce = 10 * time.Millisecond
+
+	a1 := map[string]string{"Guid": `"A"`, "Blur": `false`}
+	a2 := map[string]string{"Guid": `"A"`, "Blur": `true`}
+	failed := make(chan struct{})
+	reads := 0
+	mock := &mockClient{}
+	mock.callFunc = func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		if req.GetListProfilesRequest() == nil {
+			return notificationOK(), nil
+		}
+		// The profile is read when subscribing, then fails once.
+		reads++
+		switch reads {
+		case 1:
+			return profiles(a1), nil
+		case 2:
+			close(failed)
+			return nil, errors.New("connection lost")
+		default:
+			return profiles(a2), nil
+		}
+	}
+	a := &app{c: mock}
+
+	changes, cancel, err := a.SubscribeProfileChange(WithProfile("A"), WithBufferSize(1))
+	if err != nil {
+		t.Fatalf("SubscribeProfileChange() error = %v", err)
+	}
+	defer cancel()
+	if guids := mock.calls[0].GetListProfilesRequest().GetGuids(); len(guids) != 1 || guids[0] != "A" {
+		t.Errorf("listed profiles %v, want [A]", guids)
+	}
+	notify := func() {
+		mock.notify(&api.Notification{ProfileChangedNotification: &api.ProfileChangedNotification{Guid: str("A")}})
+	}
+	notify()
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("profile never read")
+	}
+	select {
+	case c := <-changes:
+		t.Fatalf("unexpected change %+v after a failed read", c)
+	case <-time.After(5 * profileDebounce):
+	}
+
+	notify()
+	select {
+	case c := <-changes:
+		if c.GUID != "A" || len(c.Keys) != 1 || c.Keys[0] != "Blur" {
+			t.Errorf("change = %+v, want A with [Blur]", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change delivered after the retry")
+	}
+}
@@ -0,0 +1,102 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// setProfileProperties changes keys of the profile of the session
+// sessionID in a single request. The change only affects that session, not
+// the profile it was created from. iTerm2 only accepts several assignments
+// in one request from API version 1.1 on, and ignores them before.
+func setProfileProperties(c ClientInterface, sessionID string, assignments ...*api.SetProfilePropertyRequest_Assignment) error {
+	if err := requireAPIVersion(c, 1, 1, "setting profile properties"); err != nil {
+		return err
+	}
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_SetProfilePropertyRequest{
+			SetProfilePropertyRequest: &api.SetProfilePropertyRequest{
+				Target: &api.SetProfilePropertyRequest_Session{
+					Session: sessionID,
+				},
+				Assignments: assignments,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	switch status := resp.GetSetProfilePropertyResponse().GetStatus(); status {
+	case api.SetProfilePropertyResponse_OK:
+		return nil
+	case api.SetProfilePropertyResponse_SESSION_NOT_FOUND:
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, sessionID)
+	default:
+		return fmt.Errorf("unexpected status setting profile properties: %s", status)
+	}
+}
+
+// getProfileProperties reads keys from the profile of the session
+// sessionID. The values are returned JSON-encoded; keys the profile does not
+// have are missing from the map.
+func getProfileProperties(c ClientInterface, sessionID string, keys ...string) (map[string]string, error) {
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_GetProfilePropertyRequest{
+			GetProfilePropertyRequest: &api.GetProfilePropertyRequest{
+				Session: &sessionID,
+				Keys:    keys,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	gpr := resp.GetGetProfilePropertyResponse()
+	switch status := gpr.GetStatus(); status {
+	case api.GetProfilePropertyResponse_OK:
+	case api.GetProfilePropertyResponse_SESSION_NOT_FOUND:
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, sessionID)
+	default:
+		return nil, fmt.Errorf("unexpected status getting profile properties: %s", status)
+	}
+	values := make(map[string]string, len(gpr.GetProperties()))
+	for _, p := range gpr.GetProperties() {
+		values[p.GetKey()] = p.GetJsonValue()
+	}
+	return values, nil
+}
+
+// setProperty sets the property name of the window or session identified
+// by req to the JSON encoding of value, and returns iTerm2's status so that
+// callers can report it in terms of their own object.
+func setProperty(c ClientInterface, req *api.SetPropertyRequest, name string, value interface{}) (api.SetPropertyResponse_Status, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not encode property %q: %w", name, err)
+	}
+	req.Name = &name
+	req.JsonValue = str(string(data))
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_SetPropertyRequest{SetPropertyRequest: req},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.GetSetPropertyResponse().GetStatus(), nil
+}
+
+// getProperty reads the property name of the window or session identified
+// by req. The value is returned JSON-encoded along with iTerm2's status.
+func getProperty(c ClientInterface, req *api.GetPropertyRequest, name string) (string, api.GetPropertyResponse_Status, error) {
+	req.Name = &name
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_GetPropertyRequest{GetPropertyRequest: req},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	gpr := resp.GetGetPropertyResponse()
+	return gpr.GetJsonValue(), gpr.GetStatus(), nil
+}
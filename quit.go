@@ -0,0 +1,72 @@
+package iterm2
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// quitITerm2 asks iTerm2 to quit through AppleScript, since its API has no
+// request for that. It reports whether the user declined.
+var quitITerm2 = func() (declined bool, err error) {
+	out, err := exec.Command("osascript", "-e", `tell application "iTerm2" to quit`).CombinedOutput()
+	if err != nil {
+		// -128 is AppleScript's "User canceled" error.
+		return bytes.Contains(out, []byte("-128")), fmt.Errorf("%w: %s", err, bytes.TrimSpace(out))
+	}
+	return false, nil
+}
+
+// Quit quits iTerm2 itself, ending every session in it, as opposed to
+// Close, which only closes this App's connection. Use it with care.
+//
+// Without force, iTerm2 asks the user to confirm as it would for Cmd-Q if
+// sessions are still running, and Quit returns an error matching
+// ErrUserDeclined if they cancel. With force, all windows are closed first
+// without asking, so nothing is left to confirm.
+//
+// The App cannot be used after iTerm2 has quit, but Close must still be
+// called.
+func (a *app) Quit(force bool) error {
+	if force {
+		if err := a.closeAllWindows(); err != nil {
+			return fmt.Errorf("could not quit iTerm2: %w", err)
+		}
+	}
+	declined, err := quitITerm2()
+	if declined {
+		return fmt.Errorf("could not quit iTerm2: %w", ErrUserDeclined)
+	}
+	if err != nil {
+		return fmt.Errorf("could not quit iTerm2: %w", err)
+	}
+	return nil
+}
+
+// closeAllWindows closes every window without asking for confirmation.
+func (a *app) closeAllWindows() error {
+	lsr, err := listSessions(a.c)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, w := range lsr.GetWindows() {
+		ids = append(ids, w.GetWindowId())
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err = a.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_CloseRequest{
+			CloseRequest: &api.CloseRequest{
+				Target: &api.CloseRequest_Windows{
+					Windows: &api.CloseRequest_CloseWindows{WindowIds: ids},
+				},
+				Force: b(true),
+			},
+		},
+	})
+	return err
+}
@@ -0,0 +1,57 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestQuit verifies forced quits close all windows first and a declined quit reports ErrUserDeclined
+func TestQuit(t *testing.T) {
+	defer func(f func() (bool, error)) { quitITerm2 = f }(quitITerm2)
+
+	tests := []struct {
+		name      string
+		force     bool
+		declined  bool
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "force", force: true, wantCalls: 2},
+		{name: "confirmed", wantCalls: 0},
+		{name: "declined", declined: true, wantErr: ErrUserDeclined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quit := 0
+			quitITerm2 = func() (bool, error) {
+				quit++
+				if tt.declined {
+					return true, errors.New("User canceled. (-128)")
+				}
+				return false, nil
+			}
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}
+			a := &app{c: mock}
+
+			err := a.Quit(tt.force)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Quit() error = %v, want %v", err, tt.wantErr)
+			}
+			if quit != 1 {
+				t.Errorf("quit requested %d times, want 1", quit)
+			}
+			if len(mock.calls) != tt.wantCalls {
+				t.Fatalf("expected %d Calls, got %d", tt.wantCalls, len(mock.calls))
+			}
+			if tt.force {
+				req := mock.calls[1].GetCloseRequest()
+				ids := req.GetWindows().GetWindowIds()
+				if !req.GetForce() || len(ids) != 2 || ids[0] != "win-1" || ids[1] != "win-2" {
+					t.Errorf("unexpected CloseRequest %v", req)
+				}
+			}
+		})
+	}
+}
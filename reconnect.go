@@ -0,0 +1,304 @@
+package iterm2
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewReconnectingApp is like NewApp but returns an App that survives iTerm2
+// restarts. When a call finds the connection gone, the App dials iTerm2
+// again and re-registers every active notification subscription. Calls that
+// only read state or subscribe to notifications are then retried once.
+// Other calls, such as SendText or CreateTab, return the error instead:
+// iTerm2 may have carried them out before the connection dropped, and
+// retrying them could do so twice. The next call uses the new connection.
+//
+// Window, tab and session ids are only valid for the iTerm2 process that
+// issued them. The first call on a new connection fails with
+// ErrStaleReference if iTerm2 no longer recognizes the object it addresses;
+// look the object up again (for example through ListWindows) and carry on
+// with the new handle.
+//
+// It is equivalent to NewApp(name, WithReconnect()).
+func NewReconnectingApp(name string) (App, error) {
+	return NewApp(name, WithReconnect())
+}
+
+// conn is a ClientInterface that can tell when its connection is gone.
+type conn interface {
+	ClientInterface
+	Done() <-chan struct{}
+}
+
+// reconnectingClient is a ClientInterface that redials iTerm2 whenever the
+// underlying connection is lost.
+type reconnectingClient struct {
+	dial func() (conn, error)
+
+	mu     sync.Mutex
+	c      conn
+	closed bool
+	// quit is closed by Close, waking a Run that waits to redial.
+	quit chan struct{}
+	// subscriptions holds the notification requests to replay on a new
+	// connection.
+	subscriptions []*api.NotificationRequest
+
+	// handlers outlive individual connections; every new connection
+	// forwards its notifications to them.
+	hmu         sync.RWMutex
+	handlers    map[int]func(*api.Notification)
+	nextHandler int
+}
+
+func newReconnectingClient(dial func() (conn, error)) (*reconnectingClient, error) {
+	c, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	r := &reconnectingClient{dial: dial, quit: make(chan struct{})}
+	r.forward(c)
+	r.c = c
+	return r, nil
+}
+
+// AddNotificationHandler registers h for notifications arriving on the
+// current connection and on any connection dialed after it.
+func (r *reconnectingClient) AddNotificationHandler(h func(*api.Notification)) (remove func()) {
+	r.hmu.Lock()
+	defer r.hmu.Unlock()
+	if r.handlers == nil {
+		r.handlers = make(map[int]func(*api.Notification))
+	}
+	id := r.nextHandler
+	r.nextHandler++
+	r.handlers[id] = h
+	return func() {
+		r.hmu.Lock()
+		delete(r.handlers, id)
+		r.hmu.Unlock()
+	}
+}
+
+// forward routes the notifications of c to the registered handlers.
+func (r *reconnectingClient) forward(c conn) {
+	src, ok := c.(notificationSource)
+	if !ok {
+		return
+	}
+	src.AddNotificationHandler(func(n *api.Notification) {
+		r.hmu.RLock()
+		defer r.hmu.RUnlock()
+		for _, h := range r.handlers {
+			h(n)
+		}
+	})
+}
+
+func (r *reconnectingClient) Call(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+	c, reconnected, err := r.conn(nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Call(req)
+	if err != nil && isGone(c) && retryable(req) {
+		c, _, err = r.conn(c)
+		if err != nil {
+			return nil, err
+		}
+		reconnected = true
+		resp, err = c.Call(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if reconnected && rejectsTarget(resp) {
+		return nil, fmt.Errorf("%w: iTerm2 rejected the target of %T", ErrStaleReference, req.GetSubmessage())
+	}
+	r.track(req.GetNotificationRequest())
+	return resp, nil
+}
+
+// conn returns a live connection, dialing a new one if the current one is
+// gone or is the broken connection passed in. It reports whether it had to
+// reconnect.
+func (r *reconnectingClient) conn(broken conn) (conn, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, false, fmt.Errorf("app was closed: %w", ErrConnectionClosed)
+	}
+	if r.c != broken && !isGone(r.c) {
+		return r.c, false, nil
+	}
+	r.c.Close()
+	c, err := r.dial()
+	if err != nil {
+		return nil, false, fmt.Errorf("could not reconnect to iTerm2: %w", err)
+	}
+	r.forward(c)
+	for _, sub := range r.subscriptions {
+		req := proto.Clone(sub).(*api.NotificationRequest)
+		req.Subscribe = b(true)
+		_, err := c.Call(&api.ClientOriginatedMessage{
+			Submessage: &api.ClientOriginatedMessage_NotificationRequest{
+				NotificationRequest: req,
+			},
+		})
+		if err != nil {
+			c.Close()
+			return nil, false, fmt.Errorf("could not restore %s subscription: %w", sub.GetNotificationType(), err)
+		}
+	}
+	r.c = c
+	return c, true, nil
+}
+
+// track remembers successful notification subscriptions so they can be
+// restored after a reconnect, and forgets them once unsubscribed.
+func (r *reconnectingClient) track(req *api.NotificationRequest) {
+	if req == nil {
+		return
+	}
+	key := proto.Clone(req).(*api.NotificationRequest)
+	key.Subscribe = nil
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, sub := range r.subscriptions {
+		if proto.Equal(sub, key) {
+			r.subscriptions = append(r.subscriptions[:i], r.subscriptions[i+1:]...)
+			break
+		}
+	}
+	if req.GetSubscribe() {
+		r.subscriptions = append(r.subscriptions, key)
+	}
+}
+
+// ProtocolVersion returns the protocol version of the current connection.
+func (r *reconnectingClient) ProtocolVersion() string {
+	r.mu.Lock()
+	c := r.c
+	r.mu.Unlock()
+	if v, ok := c.(protocolVersioner); ok {
+		return v.ProtocolVersion()
+	}
+	return ""
+}
+
+func (r *reconnectingClient) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.quit)
+	return r.c.Close()
+}
+
+func isGone(c conn) bool {
+	select {
+	case <-c.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// retryable reports whether req can be sent again without harm if iTerm2
+// already carried it out: it only reads state, or (un)subscribes to
+// notifications, which iTerm2 treats the same however often it is asked.
+func retryable(req *api.ClientOriginatedMessage) bool {
+	switch m := req.GetSubmessage().(type) {
+	case *api.ClientOriginatedMessage_ListSessionsRequest,
+		*api.ClientOriginatedMessage_GetBufferRequest,
+		*api.ClientOriginatedMessage_GetPromptRequest,
+		*api.ClientOriginatedMessage_ListPromptsRequest,
+		*api.ClientOriginatedMessage_GetProfilePropertyRequest,
+		*api.ClientOriginatedMessage_GetPropertyRequest,
+		*api.ClientOriginatedMessage_ListProfilesRequest,
+		*api.ClientOriginatedMessage_FocusRequest,
+		*api.ClientOriginatedMessage_GetBroadcastDomainsRequest,
+		*api.ClientOriginatedMessage_ColorPresetRequest,
+		*api.ClientOriginatedMessage_NotificationRequest:
+		return true
+	case *api.ClientOriginatedMessage_VariableRequest:
+		return len(m.VariableRequest.GetSet()) == 0
+	case *api.ClientOriginatedMessage_SelectionRequest:
+		return m.SelectionRequest.GetGetSelectionRequest() != nil
+	default:
+		return false
+	}
+}
+
+// rejectsTarget reports whether resp carries the status its request type
+// uses to say that the window, tab or session the request addressed does
+// not exist. Statuses about anything else, such as an unknown profile or
+// color preset, do not count.
+func rejectsTarget(resp *api.ServerOriginatedMessage) bool {
+	switch m := resp.GetSubmessage().(type) {
+	case *api.ServerOriginatedMessage_SendTextResponse:
+		return m.SendTextResponse.GetStatus() == api.SendTextResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_InjectResponse:
+		for _, status := range m.InjectResponse.GetStatus() {
+			if status == api.InjectResponse_SESSION_NOT_FOUND {
+				return true
+			}
+		}
+	case *api.ServerOriginatedMessage_CreateTabResponse:
+		return m.CreateTabResponse.GetStatus() == api.CreateTabResponse_INVALID_WINDOW_ID
+	case *api.ServerOriginatedMessage_SplitPaneResponse:
+		return m.SplitPaneResponse.GetStatus() == api.SplitPaneResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_GetBufferResponse:
+		return m.GetBufferResponse.GetStatus() == api.GetBufferResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_GetPromptResponse:
+		return m.GetPromptResponse.GetStatus() == api.GetPromptResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_ListPromptsResponse:
+		return m.ListPromptsResponse.GetStatus() == api.ListPromptsResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_GetProfilePropertyResponse:
+		return m.GetProfilePropertyResponse.GetStatus() == api.GetProfilePropertyResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_SetProfilePropertyResponse:
+		return m.SetProfilePropertyResponse.GetStatus() == api.SetProfilePropertyResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_GetPropertyResponse:
+		return m.GetPropertyResponse.GetStatus() == api.GetPropertyResponse_INVALID_TARGET
+	case *api.ServerOriginatedMessage_SetPropertyResponse:
+		return m.SetPropertyResponse.GetStatus() == api.SetPropertyResponse_INVALID_TARGET
+	case *api.ServerOriginatedMessage_ActivateResponse:
+		return m.ActivateResponse.GetStatus() == api.ActivateResponse_BAD_IDENTIFIER
+	case *api.ServerOriginatedMessage_VariableResponse:
+		switch m.VariableResponse.GetStatus() {
+		case api.VariableResponse_SESSION_NOT_FOUND, api.VariableResponse_TAB_NOT_FOUND, api.VariableResponse_WINDOW_NOT_FOUND:
+			return true
+		}
+	case *api.ServerOriginatedMessage_NotificationResponse:
+		return m.NotificationResponse.GetStatus() == api.NotificationResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_RestartSessionResponse:
+		return m.RestartSessionResponse.GetStatus() == api.RestartSessionResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_SelectionResponse:
+		return m.SelectionResponse.GetStatus() == api.SelectionResponse_INVALID_SESSION
+	case *api.ServerOriginatedMessage_SetTabLayoutResponse:
+		return m.SetTabLayoutResponse.GetStatus() == api.SetTabLayoutResponse_BAD_TAB_ID
+	case *api.ServerOriginatedMessage_ReorderTabsResponse:
+		switch m.ReorderTabsResponse.GetStatus() {
+		case api.ReorderTabsResponse_INVALID_WINDOW_ID, api.ReorderTabsResponse_INVALID_TAB_ID:
+			return true
+		}
+	case *api.ServerOriginatedMessage_SavedArrangementResponse:
+		return m.SavedArrangementResponse.GetStatus() == api.SavedArrangementResponse_WINDOW_NOT_FOUND
+	case *api.ServerOriginatedMessage_SetBroadcastDomainsResponse:
+		return m.SetBroadcastDomainsResponse.GetStatus() == api.SetBroadcastDomainsResponse_SESSION_NOT_FOUND
+	case *api.ServerOriginatedMessage_CloseResponse:
+		for _, status := range m.CloseResponse.GetStatuses() {
+			if status == api.CloseResponse_NOT_FOUND {
+				return true
+			}
+		}
+	case *api.ServerOriginatedMessage_InvokeFunctionResponse:
+		return m.InvokeFunctionResponse.GetError().GetStatus() == api.InvokeFunctionResponse_INVALID_ID
+	}
+	return false
+}
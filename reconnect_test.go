@@ -0,0 +1,293 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// fakeConn is a conn whose connection can be dropped on demand
+type fakeConn struct {
+	mockClient
+	done   chan struct{}
+	closed bool
+}
+
+func newFakeConn(responses ...*api.ServerOriginatedMessage) *fakeConn {
+	return &fakeConn{
+		mockClient: mockClient{responses: responses},
+		done:       make(chan struct{}),
+	}
+}
+
+func (f *fakeConn) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *fakeConn) Close() error {
+	if !f.closed {
+		f.closed = true
+		close(f.done)
+	}
+	return nil
+}
+
+// drop makes the next Call lose the connection as if iTerm2 went away
+func (f *fakeConn) drop() {
+	f.callFunc = func(*api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		f.Close()
+		return nil, errors.New("connection to iTerm2 lost")
+	}
+}
+
+// dialer hands out the given conns in order
+func dialer(t *testing.T, conns ...*fakeConn) func() (conn, error) {
+	return func() (conn, error) {
+		if len(conns) == 0 {
+			t.Fatal("unexpected dial")
+		}
+		c := conns[0]
+		conns = conns[1:]
+		return c, nil
+	}
+}
+
+func sendText() *api.ClientOriginatedMessage {
+	return &api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_SendTextRequest{
+			SendTextRequest: &api.SendTextRequest{Session: str("sess-1"), Text: str("ls")},
+		},
+	}
+}
+
+func listRequest() *api.ClientOriginatedMessage {
+	return &api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_ListSessionsRequest{
+			ListSessionsRequest: &api.ListSessionsRequest{},
+		},
+	}
+}
+
+// TestReconnectingClient_RetriesOnNewConnection verifies a read on a dropped connection is retried once after redialing
+func TestReconnectingClient_RetriesOnNewConnection(t *testing.T) {
+	first, second := newFakeConn(), newFakeConn(layout())
+	rc, err := newReconnectingClient(dialer(t, first, second))
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	first.drop()
+
+	if _, err := rc.Call(listRequest()); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if len(first.calls) != 1 || len(second.calls) != 1 {
+		t.Errorf("calls = %d on first, %d on second; want 1 and 1", len(first.calls), len(second.calls))
+	}
+}
+
+// TestReconnectingClient_DoesNotRepeatWrites verifies a call iTerm2 may already have carried out fails instead of being sent twice
+func TestReconnectingClient_DoesNotRepeatWrites(t *testing.T) {
+	requests := map[string]*api.ClientOriginatedMessage{
+		"send text": sendText(),
+		"create tab": {Submessage: &api.ClientOriginatedMessage_CreateTabRequest{
+			CreateTabRequest: &api.CreateTabRequest{},
+		}},
+		"set variable": {Submessage: &api.ClientOriginatedMessage_VariableRequest{
+			VariableRequest: &api.VariableRequest{Set: []*api.VariableRequest_Set{{Name: str("user.x"), Value: str(`"1"`)}}},
+		}},
+	}
+	for name, req := range requests {
+		t.Run(name, func(t *testing.T) {
+			first, second := newFakeConn(), newFakeConn(layout())
+			rc, err := newReconnectingClient(dialer(t, first, second))
+			if err != nil {
+				t.Fatalf("newReconnectingClient() error = %v", err)
+			}
+			first.drop()
+
+			if _, err := rc.Call(req); err == nil {
+				t.Fatal("Call() expected error, got nil")
+			}
+			if len(second.calls) != 0 {
+				t.Fatalf("expected no retry, got %d calls on the new connection", len(second.calls))
+			}
+			// The next call goes out on a new connection.
+			if _, err := rc.Call(listRequest()); err != nil {
+				t.Fatalf("Call() after the failure error = %v", err)
+			}
+			if len(second.calls) != 1 {
+				t.Errorf("expected 1 call on the new connection, got %d", len(second.calls))
+			}
+		})
+	}
+}
+
+// TestReconnectingClient_RetriesOnce verifies a failure on the new connection is returned
+func TestReconnectingClient_RetriesOnce(t *testing.T) {
+	first, second := newFakeConn(), newFakeConn()
+	rc, err := newReconnectingClient(dialer(t, first, second))
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	first.drop()
+	second.callFunc = func(*api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		return nil, errors.New("error from server: malformed")
+	}
+
+	if _, err := rc.Call(listRequest()); err == nil {
+		t.Fatal("Call() expected error, got nil")
+	}
+	if len(second.calls) != 1 {
+		t.Errorf("expected exactly one retry, got %d", len(second.calls))
+	}
+}
+
+// TestReconnectingClient_StaleReference verifies targets rejected after a reconnect are reported as stale
+func TestReconnectingClient_StaleReference(t *testing.T) {
+	first := newFakeConn()
+	second := newFakeConn(&api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_SendTextResponse{
+			SendTextResponse: &api.SendTextResponse{
+				Status: api.SendTextResponse_SESSION_NOT_FOUND.Enum(),
+			},
+		},
+	})
+	rc, err := newReconnectingClient(dialer(t, first, second))
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	first.drop()
+	if _, err := rc.Call(sendText()); err == nil {
+		t.Fatal("Call() on the dropped connection expected error, got nil")
+	}
+
+	_, err = rc.Call(sendText())
+	if !errors.Is(err, ErrStaleReference) {
+		t.Errorf("Call() error = %v, want ErrStaleReference", err)
+	}
+}
+
+// TestReconnectingClient_OtherNotFoundStatuses verifies statuses about things other than the addressed object are passed on after a reconnect
+func TestReconnectingClient_OtherNotFoundStatuses(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *api.ClientOriginatedMessage
+		resp *api.ServerOriginatedMessage
+	}{
+		{
+			name: "unknown color preset",
+			req: &api.ClientOriginatedMessage{Submessage: &api.ClientOriginatedMessage_ColorPresetRequest{
+				ColorPresetRequest: &api.ColorPresetRequest{},
+			}},
+			resp: &api.ServerOriginatedMessage{Submessage: &api.ServerOriginatedMessage_ColorPresetResponse{
+				ColorPresetResponse: &api.ColorPresetResponse{Status: api.ColorPresetResponse_PRESET_NOT_FOUND.Enum()},
+			}},
+		},
+		{
+			name: "unknown profile",
+			req: &api.ClientOriginatedMessage{Submessage: &api.ClientOriginatedMessage_CreateTabRequest{
+				CreateTabRequest: &api.CreateTabRequest{ProfileName: str("Missing")},
+			}},
+			resp: &api.ServerOriginatedMessage{Submessage: &api.ServerOriginatedMessage_CreateTabResponse{
+				CreateTabResponse: &api.CreateTabResponse{Status: api.CreateTabResponse_INVALID_PROFILE_NAME.Enum()},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := newFakeConn(), newFakeConn(tt.resp)
+			rc, err := newReconnectingClient(dialer(t, first, second))
+			if err != nil {
+				t.Fatalf("newReconnectingClient() error = %v", err)
+			}
+			first.Close()
+
+			if _, err := rc.Call(tt.req); err != nil {
+				t.Errorf("Call() error = %v, want the response passed on", err)
+			}
+		})
+	}
+}
+
+// TestReconnectingClient_RestoresSubscriptions verifies active subscriptions are replayed on the new connection
+func TestReconnectingClient_RestoresSubscriptions(t *testing.T) {
+	first, second := newFakeConn(), newFakeConn()
+	rc, err := newReconnectingClient(dialer(t, first, second))
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	notify := func(typ api.NotificationType, subscribe bool) *api.ClientOriginatedMessage {
+		return &api.ClientOriginatedMessage{
+			Submessage: &api.ClientOriginatedMessage_NotificationRequest{
+				NotificationRequest: &api.NotificationRequest{
+					Subscribe:        b(subscribe),
+					NotificationType: typ.Enum(),
+				},
+			},
+		}
+	}
+	for _, req := range []*api.ClientOriginatedMessage{
+		notify(api.NotificationType_NOTIFY_ON_NEW_SESSION, true),
+		notify(api.NotificationType_NOTIFY_ON_TERMINATE_SESSION, true),
+		notify(api.NotificationType_NOTIFY_ON_NEW_SESSION, false),
+	} {
+		if _, err := rc.Call(req); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+	}
+	first.drop()
+
+	if _, err := rc.Call(listRequest()); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if len(first.calls) != 4 || len(second.calls) != 2 {
+		t.Fatalf("expected the call to fail on the first connection and be replayed on the second, got %d and %d calls",
+			len(first.calls), len(second.calls))
+	}
+	replayed := second.calls[0].GetNotificationRequest()
+	if replayed.GetNotificationType() != api.NotificationType_NOTIFY_ON_TERMINATE_SESSION || !replayed.GetSubscribe() {
+		t.Errorf("replayed %v, want terminate-session subscription", replayed)
+	}
+	if second.calls[1].GetListSessionsRequest() == nil {
+		t.Errorf("expected retried ListSessionsRequest, got %v", second.calls[1])
+	}
+}
+
+// TestReconnectingClient_Close verifies calls fail after Close without redialing
+func TestReconnectingClient_Close(t *testing.T) {
+	first := newFakeConn()
+	rc, err := newReconnectingClient(dialer(t, first))
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := rc.Call(sendText()); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Call() after Close error = %v, want ErrConnectionClosed", err)
+	}
+}
+
+// TestReconnectingClient_ForwardsNotifications verifies handlers keep receiving notifications after a reconnect
+func TestReconnectingClient_ForwardsNotifications(t *testing.T) {
+	first, second := newFakeConn(), newFakeConn(layout())
+	rc, err := newReconnectingClient(dialer(t, first, second))
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	var got []string
+	rc.AddNotificationHandler(func(n *api.Notification) {
+		got = append(got, n.GetVariableChangedNotification().GetName())
+	})
+	first.notify(variableChanged(api.VariableScope_APP, "", "before", "null"))
+	first.drop()
+	if _, err := rc.Call(listRequest()); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	second.notify(variableChanged(api.VariableScope_APP, "", "after", "null"))
+
+	if len(got) != 2 || got[0] != "before" || got[1] != "after" {
+		t.Errorf("received %v, want [before after]", got)
+	}
+}
@@ -0,0 +1,81 @@
+package iterm2
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Run waits redialMinDelay before dialing a lost connection again, and
+// doubles the wait after every failed attempt, up to redialMaxDelay.
+var (
+	redialMinDelay = 250 * time.Millisecond
+	redialMaxDelay = 5 * time.Second
+)
+
+// Run blocks while the App is connected, for plugins that only react to
+// notifications: subscriptions keep delivering on their channels while it
+// runs. It returns ctx.Err() once ctx is done, or an error matching
+// ErrConnectionClosed once the App is closed. If the connection to iTerm2 is
+// lost, an App made with WithReconnect keeps dialing iTerm2 again, backing
+// off from a quarter of a second to five seconds between attempts, until
+// iTerm2 is back; it then restores its subscriptions and keeps running. Any
+// other App returns the error that ended the connection.
+func (a *app) Run(ctx context.Context) error {
+	if r, ok := a.c.(*reconnectingClient); ok {
+		return r.run(ctx)
+	}
+	c, ok := a.c.(conn)
+	if !ok {
+		// Without a way to tell when the connection is gone, all there is
+		// to wait for is ctx.
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.Done():
+		return connErr(c)
+	}
+}
+
+// run waits for ctx to be done, redialing whenever the connection is lost.
+func (r *reconnectingClient) run(ctx context.Context) error {
+	for {
+		r.mu.Lock()
+		c := r.c
+		r.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.Done():
+		}
+		for delay := redialMinDelay; ; {
+			if _, _, err := r.conn(c); err == nil {
+				break
+			}
+			t := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-r.quit:
+				t.Stop()
+				return fmt.Errorf("app was closed: %w", ErrConnectionClosed)
+			case <-t.C:
+			}
+			if delay *= 2; delay > redialMaxDelay {
+				delay = redialMaxDelay
+			}
+		}
+	}
+}
+
+// connErr returns why the connection c is gone.
+func connErr(c conn) error {
+	if e, ok := c.(interface{ Err() error }); ok && e.Err() != nil {
+		return fmt.Errorf("connection to iTerm2 ended: %w", e.Err())
+	}
+	return fmt.Errorf("connection to iTerm2 ended: %w", ErrConnectionClosed)
+}
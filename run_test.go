@@ -0,0 +1,143 @@
+package iterm2
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// runAsync starts a.Run(ctx) and returns the channel its result is sent on
+func runAsync(a App, ctx context.Context) <-chan error {
+	errc := make(chan error, 1)
+	go func() { errc <- a.Run(ctx) }()
+	return errc
+}
+
+// waitRun returns the result of Run or fails the test if it does not return
+func waitRun(t *testing.T, errc <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return")
+		return nil
+	}
+}
+
+// TestRun verifies Run returns when the context is done or the connection ends
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := runAsync(&app{c: newFakeConn()}, ctx)
+	cancel()
+	if err := waitRun(t, errc); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+
+	c := newFakeConn()
+	errc = runAsync(&app{c: c}, context.Background())
+	c.Close()
+	if err := waitRun(t, errc); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Run() error = %v, want ErrConnectionClosed", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	errc = runAsync(&app{c: &mockClient{}}, ctx)
+	cancel()
+	if err := waitRun(t, errc); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() without Done error = %v, want context.Canceled", err)
+	}
+}
+
+// TestRun_Reconnect verifies Run redials a lost connection, restoring subscriptions, until the App is closed
+func TestRun_Reconnect(t *testing.T) {
+	first, second := newFakeConn(notificationOK()), newFakeConn(notificationOK())
+	conns := []*fakeConn{first, second}
+	dialed := make(chan struct{}, len(conns))
+	rc, err := newReconnectingClient(func() (conn, error) {
+		if len(conns) == 0 {
+			return nil, errors.New("unexpected dial")
+		}
+		c := conns[0]
+		conns = conns[1:]
+		dialed <- struct{}{}
+		return c, nil
+	})
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	<-dialed
+	a := &app{c: rc}
+	if _, _, err := a.SubscribeNewSession(); err != nil {
+		t.Fatalf("SubscribeNewSession() error = %v", err)
+	}
+
+	errc := runAsync(a, context.Background())
+	first.Close()
+	select {
+	case <-dialed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not redial")
+	}
+	a.Close()
+	if err := waitRun(t, errc); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Run() error = %v, want ErrConnectionClosed", err)
+	}
+	if len(second.calls) != 1 || second.calls[0].GetNotificationRequest().GetNotificationType() != api.NotificationType_NOTIFY_ON_NEW_SESSION {
+		t.Errorf("expected the subscription to be restored on the new connection, got %v", second.calls)
+	}
+}
+
+// TestRun_ReconnectBackoff verifies Run keeps redialing while iTerm2 is not back yet
+func TestRun_ReconnectBackoff(t *testing.T) {
+	defer func(min, max time.Duration) { redialMinDelay, redialMaxDelay = min, max }(redialMinDelay, redialMaxDelay)
+	redialMinDelay, redialMaxDelay = time.Millisecond, 4*time.Millisecond
+
+	first, second := newFakeConn(notificationOK()), newFakeConn(notificationOK())
+	failures := 3
+	dialed := make(chan struct{}, 1)
+	rc, err := newReconnectingClient(func() (conn, error) {
+		if first != nil {
+			c := first
+			first = nil
+			return c, nil
+		}
+		if failures > 0 {
+			failures--
+			return nil, errors.New("connection refused")
+		}
+		dialed <- struct{}{}
+		return second, nil
+	})
+	if err != nil {
+		t.Fatalf("newReconnectingClient() error = %v", err)
+	}
+	c := rc.c.(*fakeConn)
+	a := &app{c: rc}
+	if _, _, err := a.SubscribeNewSession(); err != nil {
+		t.Fatalf("SubscribeNewSession() error = %v", err)
+	}
+
+	errc := runAsync(a, context.Background())
+	c.Close()
+	select {
+	case <-dialed:
+	case err := <-errc:
+		t.Fatalf("Run() returned %v before iTerm2 was back", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not redial")
+	}
+	a.Close()
+	if err := waitRun(t, errc); !errors.Is(err, ErrConnectionClosed) {
+		t.Errorf("Run() error = %v, want ErrConnectionClosed", err)
+	}
+	if failures != 0 {
+		t.Errorf("expected every failed dial to be retried, %d left", failures)
+	}
+	if len(second.calls) != 1 || second.calls[0].GetNotificationRequest().GetNotificationType() != api.NotificationType_NOTIFY_ON_NEW_SESSION {
+		t.Errorf("expected the subscription to be restored on the new connection, got %v", second.calls)
+	}
+}
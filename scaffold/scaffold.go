@@ -90,14 +90,14 @@ func createTab(tab iterm2.Tab, ts TabSpec) error {
 	}
 	sesh := sessions[0]
 	if ts.Dir != "" {
-		err = sesh.SendText(fmt.Sprintf("cd %v\n", ts.Dir))
+		err = sesh.SendCommand(fmt.Sprintf("cd %v", ts.Dir))
 		if err != nil {
 			return fmt.Errorf("error changing directory: %w", err)
 		}
 	}
 	if ts.Env != nil {
 		for _, e := range ts.Env.GetEnv() {
-			err = sesh.SendText(fmt.Sprintf("export %s\n", e))
+			err = sesh.SendCommand(fmt.Sprintf("export %s", e))
 			if err != nil {
 				return fmt.Errorf("error exporting env: %w", err)
 			}
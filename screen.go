@@ -0,0 +1,96 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+)
+
+// Frame is a rectangle in screen coordinates. As everywhere in macOS, the
+// origin is the bottom-left corner of the main screen and y grows upwards.
+type Frame struct {
+	X, Y          int
+	Width, Height int
+}
+
+// Screen describes a display attached to the Mac.
+type Screen struct {
+	// Frame is the full area of the display.
+	Frame Frame
+	// VisibleFrame excludes the menu bar and the Dock.
+	VisibleFrame Frame
+	// Main reports whether this is the primary display, the one
+	// holding the menu bar and the origin of the coordinate system.
+	Main bool
+}
+
+// screensScript lists NSScreen frames as JSON. The first screen
+// returned by NSScreen is always the primary one.
+const screensScript = `ObjC.import('AppKit');
+var screens = $.NSScreen.screens, out = [];
+function rect(r) {
+	return {origin: {x: r.origin.x, y: r.origin.y}, size: {width: r.size.width, height: r.size.height}};
+}
+for (var i = 0; i < screens.count; i++) {
+	var s = screens.objectAtIndex(i);
+	out.push({frame: rect(s.frame), visible: rect(s.visibleFrame)});
+}
+JSON.stringify(out);`
+
+// GetScreens returns the displays attached to the Mac. iTerm2's API does
+// not expose screen geometry, so this asks AppKit directly through
+// osascript.
+func (a *app) GetScreens() ([]Screen, error) {
+	out, err := exec.Command("osascript", "-l", "JavaScript", "-e", screensScript).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not query screens: %w", err)
+	}
+	return parseScreens(out)
+}
+
+type jsonFrame struct {
+	Origin struct {
+		X float64 `json:"x"`
+		Y float64 `json:"y"`
+	} `json:"origin"`
+	Size struct {
+		Width  float64 `json:"width"`
+		Height float64 `json:"height"`
+	} `json:"size"`
+}
+
+func (f jsonFrame) frame() Frame {
+	return Frame{
+		X:      int(math.Round(f.Origin.X)),
+		Y:      int(math.Round(f.Origin.Y)),
+		Width:  int(math.Round(f.Size.Width)),
+		Height: int(math.Round(f.Size.Height)),
+	}
+}
+
+func newJSONFrame(f Frame) jsonFrame {
+	var j jsonFrame
+	j.Origin.X, j.Origin.Y = float64(f.X), float64(f.Y)
+	j.Size.Width, j.Size.Height = float64(f.Width), float64(f.Height)
+	return j
+}
+
+func parseScreens(data []byte) ([]Screen, error) {
+	var raw []struct {
+		Frame   jsonFrame `json:"frame"`
+		Visible jsonFrame `json:"visible"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("could not parse screens %q: %w", data, err)
+	}
+	screens := make([]Screen, 0, len(raw))
+	for i, r := range raw {
+		screens = append(screens, Screen{
+			Frame:        r.Frame.frame(),
+			VisibleFrame: r.Visible.frame(),
+			Main:         i == 0,
+		})
+	}
+	return screens, nil
+}
@@ -0,0 +1,35 @@
+package iterm2
+
+import "testing"
+
+// TestParseScreens verifies osascript output is converted into screens
+func TestParseScreens(t *testing.T) {
+	out := []byte(`[{"frame":{"origin":{"x":0,"y":0},"size":{"width":1728,"height":1117}},` +
+		`"visible":{"origin":{"x":0,"y":0},"size":{"width":1728,"height":1079}}},` +
+		`{"frame":{"origin":{"x":-1920,"y":-63.5},"size":{"width":1920,"height":1080}},` +
+		`"visible":{"origin":{"x":-1920,"y":-63.5},"size":{"width":1920,"height":1055}}}]`)
+
+	screens, err := parseScreens(out)
+	if err != nil {
+		t.Fatalf("parseScreens() error = %v", err)
+	}
+	if len(screens) != 2 {
+		t.Fatalf("expected 2 screens, got %d", len(screens))
+	}
+	if !screens[0].Main || screens[1].Main {
+		t.Errorf("only the first screen should be main: %+v", screens)
+	}
+	if want := (Frame{X: 0, Y: 0, Width: 1728, Height: 1117}); screens[0].Frame != want {
+		t.Errorf("screen 0 frame = %+v, want %+v", screens[0].Frame, want)
+	}
+	if want := (Frame{X: -1920, Y: -64, Width: 1920, Height: 1055}); screens[1].VisibleFrame != want {
+		t.Errorf("screen 1 visible frame = %+v, want %+v", screens[1].VisibleFrame, want)
+	}
+}
+
+// TestParseScreens_Invalid verifies malformed output is reported
+func TestParseScreens_Invalid(t *testing.T) {
+	if _, err := parseScreens([]byte("execution error")); err == nil {
+		t.Error("parseScreens() expected error for malformed output, got nil")
+	}
+}
@@ -0,0 +1,172 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// jumpToSelectionMenuItem is the identifier of Edit > Find > Jump to
+// Selection, which scrolls the current session until its selection shows.
+const jumpToSelectionMenuItem = "Find.Jump to Selection"
+
+// ScrollToTop scrolls the session to the oldest line of its scrollback
+// history. It returns ErrNoScrollback if there are no lines above the
+// screen.
+//
+// iTerm2's API cannot scroll a session directly, so the scroll methods
+// select the target line, focus the session as FocusSession does and use
+// Edit > Find > Jump to Selection, which only acts on the current session of
+// the active app. Afterwards the window, tab and session that had focus
+// within iTerm2 get it back, as with RestoreFocus, and so does the session's
+// selection. iTerm2 itself stays the active app: the API has no way to hand
+// focus back to the app the user was in before.
+func (s *session) ScrollToTop() error {
+	h, err := s.history()
+	if err != nil {
+		return err
+	}
+	return s.scrollTo(h.first)
+}
+
+// ScrollToBottom scrolls the session back down to its screen, where new
+// output appears. It returns ErrNoScrollback if there are no lines above
+// the screen, in which case there is nothing to scroll. See ScrollToTop for
+// how scrolling works.
+func (s *session) ScrollToBottom() error {
+	h, err := s.history()
+	if err != nil {
+		return err
+	}
+	return s.scrollTo(h.last)
+}
+
+// ScrollToMark scrolls the session to the prompt of m, a mark returned by
+// ListMarks. It returns ErrNoScrollback if the session has no lines above
+// the screen, and an error if the mark has since been dropped from the
+// history. See ScrollToTop for how scrolling works.
+func (s *session) ScrollToMark(m Mark) error {
+	h, err := s.history()
+	if err != nil {
+		return err
+	}
+	if y := m.Prompt.Start.Y; y < h.first || y > h.last {
+		return fmt.Errorf("mark %q is no longer in the history of session %q", m.ID, s.id)
+	}
+	return s.scrollTo(m.Prompt.Start.Y)
+}
+
+// lineSpan is the range of lines a session holds, from the oldest line of
+// its scrollback history to the last line of its screen.
+type lineSpan struct {
+	first, last int
+}
+
+// history returns the lines the session holds, or ErrNoScrollback if they
+// all fit on its screen.
+func (s *session) history() (lineSpan, error) {
+	gbr, err := s.getBufferResponse(&api.LineRange{ScreenContentsOnly: proto.Bool(true)})
+	if err != nil {
+		return lineSpan{}, err
+	}
+	top := int(gbr.GetWindowedCoordRange().GetCoordRange().GetStart().GetY())
+	span := lineSpan{first: top, last: top + len(gbr.GetContents()) - 1}
+
+	// The count of lines above the screen includes the ones discarded
+	// once the history reached its limit, so it is capped by that limit.
+	above := int(gbr.GetNumLinesAboveScreen())
+	props, err := getProfileProperties(s.c, s.id, "Unlimited Scrollback", "Scrollback Lines")
+	if err != nil {
+		return lineSpan{}, fmt.Errorf("could not get scrollback of session %q: %w", s.id, err)
+	}
+	var unlimited bool
+	var limit int
+	if json.Unmarshal([]byte(props["Unlimited Scrollback"]), &unlimited) == nil && !unlimited &&
+		json.Unmarshal([]byte(props["Scrollback Lines"]), &limit) == nil && above > limit {
+		above = limit
+	}
+	if above <= 0 {
+		return lineSpan{}, fmt.Errorf("%w: session %q", ErrNoScrollback, s.id)
+	}
+	span.first -= above
+	return span, nil
+}
+
+// scrollTo scrolls the session until line y shows.
+func (s *session) scrollTo(y int) error {
+	saved, err := s.getSelection()
+	if err != nil {
+		return err
+	}
+	target := &api.Selection{SubSelections: []*api.SubSelection{{
+		WindowedCoordRange: &api.WindowedCoordRange{
+			CoordRange: GridRange{Start: Coord{X: 0, Y: y}, End: Coord{X: 0, Y: y + 1}}.Proto(),
+		},
+		SelectionMode: api.SelectionMode_CHARACTER.Enum(),
+	}}}
+	if err := s.setSelection(target); err != nil {
+		return err
+	}
+	// The menu item acts on the current session, so the session is
+	// focused for it and the previous focus restored afterwards.
+	a := &app{c: s.c}
+	snap, err := a.GetFocusSnapshot()
+	if err == nil {
+		err = a.FocusSession(s.id)
+		if err == nil {
+			err = selectMenuItem(s.c, jumpToSelectionMenuItem)
+		}
+		if restoreErr := a.RestoreFocus(snap); err == nil {
+			err = restoreErr
+		}
+	}
+	if restoreErr := s.setSelection(saved); err == nil {
+		err = restoreErr
+	}
+	return err
+}
+
+func (s *session) getSelection() (*api.Selection, error) {
+	resp, err := s.selection(&api.SelectionRequest{
+		Request: &api.SelectionRequest_GetSelectionRequest_{
+			GetSelectionRequest: &api.SelectionRequest_GetSelectionRequest{SessionId: &s.id},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sel := resp.GetGetSelectionResponse().GetSelection()
+	if sel == nil {
+		sel = &api.Selection{}
+	}
+	return sel, nil
+}
+
+func (s *session) setSelection(sel *api.Selection) error {
+	_, err := s.selection(&api.SelectionRequest{
+		Request: &api.SelectionRequest_SetSelectionRequest_{
+			SetSelectionRequest: &api.SelectionRequest_SetSelectionRequest{SessionId: &s.id, Selection: sel},
+		},
+	})
+	return err
+}
+
+func (s *session) selection(req *api.SelectionRequest) (*api.SelectionResponse, error) {
+	resp, err := s.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_SelectionRequest{SelectionRequest: req},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not access selection of session %q: %w", s.id, err)
+	}
+	sr := resp.GetSelectionResponse()
+	switch status := sr.GetStatus(); status {
+	case api.SelectionResponse_OK:
+		return sr, nil
+	case api.SelectionResponse_INVALID_SESSION:
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
+		return nil, fmt.Errorf("unexpected status accessing selection of session %q: %s", s.id, status)
+	}
+}
@@ -0,0 +1,114 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// screenResponse is a canned GetBufferResponse for a three-line screen
+// starting at line first, with above lines before it
+func screenResponse(first, above int64) *api.ServerOriginatedMessage {
+	resp := bufferResponse(first, "$ make", "ok", "$ ")
+	resp.GetGetBufferResponse().NumLinesAboveScreen = proto.Int64(above)
+	return resp
+}
+
+func selectionOK() *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_SelectionResponse{
+			SelectionResponse: &api.SelectionResponse{Status: api.SelectionResponse_OK.Enum()},
+		},
+	}
+}
+
+// TestScrollTo verifies the target line is selected, jumped to and the old selection restored
+func TestScrollTo(t *testing.T) {
+	activateOK := &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ActivateResponse{
+			ActivateResponse: &api.ActivateResponse{Status: api.ActivateResponse_OK.Enum()},
+		},
+	}
+	menuOK := &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_MenuItemResponse{
+			MenuItemResponse: &api.MenuItemResponse{Status: api.MenuItemResponse_OK.Enum()},
+		},
+	}
+	limited := profileProperties(api.GetProfilePropertyResponse_OK, "Unlimited Scrollback", "false", "Scrollback Lines", "1000")
+	unlimited := profileProperties(api.GetProfilePropertyResponse_OK, "Unlimited Scrollback", "true", "Scrollback Lines", "1000")
+	tests := []struct {
+		name       string
+		scroll     func(s *session) error
+		scrollback *api.ServerOriginatedMessage
+		want       int
+	}{
+		{"top of full history", (*session).ScrollToTop, limited, 4000},
+		{"top of unlimited history", (*session).ScrollToTop, unlimited, 0},
+		{"bottom", (*session).ScrollToBottom, limited, 5002},
+		{"mark", func(s *session) error {
+			return s.ScrollToMark(Mark{ID: "p1", Prompt: GridRange{Start: Coord{0, 4500}}})
+		}, limited, 4500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Before scrolling, sess-3 has focus.
+			focus := focusResponse(api.FocusChangedNotification_Window_TERMINAL_WINDOW_BECAME_KEY, "win-1",
+				[]string{"2"}, []string{"sess-3"})
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+				screenResponse(5000, 5000), tt.scrollback, selectionOK(), selectionOK(),
+				focus, layout(), activateOK, menuOK, activateOK, selectionOK(),
+			}}
+			s := &session{c: mock, id: "sess-1"}
+			if err := tt.scroll(s); err != nil {
+				t.Fatalf("scroll error = %v", err)
+			}
+			if len(mock.calls) != 10 {
+				t.Fatalf("expected 10 Calls, got %d", len(mock.calls))
+			}
+			if !mock.calls[0].GetGetBufferRequest().GetLineRange().GetScreenContentsOnly() {
+				t.Errorf("expected only the screen to be fetched, got %v", mock.calls[0])
+			}
+			set := mock.calls[3].GetSelectionRequest().GetSetSelectionRequest()
+			subs := set.GetSelection().GetSubSelections()
+			if len(subs) != 1 || subs[0].GetWindowedCoordRange().GetCoordRange().GetStart().GetY() != int64(tt.want) {
+				t.Errorf("selected %v, want line %d", set, tt.want)
+			}
+			if id := mock.calls[6].GetActivateRequest().GetSessionId(); id != "sess-1" {
+				t.Errorf("focused %q, want sess-1", id)
+			}
+			if item := mock.calls[7].GetMenuItemRequest().GetIdentifier(); item != "Find.Jump to Selection" {
+				t.Errorf("menu item = %q", item)
+			}
+			if id := mock.calls[8].GetActivateRequest().GetSessionId(); id != "sess-3" {
+				t.Errorf("focus given back to %q, want sess-3", id)
+			}
+			restored := mock.calls[9].GetSelectionRequest().GetSetSelectionRequest()
+			if restored == nil || len(restored.GetSelection().GetSubSelections()) != 0 {
+				t.Errorf("expected the empty selection to be restored, got %v", mock.calls[9])
+			}
+		})
+	}
+}
+
+// TestScrollTo_NoScrollback verifies sessions without history and marks outside it are rejected before scrolling
+func TestScrollTo_NoScrollback(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		screenResponse(0, 0),
+		profileProperties(api.GetProfilePropertyResponse_OK, "Unlimited Scrollback", "true"),
+	}}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.ScrollToBottom(); !errors.Is(err, ErrNoScrollback) || len(mock.calls) != 2 {
+		t.Errorf("ScrollToBottom() error = %v after %d calls, want ErrNoScrollback after 2", err, len(mock.calls))
+	}
+
+	mock = &mockClient{responses: []*api.ServerOriginatedMessage{
+		screenResponse(5000, 5000),
+		profileProperties(api.GetProfilePropertyResponse_OK, "Unlimited Scrollback", "false", "Scrollback Lines", "1000"),
+	}}
+	s = &session{c: mock, id: "sess-1"}
+	if err := s.ScrollToMark(Mark{ID: "p1", Prompt: GridRange{Start: Coord{0, 100}}}); err == nil || len(mock.calls) != 2 {
+		t.Errorf("ScrollToMark() of a dropped mark error = %v after %d calls, want an error after 2", err, len(mock.calls))
+	}
+}
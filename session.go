@@ -1,7 +1,19 @@
 package iterm2
 
 import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Tombar/iterm2/api"
 )
@@ -10,8 +22,66 @@ import (
 // within a Tab where the terminal is active
 type Session interface {
 	SendText(s string) error
+	SendCommand(cmd string) error
+	SendBytes(data []byte) error
+	SendHex(h string) error
+	PasteText(text string) error
+	TypeSlowly(text string, perCharDelay time.Duration) error
+	TypeSlowlyContext(ctx context.Context, text string, perCharDelay time.Duration) error
+	SendTextWithOptions(s string, opts SendTextOptions) error
+	SendKeyEvent(key Key, mods Modifiers) error
+	SendSignal(sig os.Signal) error
 	Activate(selectTab, orderWindowFront bool) error
 	SplitPane(opts SplitPaneOptions) (Session, error)
+	Duplicate() (Session, error)
+	Bury() error
+	GetVariables(names ...string) (map[string]string, error)
+	GetName() (string, error)
+	SetName(name string) error
+	GetTTY() (string, error)
+	GetHostname() (string, error)
+	GetUsername() (string, error)
+	IsProfileDivorced() (bool, error)
+	GetProfileName() (string, error)
+	OpenURL(url string) error
+	SetWorkingDirectory(dir string) error
+	SetTransparency(level float64) error
+	SetBlur(enabled bool) error
+	SetBackgroundImage(path string, mode BackgroundMode) error
+	SetProfileProperties(props map[string]string) error
+	SetCursorShape(shape CursorShape) error
+	SetCursorBlink(enabled bool) error
+	SetOptionKeyMode(left, right OptionKeyMode) error
+	SetBellSilenced(silenced bool) error
+	SetVisualBell(enabled bool) error
+	SetTerminalType(term string) error
+	SetAnswerback(answerback string) error
+	SetWordCharacters(chars string) error
+	SetTabStopWidth(width int) error
+	Reset(hard bool) error
+	SetTitleComponents(components []TitleComponent) error
+	SetTitleFormat(format string) error
+	SetScrollbackLines(n int) error
+	SetLogging(enabled bool, dir string) error
+	ApplyColorPreset(name string) error
+	GetScreenContents() (*ScreenContents, error)
+	GetContentsSince(token Token) ([]string, Token, error)
+	FindText(substr string, opts FindOptions) ([]GridRange, error)
+	SaveScreenContents(path string, includeScrollback bool) error
+	SubscribeScreenUpdate() (<-chan struct{}, func(), error)
+	WaitForText(substr string, timeout time.Duration) error
+	WaitForMatch(re *regexp.Regexp, timeout time.Duration) ([]string, error)
+	SetMark() error
+	SetTouchBarLabel(text string) error
+	ListMarks() ([]Mark, error)
+	// The scroll methods activate iTerm2, taking focus from other apps;
+	// see ScrollToTop.
+	ScrollToTop() error
+	ScrollToBottom() error
+	ScrollToMark(m Mark) error
+	GetLastCommand() (command string, exitCode int, err error)
+	InvokeFunction(invocation string) (string, error)
+	GetTab() (Tab, error)
 	GetSessionID() string
 }
 
@@ -19,31 +89,139 @@ type Session interface {
 // More options can be added here as needed
 type SplitPaneOptions struct {
 	Vertical bool
+	// Profile is the name of the profile for the new pane, or empty for
+	// the default profile.
+	Profile string
+}
+
+// SendTextOptions for customizing how text is delivered to a session.
+type SendTextOptions struct {
+	// SuppressBroadcast keeps the text from being copied to the other
+	// sessions in this session's broadcast group.
+	SuppressBroadcast bool
 }
 
 type session struct {
 	c  ClientInterface
 	id string
+	// windowID and tabID locate the session when it was looked up
+	// through ListSessions. They are empty for buried sessions and for
+	// sessions obtained some other way. mu guards them, since moving the
+	// session changes them.
+	mu       sync.Mutex
+	windowID string
+	tabID    string
+}
+
+// location returns the ids of the window and tab the session was last
+// known to be in.
+func (s *session) location() (windowID, tabID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.windowID, s.tabID
 }
 
+func (s *session) setLocation(windowID, tabID string) {
+	s.mu.Lock()
+	s.windowID, s.tabID = windowID, tabID
+	s.mu.Unlock()
+}
+
+// SendText types t into the session exactly as given. To run a command,
+// use SendCommand or end t with "\r": that is what the Return key sends,
+// and what full-screen programs and some shells expect rather than "\n".
 func (s *session) SendText(t string) error {
+	return s.SendTextWithOptions(t, SendTextOptions{})
+}
+
+// SendCommand types cmd into the session followed by a carriage return,
+// submitting it as if the user had pressed Return.
+func (s *session) SendCommand(cmd string) error {
+	return s.SendText(cmd + "\r")
+}
+
+// SendBytes types data into the session as raw bytes, as if it had arrived
+// on the program's standard input. iTerm2 decodes what it is sent as UTF-8,
+// so byte sequences that are not valid UTF-8 may not arrive unchanged.
+func (s *session) SendBytes(data []byte) error {
+	return s.SendText(string(data))
+}
+
+// SendHex decodes h, a string of hex digits such as "1b5b41", and sends the
+// resulting bytes with SendBytes. Nothing is sent if h has an odd length or
+// contains anything other than hex digits.
+func (s *session) SendHex(h string) error {
+	data, err := hex.DecodeString(h)
+	if err != nil {
+		return fmt.Errorf("could not send hex to session %q: %w", s.id, err)
+	}
+	return s.SendBytes(data)
+}
+
+func (s *session) SendTextWithOptions(t string, opts SendTextOptions) error {
+	req := &api.SendTextRequest{
+		Session: &s.id,
+		Text:    &t,
+	}
+	if opts.SuppressBroadcast {
+		req.SuppressBroadcast = b(true)
+	}
 	resp, err := s.c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_SendTextRequest{
-			SendTextRequest: &api.SendTextRequest{
-				Session: &s.id,
-				Text:    &t,
-			},
+			SendTextRequest: req,
 		},
 	})
 	if err != nil {
 		return fmt.Errorf("error sending text to session %q: %w", s.id, err)
 	}
-	if status := resp.GetSendTextResponse().GetStatus(); status != api.SendTextResponse_OK {
+	switch status := resp.GetSendTextResponse().GetStatus(); status {
+	case api.SendTextResponse_OK:
+	case api.SendTextResponse_SESSION_NOT_FOUND:
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
 		return fmt.Errorf("unexpected status for session %q: %s", s.id, status)
 	}
 	return nil
 }
 
+// inject makes the session process data as if the program running in it had
+// written it to the terminal. Unlike SendText, nothing reaches the program.
+func (s *session) inject(data []byte) error {
+	resp, err := s.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_InjectRequest{
+			InjectRequest: &api.InjectRequest{
+				SessionId: []string{s.id},
+				Data:      data,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error injecting into session %q: %w", s.id, err)
+	}
+	for _, status := range resp.GetInjectResponse().GetStatus() {
+		switch status {
+		case api.InjectResponse_OK:
+		case api.InjectResponse_SESSION_NOT_FOUND:
+			return fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+		default:
+			return fmt.Errorf("unexpected status for session %q: %s", s.id, status)
+		}
+	}
+	return nil
+}
+
+// SendKeyEvent sends key to the session as if it were typed while holding
+// mods, using the escape sequences xterm produces. This is what full-screen
+// programs such as editors and pagers expect for arrows, function keys and
+// the like.
+func (s *session) SendKeyEvent(key Key, mods Modifiers) error {
+	seq, err := keySequence(key, mods)
+	if err != nil {
+		return fmt.Errorf("could not send key to session %q: %w", s.id, err)
+	}
+	return s.SendText(seq)
+}
+
 func (s *session) Activate(selectTab, orderWindowFront bool) error {
 	resp, err := s.c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_ActivateRequest{
@@ -59,8 +237,12 @@ func (s *session) Activate(selectTab, orderWindowFront bool) error {
 	if err != nil {
 		return fmt.Errorf("error activating session %q: %w", s.id, err)
 	}
-	if status := resp.GetActivateResponse().GetStatus(); status != api.ActivateResponse_OK {
-		return fmt.Errorf("unexpected status for activate request: %s", status)
+	switch status := resp.GetActivateResponse().GetStatus(); status {
+	case api.ActivateResponse_OK:
+	case api.ActivateResponse_BAD_IDENTIFIER:
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
+		return fmt.Errorf("unexpected status activating session %q: %s", s.id, status)
 	}
 	return nil
 }
@@ -70,20 +252,40 @@ func (s *session) SplitPane(opts SplitPaneOptions) (Session, error) {
 	if opts.Vertical {
 		direction = api.SplitPaneRequest_VERTICAL.Enum()
 	}
+	req := &api.SplitPaneRequest{
+		Session:        &s.id,
+		SplitDirection: direction,
+	}
+	if opts.Profile != "" {
+		req.ProfileName = &opts.Profile
+	}
+	return s.split(req)
+}
+
+// split splits the session as described by req and returns the new session.
+func (s *session) split(req *api.SplitPaneRequest) (*session, error) {
 	resp, err := s.c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_SplitPaneRequest{
-			SplitPaneRequest: &api.SplitPaneRequest{
-				Session:        &s.id,
-				SplitDirection: direction,
-			},
+			SplitPaneRequest: req,
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error splitting pane: %w", err)
+		return nil, fmt.Errorf("could not split session %q: %w", s.id, err)
 	}
 	spResp := resp.GetSplitPaneResponse()
+	switch status := spResp.GetStatus(); status {
+	case api.SplitPaneResponse_OK:
+	case api.SplitPaneResponse_SESSION_NOT_FOUND:
+		return nil, fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	case api.SplitPaneResponse_INVALID_PROFILE_NAME:
+		return nil, fmt.Errorf("%w: %q", ErrProfileNotFound, req.GetProfileName())
+	case api.SplitPaneResponse_CANNOT_SPLIT:
+		return nil, fmt.Errorf("%w: %q", ErrCannotSplit, s.id)
+	default:
+		return nil, fmt.Errorf("unexpected status splitting session %q: %s", s.id, status)
+	}
 	if len(spResp.GetSessionId()) < 1 {
-		return nil, fmt.Errorf("expected at least one new session in split pane")
+		return nil, fmt.Errorf("%w: splitting session %q returned no new session", ErrMalformedResponse, s.id)
 	}
 	return &session{
 		c:  s.c,
@@ -91,6 +293,325 @@ func (s *session) SplitPane(opts SplitPaneOptions) (Session, error) {
 	}, nil
 }
 
+// Duplicate opens a new pane to the right of the session that uses the same
+// profile, runs the same command and, when iTerm2 knows the session's
+// working directory, starts in that directory too. Changes made to the
+// session's own copy of the profile other than its command are not carried
+// over.
+func (s *session) Duplicate() (Session, error) {
+	values, err := s.GetVariables("profileName", "path")
+	if err != nil {
+		return nil, fmt.Errorf("could not duplicate session %q: %w", s.id, err)
+	}
+	props, err := getProfileProperties(s.c, s.id, "Custom Command", "Command")
+	if err != nil {
+		return nil, fmt.Errorf("could not duplicate session %q: %w", s.id, err)
+	}
+	req := &api.SplitPaneRequest{
+		Session:        &s.id,
+		SplitDirection: api.SplitPaneRequest_VERTICAL.Enum(),
+	}
+	if name := values["profileName"]; name != "" {
+		req.ProfileName = &name
+	}
+	for _, key := range []string{"Custom Command", "Command"} {
+		if value, ok := props[key]; ok {
+			req.CustomProfileProperties = append(req.CustomProfileProperties,
+				&api.ProfileProperty{Key: str(key), JsonValue: str(value)})
+		}
+	}
+	if dir := values["path"]; dir != "" {
+		custom, err := profileProperty("Custom Directory", "Yes")
+		if err != nil {
+			return nil, err
+		}
+		wd, err := profileProperty("Working Directory", dir)
+		if err != nil {
+			return nil, err
+		}
+		req.CustomProfileProperties = append(req.CustomProfileProperties, custom, wd)
+	}
+	return s.split(req)
+}
+
+// Bury removes the session from its tab without terminating it. Buried
+// sessions keep running and can be restored from iTerm2's
+// Session > Buried Sessions menu.
+func (s *session) Bury() error {
+	status, err := setProperty(s.c, &api.SetPropertyRequest{
+		Identifier: &api.SetPropertyRequest_SessionId{SessionId: s.id},
+	}, "buried", true)
+	if err != nil {
+		return fmt.Errorf("could not bury session %q: %w", s.id, err)
+	}
+	switch status {
+	case api.SetPropertyResponse_OK:
+		return nil
+	case api.SetPropertyResponse_INVALID_TARGET:
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	default:
+		return fmt.Errorf("session %q could not be buried: %s", s.id, status)
+	}
+}
+
+// GetVariables fetches several session variables, such as "jobName" or
+// "path", in one round trip. Variables that are not set map to the empty
+// string; only a session that no longer exists is an error.
+func (s *session) GetVariables(names ...string) (map[string]string, error) {
+	if len(names) == 0 {
+		return map[string]string{}, nil
+	}
+	return getVariables(s.c, SessionScope(s.id), names)
+}
+
+// GetName returns the session's name, as shown in its title bar.
+func (s *session) GetName() (string, error) {
+	values, err := s.GetVariables("name")
+	if err != nil {
+		return "", err
+	}
+	return values["name"], nil
+}
+
+// SetName sets the session's name, which iTerm2 shows in its title. Any
+// Unicode text is allowed, such as an emoji prefix to tell sessions apart.
+// Names may refer to variables, as described in SetTitleFormat.
+func (s *session) SetName(name string) error {
+	return s.setProfileProperty("Name", name)
+}
+
+// GetTTY returns the path of the session's terminal device, such as
+// /dev/ttys003. It is an error if the session has none, as is the case for
+// tmux integration sessions.
+func (s *session) GetTTY() (string, error) {
+	values, err := s.GetVariables("tty")
+	if err != nil {
+		return "", err
+	}
+	if values["tty"] == "" {
+		return "", fmt.Errorf("session %q has no tty", s.id)
+	}
+	return values["tty"], nil
+}
+
+// GetHostname returns the name of the host the session's shell is running
+// on, as reported by iTerm2's shell integration. It changes when the user
+// logs in to another machine with ssh, provided shell integration is
+// installed there too. Without shell integration it returns
+// ErrShellIntegrationUnavailable.
+func (s *session) GetHostname() (string, error) {
+	return s.shellIntegrationVariable("hostname")
+}
+
+// GetUsername returns the name of the user the session's shell is running
+// as, as reported by iTerm2's shell integration, or
+// ErrShellIntegrationUnavailable without it.
+func (s *session) GetUsername() (string, error) {
+	return s.shellIntegrationVariable("username")
+}
+
+// shellIntegrationVariable returns the value of a session variable that
+// only shell integration sets.
+func (s *session) shellIntegrationVariable(name string) (string, error) {
+	values, err := s.GetVariables(name)
+	if err != nil {
+		return "", err
+	}
+	if values[name] == "" {
+		return "", fmt.Errorf("%w: no %s reported for session %q", ErrShellIntegrationUnavailable, name, s.id)
+	}
+	return values[name], nil
+}
+
+// OpenURL opens url, or a file path, with its default macOS application.
+//
+// iTerm2's API has no request for opening URLs, so this falls back to
+// typing an `open` command into the session. The session must be sitting
+// at a shell prompt on the local machine for this to work. The URL is
+// single-quoted so the shell passes it through untouched.
+func (s *session) OpenURL(url string) error {
+	if url == "" {
+		return fmt.Errorf("could not open url in session %q: url must not be empty", s.id)
+	}
+	if strings.ContainsAny(url, "\r\n") {
+		return fmt.Errorf("could not open url %q in session %q: url must not contain line breaks", url, s.id)
+	}
+	return s.SendCommand("open " + shellQuote(url))
+}
+
+// SetWorkingDirectory changes the session's current directory to dir, which
+// must be an absolute path.
+//
+// Like OpenURL, this types a `cd` command into the session, so it relies on
+// the shell to change directory: the session must be sitting at a shell
+// prompt, and the command shows up in the shell's history. The path is
+// single-quoted so the shell passes it through untouched.
+func (s *session) SetWorkingDirectory(dir string) error {
+	if !path.IsAbs(dir) {
+		return fmt.Errorf("could not change directory of session %q: %q is not an absolute path", s.id, dir)
+	}
+	if strings.ContainsAny(dir, "\r\n") {
+		return fmt.Errorf("could not change directory of session %q: %q contains line breaks", s.id, dir)
+	}
+	return s.SendCommand("cd " + shellQuote(dir))
+}
+
+// SetTransparency sets how transparent the session's background is, from
+// 0 (opaque) to 1 (fully transparent).
+func (s *session) SetTransparency(level float64) error {
+	if !(level >= 0 && level <= 1) {
+		return fmt.Errorf("could not set transparency of session %q: %v out of range [0, 1]", s.id, level)
+	}
+	return s.setProfileProperty("Transparency", level)
+}
+
+// SetBlur turns blurring of whatever shows through a transparent
+// background on or off.
+func (s *session) SetBlur(enabled bool) error {
+	return s.setProfileProperty("Blur", enabled)
+}
+
+// maxScrollbackLines is the largest limit iTerm2 stores for "Scrollback
+// Lines".
+const maxScrollbackLines = math.MaxInt32
+
+// SetScrollbackLines sets how many lines of history the session keeps. A
+// value of zero or less makes the scrollback unlimited, bounded only by
+// memory; use it with care for sessions producing a lot of output.
+func (s *session) SetScrollbackLines(n int) error {
+	if n > maxScrollbackLines {
+		return fmt.Errorf("could not set scrollback for session %q: %d lines exceeds the maximum of %d", s.id, n, maxScrollbackLines)
+	}
+	assignments := []*api.SetProfilePropertyRequest_Assignment{{
+		Key:       str("Unlimited Scrollback"),
+		JsonValue: str(strconv.FormatBool(n <= 0)),
+	}}
+	if n > 0 {
+		assignments = append(assignments, &api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Scrollback Lines"),
+			JsonValue: str(strconv.Itoa(n)),
+		})
+	}
+	if err := setProfileProperties(s.c, s.id, assignments...); err != nil {
+		return fmt.Errorf("could not set scrollback for session %q: %w", s.id, err)
+	}
+	return nil
+}
+
+// IsProfileDivorced reports whether the session uses a private copy of its
+// profile, which it gets the first time one of its profile keys is changed,
+// for example by SetTransparency. Changes to a divorced session do not
+// affect other sessions created from the same profile.
+func (s *session) IsProfileDivorced() (bool, error) {
+	props, err := getProfileProperties(s.c, s.id, "Guid", "Original Guid")
+	if err != nil {
+		return false, fmt.Errorf("could not get profile of session %q: %w", s.id, err)
+	}
+	// A divorced session's copy has a GUID of its own and remembers the
+	// GUID of the profile it was copied from.
+	var guid, original string
+	json.Unmarshal([]byte(props["Guid"]), &guid)
+	json.Unmarshal([]byte(props["Original Guid"]), &original)
+	return original != "" && original != guid, nil
+}
+
+// GetProfileName returns the name of the profile the session was created
+// from. For a divorced session that is the name of the profile it was
+// copied from, even if the copy has been renamed since, as SetTitleFormat
+// does; see IsProfileDivorced. If that profile has been deleted, the name
+// of the session's copy is returned.
+func (s *session) GetProfileName() (string, error) {
+	props, err := getProfileProperties(s.c, s.id, "Name", "Guid", "Original Guid")
+	if err != nil {
+		return "", fmt.Errorf("could not get profile of session %q: %w", s.id, err)
+	}
+	var name, guid, original string
+	json.Unmarshal([]byte(props["Name"]), &name)
+	json.Unmarshal([]byte(props["Guid"]), &guid)
+	json.Unmarshal([]byte(props["Original Guid"]), &original)
+	if original == "" || original == guid {
+		return name, nil
+	}
+	profiles, err := listProfiles(s.c, original)
+	if err != nil {
+		return "", fmt.Errorf("could not get profile of session %q: %w", s.id, err)
+	}
+	if base, ok := profiles[original]; ok {
+		var baseName string
+		if json.Unmarshal([]byte(base["Name"]), &baseName) == nil {
+			return baseName, nil
+		}
+	}
+	return name, nil
+}
+
+// setProfileProperty sets a single key of the session's profile to the
+// JSON encoding of value. Like every profile change made through a
+// session, it divorces the session from its profile; see IsProfileDivorced.
+func (s *session) setProfileProperty(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("could not encode %q for session %q: %w", key, s.id, err)
+	}
+	err = setProfileProperties(s.c, s.id, &api.SetProfilePropertyRequest_Assignment{
+		Key:       &key,
+		JsonValue: str(string(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("could not set %q for session %q: %w", key, s.id, err)
+	}
+	return nil
+}
+
+// SetProfileProperties sets several keys of the session's profile in a
+// single request, so that a whole theme of colors and transparency changes
+// at once. props maps profile keys, such as "Background Color", to their
+// JSON-encoded values; every value is checked to be valid JSON before
+// anything is sent. Like the other setters, this divorces the session from
+// its profile, and it needs API version 1.1; see App.GetAPIVersion.
+func (s *session) SetProfileProperties(props map[string]string) error {
+	if len(props) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	assignments := make([]*api.SetProfilePropertyRequest_Assignment, 0, len(keys))
+	for _, k := range keys {
+		if !json.Valid([]byte(props[k])) {
+			return fmt.Errorf("could not set profile properties for session %q: %q is not valid JSON: %s", s.id, k, props[k])
+		}
+		assignments = append(assignments, &api.SetProfilePropertyRequest_Assignment{
+			Key:       str(k),
+			JsonValue: str(props[k]),
+		})
+	}
+	if err := setProfileProperties(s.c, s.id, assignments...); err != nil {
+		return fmt.Errorf("could not set profile properties for session %q: %w", s.id, err)
+	}
+	return nil
+}
+
+// GetTab returns the tab the session is in. Sessions found through a
+// listing remember their tab, so this normally needs no call to iTerm2;
+// other sessions are looked up. Buried sessions are in no tab, and
+// return an error.
+func (s *session) GetTab() (Tab, error) {
+	windowID, tabID := s.location()
+	if tabID == "" {
+		found, err := findSession(s.c, s.id)
+		if err != nil {
+			return nil, fmt.Errorf("could not get tab of session %q: %w", s.id, err)
+		}
+		if windowID, tabID = found.location(); tabID == "" {
+			return nil, fmt.Errorf("session %q is buried and not in any tab", s.id)
+		}
+	}
+	return &tab{c: s.c, id: tabID, windowID: windowID}, nil
+}
+
 func (s *session) GetSessionID() string {
 	return s.id
 }
@@ -0,0 +1,760 @@
+package iterm2
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// sendTextOK is a canned successful SendTextResponse
+func sendTextOK() *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_SendTextResponse{
+			SendTextResponse: &api.SendTextResponse{
+				Status: api.SendTextResponse_OK.Enum(),
+			},
+		},
+	}
+}
+
+// TestSendTextWithOptions verifies the suppress_broadcast flag is only set when requested
+func TestSendTextWithOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		send         func(s *session) error
+		wantSuppress bool
+		wantSet      bool
+	}{
+		{
+			name:    "SendText keeps default broadcast behavior",
+			send:    func(s *session) error { return s.SendText("ls") },
+			wantSet: false,
+		},
+		{
+			name: "zero options keep default broadcast behavior",
+			send: func(s *session) error {
+				return s.SendTextWithOptions("ls", SendTextOptions{})
+			},
+			wantSet: false,
+		},
+		{
+			name: "suppress broadcast",
+			send: func(s *session) error {
+				return s.SendTextWithOptions("ls", SendTextOptions{SuppressBroadcast: true})
+			},
+			wantSuppress: true,
+			wantSet:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{
+				responses: []*api.ServerOriginatedMessage{sendTextOK()},
+			}
+			s := &session{c: mock, id: "sess-1"}
+
+			if err := tt.send(s); err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+			req := mock.calls[0].GetSendTextRequest()
+			if req.GetSession() != "sess-1" || req.GetText() != "ls" {
+				t.Errorf("request = %v, want session sess-1 and text ls", req)
+			}
+			if (req.SuppressBroadcast != nil) != tt.wantSet {
+				t.Errorf("suppress_broadcast set = %v, want %v", req.SuppressBroadcast != nil, tt.wantSet)
+			}
+			if req.GetSuppressBroadcast() != tt.wantSuppress {
+				t.Errorf("suppress_broadcast = %v, want %v", req.GetSuppressBroadcast(), tt.wantSuppress)
+			}
+		})
+	}
+}
+
+// TestSendCommand verifies the exact bytes sent by SendText and SendCommand
+func TestSendCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(s *session) error
+		want string
+	}{
+		{
+			name: "SendText is verbatim",
+			send: func(s *session) error { return s.SendText("echo hi") },
+			want: "echo hi",
+		},
+		{
+			name: "SendCommand appends a carriage return",
+			send: func(s *session) error { return s.SendCommand("echo hi") },
+			want: "echo hi\r",
+		},
+		{
+			name: "SendCommand with empty command presses Return",
+			send: func(s *session) error { return s.SendCommand("") },
+			want: "\r",
+		},
+		{
+			name: "SendBytes is verbatim",
+			send: func(s *session) error { return s.SendBytes([]byte{0x1b, '[', 'A'}) },
+			want: "\x1b[A",
+		},
+		{
+			name: "SendHex decodes the bytes",
+			send: func(s *session) error { return s.SendHex("1b5B41") },
+			want: "\x1b[A",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{sendTextOK()}}
+			s := &session{c: mock, id: "sess-1"}
+
+			if err := tt.send(s); err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+			if got := mock.calls[0].GetSendTextRequest().GetText(); got != tt.want {
+				t.Errorf("sent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendHex_Invalid verifies malformed hex is rejected before calling iTerm2
+func TestSendHex_Invalid(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want error
+	}{
+		{"1b5", hex.ErrLength},
+		{"1g", hex.InvalidByteError('g')},
+		{"1b 5b", hex.InvalidByteError(' ')},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hex, func(t *testing.T) {
+			mock := &mockClient{}
+			s := &session{c: mock, id: "sess-1"}
+			if err := s.SendHex(tt.hex); !errors.Is(err, tt.want) {
+				t.Errorf("SendHex(%q) error = %v, want %v", tt.hex, err, tt.want)
+			}
+			if len(mock.calls) != 0 {
+				t.Errorf("expected no Calls, got %d", len(mock.calls))
+			}
+		})
+	}
+}
+
+// TestBury verifies the buried property is set and failures are reported
+func TestBury(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  api.SetPropertyResponse_Status
+		wantErr error
+	}{
+		{name: "buried", status: api.SetPropertyResponse_OK},
+		{name: "session gone", status: api.SetPropertyResponse_INVALID_TARGET, wantErr: ErrSessionNotFound},
+		{name: "last session", status: api.SetPropertyResponse_IMPOSSIBLE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{
+				responses: []*api.ServerOriginatedMessage{{
+					Submessage: &api.ServerOriginatedMessage_SetPropertyResponse{
+						SetPropertyResponse: &api.SetPropertyResponse{Status: tt.status.Enum()},
+					},
+				}},
+			}
+			s := &session{c: mock, id: "sess-1"}
+
+			err := s.Bury()
+			if tt.status == api.SetPropertyResponse_OK {
+				if err != nil {
+					t.Fatalf("Bury() error = %v", err)
+				}
+			} else if err == nil {
+				t.Fatal("Bury() expected error, got nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Bury() error = %v, want %v", err, tt.wantErr)
+			}
+
+			req := mock.calls[0].GetSetPropertyRequest()
+			if req.GetSessionId() != "sess-1" || req.GetName() != "buried" || req.GetJsonValue() != "true" {
+				t.Errorf("unexpected SetPropertyRequest %v", req)
+			}
+		})
+	}
+}
+
+func variableResponse(status api.VariableResponse_Status, values ...string) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_VariableResponse{
+			VariableResponse: &api.VariableResponse{Status: status.Enum(), Values: values},
+		},
+	}
+}
+
+// TestGetVariables verifies variables are fetched in a single request and unset ones map to empty strings
+func TestGetVariables(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{
+			variableResponse(api.VariableResponse_OK, `"vim"`, `null`, `"/tmp"`),
+		},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	got, err := s.GetVariables("jobName", "user.missing", "path")
+	if err != nil {
+		t.Fatalf("GetVariables() error = %v", err)
+	}
+	want := map[string]string{"jobName": "vim", "user.missing": "", "path": "/tmp"}
+	if len(got) != len(want) {
+		t.Fatalf("GetVariables() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected 1 Call, got %d", len(mock.calls))
+	}
+	req := mock.calls[0].GetVariableRequest()
+	if req.GetSessionId() != "sess-1" || len(req.GetGet()) != 3 {
+		t.Errorf("unexpected VariableRequest %v", req)
+	}
+}
+
+// TestGetVariables_SessionGone verifies a missing session is reported with ErrSessionNotFound
+func TestGetVariables_SessionGone(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{variableResponse(api.VariableResponse_SESSION_NOT_FOUND)},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	if _, err := s.GetVariables("name"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("GetVariables() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestGetName verifies GetName reads the name variable
+func TestGetName(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{variableResponse(api.VariableResponse_OK, `"build (zsh)"`)},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	name, err := s.GetName()
+	if err != nil {
+		t.Fatalf("GetName() error = %v", err)
+	}
+	if name != "build (zsh)" {
+		t.Errorf("GetName() = %q, want %q", name, "build (zsh)")
+	}
+	if get := mock.calls[0].GetVariableRequest().GetGet(); len(get) != 1 || get[0] != "name" {
+		t.Errorf("requested %v, want [name]", get)
+	}
+}
+
+// TestGetTTY verifies the tty variable is returned and a missing one is an error
+func TestGetTTY(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{
+			variableResponse(api.VariableResponse_OK, `"/dev/ttys003"`),
+			variableResponse(api.VariableResponse_OK, `null`),
+		},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	tty, err := s.GetTTY()
+	if err != nil {
+		t.Fatalf("GetTTY() error = %v", err)
+	}
+	if tty != "/dev/ttys003" {
+		t.Errorf("GetTTY() = %q, want %q", tty, "/dev/ttys003")
+	}
+	if get := mock.calls[0].GetVariableRequest().GetGet(); len(get) != 1 || get[0] != "tty" {
+		t.Errorf("requested %v, want [tty]", get)
+	}
+	if tty, err := s.GetTTY(); err == nil {
+		t.Errorf("GetTTY() without a tty = %q, want error", tty)
+	}
+}
+
+// TestGetHostname verifies the shell integration variables are returned and missing ones reported
+func TestGetHostname(t *testing.T) {
+	tests := []struct {
+		name string
+		get  func(s *session) (string, error)
+		want string
+	}{
+		{"hostname", (*session).GetHostname, "build.example.com"},
+		{"username", (*session).GetUsername, "deploy"},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{
+			responses: []*api.ServerOriginatedMessage{
+				variableResponse(api.VariableResponse_OK, `"`+tt.want+`"`),
+				variableResponse(api.VariableResponse_OK, `null`),
+			},
+		}
+		s := &session{c: mock, id: "sess-1"}
+
+		got, err := tt.get(s)
+		if err != nil {
+			t.Fatalf("getting %s error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+		if get := mock.calls[0].GetVariableRequest().GetGet(); len(get) != 1 || get[0] != tt.name {
+			t.Errorf("requested %v, want [%s]", get, tt.name)
+		}
+		if _, err := tt.get(s); !errors.Is(err, ErrShellIntegrationUnavailable) {
+			t.Errorf("%s without shell integration error = %v, want ErrShellIntegrationUnavailable", tt.name, err)
+		}
+	}
+}
+
+// TestOpenURL verifies the URL is quoted into an open command and unsafe input is rejected
+func TestOpenURL(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{sendTextOK()}}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.OpenURL("https://example.com/?q=a&b='c'"); err != nil {
+		t.Fatalf("OpenURL() error = %v", err)
+	}
+	want := `open 'https://example.com/?q=a&b='\''c'\'''` + "\r"
+	if got := mock.calls[0].GetSendTextRequest().GetText(); got != want {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+
+	for _, url := range []string{"", "https://example.com\nrm -rf ~"} {
+		if err := s.OpenURL(url); err == nil {
+			t.Errorf("OpenURL(%q) expected error, got nil", url)
+		}
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("expected no Calls for rejected URLs, got %d", len(mock.calls)-1)
+	}
+}
+
+// TestSetWorkingDirectory verifies the directory is quoted into a cd command and relative or unsafe paths are rejected
+func TestSetWorkingDirectory(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{sendTextOK()}}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetWorkingDirectory("/Users/me/it's here"); err != nil {
+		t.Fatalf("SetWorkingDirectory() error = %v", err)
+	}
+	want := `cd '/Users/me/it'\''s here'` + "\r"
+	if got := mock.calls[0].GetSendTextRequest().GetText(); got != want {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+
+	for _, dir := range []string{"", "src", "~/src", "/tmp\nrm -rf ~"} {
+		if err := s.SetWorkingDirectory(dir); err == nil {
+			t.Errorf("SetWorkingDirectory(%q) expected error, got nil", dir)
+		}
+	}
+	if len(mock.calls) != 1 {
+		t.Errorf("expected no Calls for rejected directories, got %d", len(mock.calls)-1)
+	}
+}
+
+// TestSetTransparency verifies the level is validated and written to the Transparency key
+func TestSetTransparency(t *testing.T) {
+	tests := []struct {
+		level   float64
+		want    string
+		wantErr bool
+	}{
+		{level: 0, want: "0"},
+		{level: 0.25, want: "0.25"},
+		{level: 1, want: "1"},
+		{level: -0.1, wantErr: true},
+		{level: 1.5, wantErr: true},
+		{level: math.NaN(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+
+		err := s.SetTransparency(tt.level)
+		if tt.wantErr {
+			if err == nil || len(mock.calls) != 0 {
+				t.Errorf("SetTransparency(%v) expected error without Calls, got %v and %d calls", tt.level, err, len(mock.calls))
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("SetTransparency(%v) error = %v", tt.level, err)
+		}
+		a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+		if len(a) != 1 || a[0].GetKey() != "Transparency" || a[0].GetJsonValue() != tt.want {
+			t.Errorf("SetTransparency(%v) assignments = %v, want Transparency=%s", tt.level, a, tt.want)
+		}
+	}
+}
+
+// TestSetBlur verifies the Blur key is written
+func TestSetBlur(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetBlur(true); err != nil {
+		t.Fatalf("SetBlur() error = %v", err)
+	}
+	req := mock.calls[0].GetSetProfilePropertyRequest()
+	a := req.GetAssignments()
+	if req.GetSession() != "sess-1" || len(a) != 1 || a[0].GetKey() != "Blur" || a[0].GetJsonValue() != "true" {
+		t.Errorf("unexpected SetProfilePropertyRequest %v", req)
+	}
+}
+
+// TestSetScrollbackLines verifies limited and unlimited scrollback settings
+func TestSetScrollbackLines(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want map[string]string
+	}{
+		{name: "limited", n: 100000, want: map[string]string{"Unlimited Scrollback": "false", "Scrollback Lines": "100000"}},
+		{name: "zero is unlimited", n: 0, want: map[string]string{"Unlimited Scrollback": "true"}},
+		{name: "negative is unlimited", n: -1, want: map[string]string{"Unlimited Scrollback": "true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{}
+			s := &session{c: mock, id: "sess-1"}
+
+			if err := s.SetScrollbackLines(tt.n); err != nil {
+				t.Fatalf("SetScrollbackLines() error = %v", err)
+			}
+			if len(mock.calls) != 1 {
+				t.Fatalf("expected 1 Call, got %d", len(mock.calls))
+			}
+			got := map[string]string{}
+			for _, a := range mock.calls[0].GetSetProfilePropertyRequest().GetAssignments() {
+				got[a.GetKey()] = a.GetJsonValue()
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("assignments = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// TestDuplicate verifies the new pane gets the source's profile, command and working directory
+func TestDuplicate(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		wantProps map[string]string
+	}{
+		{
+			name: "with working directory",
+			path: `"/src/app"`,
+			wantProps: map[string]string{
+				"Custom Command":    `"Yes"`,
+				"Command":           `"htop"`,
+				"Custom Directory":  `"Yes"`,
+				"Working Directory": `"/src/app"`,
+			},
+		},
+		{
+			name: "unknown working directory",
+			path: `null`,
+			wantProps: map[string]string{
+				"Custom Command": `"Yes"`,
+				"Command":        `"htop"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+				variableResponse(api.VariableResponse_OK, `"Work"`, tt.path),
+				{Submessage: &api.ServerOriginatedMessage_GetProfilePropertyResponse{
+					GetProfilePropertyResponse: &api.GetProfilePropertyResponse{
+						Status: api.GetProfilePropertyResponse_OK.Enum(),
+						Properties: []*api.ProfileProperty{
+							{Key: str("Custom Command"), JsonValue: str(`"Yes"`)},
+							{Key: str("Command"), JsonValue: str(`"htop"`)},
+						},
+					},
+				}},
+				{Submessage: &api.ServerOriginatedMessage_SplitPaneResponse{
+					SplitPaneResponse: &api.SplitPaneResponse{
+						Status:    api.SplitPaneResponse_OK.Enum(),
+						SessionId: []string{"sess-2"},
+					},
+				}},
+			}}
+			s := &session{c: mock, id: "sess-1"}
+
+			dup, err := s.Duplicate()
+			if err != nil {
+				t.Fatalf("Duplicate() error = %v", err)
+			}
+			if dup.GetSessionID() != "sess-2" {
+				t.Errorf("Duplicate() = %q, want sess-2", dup.GetSessionID())
+			}
+			req := mock.calls[2].GetSplitPaneRequest()
+			if req.GetSession() != "sess-1" || req.GetProfileName() != "Work" {
+				t.Errorf("unexpected SplitPaneRequest %v", req)
+			}
+			got := map[string]string{}
+			for _, p := range req.GetCustomProfileProperties() {
+				got[p.GetKey()] = p.GetJsonValue()
+			}
+			if len(got) != len(tt.wantProps) {
+				t.Fatalf("properties = %v, want %v", got, tt.wantProps)
+			}
+			for k, v := range tt.wantProps {
+				if got[k] != v {
+					t.Errorf("%s = %s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+// TestSessionGetTab verifies the stored tab is used and other sessions are looked up in the split trees
+func TestSessionGetTab(t *testing.T) {
+	mock := &mockClient{}
+	got, err := (&session{c: mock, id: "sess-3", windowID: "win-1", tabID: "2"}).GetTab()
+	if err != nil {
+		t.Fatalf("GetTab() error = %v", err)
+	}
+	if got.GetID() != "2" || got.(*tab).windowID != "win-1" || len(mock.calls) != 0 {
+		t.Errorf("GetTab() = %+v after %d calls, want tab 2 in win-1 without calls", got, len(mock.calls))
+	}
+
+	resp := layout()
+	resp.GetListSessionsResponse().BuriedSessions = []*api.SessionSummary{{UniqueIdentifier: str("sess-9")}}
+	tests := []struct {
+		id         string
+		wantTab    string
+		wantWindow string
+		wantErr    bool
+	}{
+		{id: "sess-4", wantTab: "2", wantWindow: "win-1"},
+		{id: "sess-5", wantTab: "3", wantWindow: "win-2"},
+		{id: "sess-9", wantErr: true},
+		{id: "sess-0", wantErr: true},
+	}
+	for _, tt := range tests {
+		s := &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{resp}}, id: tt.id}
+		got, err := s.GetTab()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("GetTab() of %s = %+v, want error", tt.id, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("GetTab() of %s error = %v", tt.id, err)
+		}
+		if got.GetID() != tt.wantTab || got.(*tab).windowID != tt.wantWindow {
+			t.Errorf("GetTab() of %s = %+v, want tab %s in %s", tt.id, got, tt.wantTab, tt.wantWindow)
+		}
+	}
+}
+
+// TestIsProfileDivorced verifies a session is divorced when its profile was copied from another one
+func TestIsProfileDivorced(t *testing.T) {
+	tests := []struct {
+		name  string
+		props []*api.ProfileProperty
+		want  bool
+	}{
+		{
+			name:  "shared profile",
+			props: []*api.ProfileProperty{{Key: str("Guid"), JsonValue: str(`"A"`)}},
+		},
+		{
+			name: "original is itself",
+			props: []*api.ProfileProperty{
+				{Key: str("Guid"), JsonValue: str(`"A"`)},
+				{Key: str("Original Guid"), JsonValue: str(`"A"`)},
+			},
+		},
+		{
+			name: "unset original",
+			props: []*api.ProfileProperty{
+				{Key: str("Guid"), JsonValue: str(`"A"`)},
+				{Key: str("Original Guid"), JsonValue: str(`null`)},
+			},
+		},
+		{
+			name: "divorced",
+			props: []*api.ProfileProperty{
+				{Key: str("Guid"), JsonValue: str(`"B"`)},
+				{Key: str("Original Guid"), JsonValue: str(`"A"`)},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: []*api.ServerOriginatedMessage{{
+				Submessage: &api.ServerOriginatedMessage_GetProfilePropertyResponse{
+					GetProfilePropertyResponse: &api.GetProfilePropertyResponse{
+						Status:     api.GetProfilePropertyResponse_OK.Enum(),
+						Properties: tt.props,
+					},
+				},
+			}}}
+			s := &session{c: mock, id: "sess-1"}
+			got, err := s.IsProfileDivorced()
+			if err != nil {
+				t.Fatalf("IsProfileDivorced() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsProfileDivorced() = %v, want %v", got, tt.want)
+			}
+			if req := mock.calls[0].GetGetProfilePropertyRequest(); req.GetSession() != "sess-1" {
+				t.Errorf("unexpected GetProfilePropertyRequest %v", req)
+			}
+		})
+	}
+}
+
+// TestGetProfileName verifies divorced sessions report the profile they were copied from
+func TestGetProfileName(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []*api.ServerOriginatedMessage
+		want      string
+		wantCalls int
+	}{
+		{
+			name: "shared profile",
+			responses: []*api.ServerOriginatedMessage{
+				profileProperties(api.GetProfilePropertyResponse_OK, "Name", `"Default"`, "Guid", `"A"`),
+			},
+			want:      "Default",
+			wantCalls: 1,
+		},
+		{
+			name: "divorced and renamed",
+			responses: []*api.ServerOriginatedMessage{
+				profileProperties(api.GetProfilePropertyResponse_OK,
+					"Name", `"\\(session.jobName)"`, "Guid", `"B"`, "Original Guid", `"A"`),
+				profiles(map[string]string{"Guid": `"A"`, "Name": `"Default"`}),
+			},
+			want:      "Default",
+			wantCalls: 2,
+		},
+		{
+			name: "base profile deleted",
+			responses: []*api.ServerOriginatedMessage{
+				profileProperties(api.GetProfilePropertyResponse_OK,
+					"Name", `"Work"`, "Guid", `"B"`, "Original Guid", `"A"`),
+				profiles(),
+			},
+			want:      "Work",
+			wantCalls: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: tt.responses}
+			s := &session{c: mock, id: "sess-1"}
+			got, err := s.GetProfileName()
+			if err != nil {
+				t.Fatalf("GetProfileName() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetProfileName() = %q, want %q", got, tt.want)
+			}
+			if len(mock.calls) != tt.wantCalls {
+				t.Fatalf("expected %d Calls, got %d", tt.wantCalls, len(mock.calls))
+			}
+			if tt.wantCalls > 1 {
+				if guids := mock.calls[1].GetListProfilesRequest().GetGuids(); len(guids) != 1 || guids[0] != "A" {
+					t.Errorf("ListProfilesRequest guids = %v, want [A]", guids)
+				}
+			}
+		})
+	}
+}
+
+// TestGetProfileName_SessionNotFound verifies a closed session is reported with ErrSessionNotFound
+func TestGetProfileName_SessionNotFound(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		profileProperties(api.GetProfilePropertyResponse_SESSION_NOT_FOUND),
+	}}
+	_, err := (&session{c: mock, id: "sess-1"}).GetProfileName()
+	if !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("GetProfileName() error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+// TestProfileSettersTargetSession verifies profile setters address the session's own copy, never profiles by GUID
+func TestProfileSettersTargetSession(t *testing.T) {
+	setters := map[string]func(s *session) error{
+		"SetTransparency":  func(s *session) error { return s.SetTransparency(0.5) },
+		"SetCursorShape":   func(s *session) error { return s.SetCursorShape(CursorBox) },
+		"SetOptionKeyMode": func(s *session) error { return s.SetOptionKeyMode(OptionKeyEsc, OptionKeyEsc) },
+		"SetName":          func(s *session) error { return s.SetName("build") },
+	}
+	for name, set := range setters {
+		mock := &mockClient{}
+		if err := set(&session{c: mock, id: "sess-1"}); err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		req := mock.calls[0].GetSetProfilePropertyRequest()
+		if req.GetSession() != "sess-1" || req.GetGuidList() != nil {
+			t.Errorf("%s() target = %v, want session sess-1", name, req.GetTarget())
+		}
+	}
+}
+
+// TestSetProfileProperties verifies all keys go out in one request and invalid JSON is rejected before calling iTerm2
+func TestSetProfileProperties(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	err := s.SetProfileProperties(map[string]string{
+		"Transparency":     "0.2",
+		"Background Color": `{"Red Component": 0, "Green Component": 0, "Blue Component": 0}`,
+		"Blur":             "true",
+	})
+	if err != nil {
+		t.Fatalf("SetProfileProperties() error = %v", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected a single Call, got %d", len(mock.calls))
+	}
+	req := mock.calls[0].GetSetProfilePropertyRequest()
+	a := req.GetAssignments()
+	if req.GetSession() != "sess-1" || len(a) != 3 ||
+		a[0].GetKey() != "Background Color" || a[1].GetKey() != "Blur" || a[2].GetKey() != "Transparency" ||
+		a[2].GetJsonValue() != "0.2" {
+		t.Errorf("unexpected SetProfilePropertyRequest %v", req)
+	}
+
+	for _, props := range []map[string]string{
+		{"Transparency": "0.2", "Name": "build"},
+		{"Blur": ""},
+	} {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+		if err := s.SetProfileProperties(props); err == nil || len(mock.calls) != 0 {
+			t.Errorf("SetProfileProperties(%v) expected error without Calls, got %v and %d calls", props, err, len(mock.calls))
+		}
+	}
+}
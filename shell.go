@@ -0,0 +1,14 @@
+package iterm2
+
+import (
+	"regexp"
+	"strings"
+)
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellQuote quotes s so that a POSIX shell reads it back as a single word
+// with no expansion applied.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
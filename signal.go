@@ -0,0 +1,32 @@
+package iterm2
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// SendSignal sends sig, such as syscall.SIGTERM, to the session's foreground
+// job: the process group of the program the session is running, or of its
+// shell when it is sitting at a prompt.
+//
+// iTerm2's API cannot deliver signals, so this signals the process directly
+// through the operating system, after looking up its id in the session's
+// jobPid variable. It therefore only works when this program runs on the
+// same machine as iTerm2 and is allowed to signal the process. For a
+// session logged into another machine the local job is the ssh client, and
+// that is what gets signalled.
+func (s *session) SendSignal(sig os.Signal) error {
+	values, err := s.GetVariables("jobPid")
+	if err != nil {
+		return err
+	}
+	pid, err := strconv.Atoi(values["jobPid"])
+	if err != nil || pid <= 0 {
+		return fmt.Errorf("could not determine the foreground process of session %q", s.id)
+	}
+	if err := signalProcessGroup(pid, sig); err != nil {
+		return fmt.Errorf("could not signal process %d of session %q: %w", pid, s.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package iterm2
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestSendSignal verifies the signal goes to the session's foreground job and a missing job is an error
+func TestSendSignal(t *testing.T) {
+	defer func(f func(int, os.Signal) error) { signalProcessGroup = f }(signalProcessGroup)
+	var gotPID int
+	var gotSig os.Signal
+	signalProcessGroup = func(pid int, sig os.Signal) error {
+		gotPID, gotSig = pid, sig
+		return nil
+	}
+
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{variableResponse(api.VariableResponse_OK, "4242")}}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.SendSignal(syscall.SIGTERM); err != nil {
+		t.Fatalf("SendSignal() error = %v", err)
+	}
+	if gotPID != 4242 || gotSig != syscall.SIGTERM {
+		t.Errorf("signalled %d with %v, want 4242 with %v", gotPID, gotSig, syscall.SIGTERM)
+	}
+	if req := mock.calls[0].GetVariableRequest(); req.GetSessionId() != "sess-1" || req.GetGet()[0] != "jobPid" {
+		t.Errorf("unexpected VariableRequest %v", req)
+	}
+
+	gotPID = 0
+	for _, value := range []string{"null", "0", `"x"`} {
+		s := &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{variableResponse(api.VariableResponse_OK, value)}}, id: "sess-1"}
+		if err := s.SendSignal(syscall.SIGTERM); err == nil {
+			t.Errorf("SendSignal() with jobPid %s expected error, got nil", value)
+		}
+	}
+	if gotPID != 0 {
+		t.Errorf("expected no signal without a job, signalled %d", gotPID)
+	}
+
+	signalProcessGroup = func(int, os.Signal) error { return syscall.EPERM }
+	s = &session{c: &mockClient{responses: []*api.ServerOriginatedMessage{variableResponse(api.VariableResponse_OK, "4242")}}, id: "sess-1"}
+	if err := s.SendSignal(syscall.SIGKILL); !errors.Is(err, syscall.EPERM) {
+		t.Errorf("SendSignal() error = %v, want EPERM", err)
+	}
+}
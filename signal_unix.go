@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package iterm2
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// signalProcessGroup sends sig to the process group of the process pid.
+// It is a variable so tests can intercept signals.
+var signalProcessGroup = func(pid int, sig os.Signal) error {
+	ssig, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %v", sig)
+	}
+	pgid, err := syscall.Getpgid(pid)
+	if err != nil {
+		return err
+	}
+	return syscall.Kill(-pgid, ssig)
+}
@@ -0,0 +1,12 @@
+package iterm2
+
+import (
+	"errors"
+	"os"
+)
+
+// signalProcessGroup is not available on Windows, which has no process
+// groups to signal.
+var signalProcessGroup = func(pid int, sig os.Signal) error {
+	return errors.New("signals are not supported on windows")
+}
@@ -0,0 +1,133 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// AppSnapshot is the layout of iTerm2's windows, tabs and sessions at one
+// point in time, as returned by Snapshot.
+type AppSnapshot struct {
+	Windows []WindowSnapshot
+}
+
+// WindowSnapshot describes a window and its tabs, in the order they are
+// shown.
+type WindowSnapshot struct {
+	ID   string
+	Tabs []TabSnapshot
+}
+
+// TabSnapshot describes a tab and its sessions, in layout order.
+type TabSnapshot struct {
+	ID string
+	// Color is the tab's color, or nil if it has none.
+	Color    *Color
+	Sessions []SessionSnapshot
+}
+
+// SessionSnapshot describes a session.
+type SessionSnapshot struct {
+	ID string
+	// Title is the session's title as shown in its tab.
+	Title string
+}
+
+// Snapshot returns all windows with their tabs, sessions, session titles
+// and tab colors. Buried sessions are left out.
+//
+// It costs one request to iTerm2 for the layout and titles, plus one per
+// tab for its color, which iTerm2 only reports per session. The color
+// requests are all sent at once rather than one after the other, so a
+// snapshot takes about two round trips however many tabs are open. This is
+// much cheaper than walking the windows, tabs and sessions through their own
+// methods, but not free: a program polling it should do so every few
+// seconds at most, or watch for changes with the Subscribe methods instead.
+// Tabs closed while the snapshot is taken are reported without a color.
+func (a *app) Snapshot() (AppSnapshot, error) {
+	lsr, err := listSessions(a.c)
+	if err != nil {
+		return AppSnapshot{}, err
+	}
+	snap := AppSnapshot{Windows: []WindowSnapshot{}}
+	for _, w := range lsr.GetWindows() {
+		ws := WindowSnapshot{ID: w.GetWindowId(), Tabs: []TabSnapshot{}}
+		for _, t := range w.GetTabs() {
+			ws.Tabs = append(ws.Tabs, TabSnapshot{ID: t.GetTabId(), Sessions: sessionSnapshots(t.GetRoot())})
+		}
+		snap.Windows = append(snap.Windows, ws)
+	}
+
+	var tabs []*TabSnapshot
+	for i := range snap.Windows {
+		for j := range snap.Windows[i].Tabs {
+			if ts := &snap.Windows[i].Tabs[j]; len(ts.Sessions) > 0 {
+				tabs = append(tabs, ts)
+			}
+		}
+	}
+	errs := make([]error, len(tabs))
+	var wg sync.WaitGroup
+	for i, ts := range tabs {
+		wg.Add(1)
+		go func(i int, ts *TabSnapshot) {
+			defer wg.Done()
+			// The tab's color is held by its first session's profile.
+			ts.Color, errs[i] = tabColor(a.c, ts.Sessions[0].ID)
+		}(i, ts)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return AppSnapshot{}, fmt.Errorf("could not get color of tab %q: %w", tabs[i].ID, err)
+		}
+	}
+	return snap, nil
+}
+
+// sessionSnapshots describes the sessions of a tab's split tree, in layout
+// order.
+func sessionSnapshots(node *api.SplitTreeNode) []SessionSnapshot {
+	sessions := []SessionSnapshot{}
+	for _, link := range node.GetLinks() {
+		if s := link.GetSession(); s != nil {
+			sessions = append(sessions, SessionSnapshot{ID: s.GetUniqueIdentifier(), Title: s.GetTitle()})
+		} else {
+			sessions = append(sessions, sessionSnapshots(link.GetNode())...)
+		}
+	}
+	return sessions
+}
+
+// tabColor returns the tab color set in the profile of the session, or nil
+// if it is turned off.
+func tabColor(c ClientInterface, sessionID string) (*Color, error) {
+	props, err := getProfileProperties(c, sessionID, "Tab Color", "Use Tab Color")
+	if err != nil {
+		return nil, err
+	}
+	var use bool
+	if err := json.Unmarshal([]byte(props["Use Tab Color"]), &use); err != nil || !use {
+		return nil, nil
+	}
+	var value struct {
+		R float64 `json:"Red Component"`
+		G float64 `json:"Green Component"`
+		B float64 `json:"Blue Component"`
+	}
+	if err := json.Unmarshal([]byte(props["Tab Color"]), &value); err != nil {
+		return nil, fmt.Errorf("%w: tab color %s", ErrMalformedResponse, props["Tab Color"])
+	}
+	return &Color{R: colorComponent(value.R), G: colorComponent(value.G), B: colorComponent(value.B)}, nil
+}
+
+// colorComponent converts a color component from the 0-1 range iTerm2
+// stores to 8 bits.
+func colorComponent(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+}
@@ -0,0 +1,83 @@
+package iterm2
+
+import (
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// profileProperties is a canned GetProfilePropertyResponse
+func profileProperties(status api.GetProfilePropertyResponse_Status, keyValues ...string) *api.ServerOriginatedMessage {
+	var props []*api.ProfileProperty
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		props = append(props, &api.ProfileProperty{Key: str(keyValues[i]), JsonValue: str(keyValues[i+1])})
+	}
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_GetProfilePropertyResponse{
+			GetProfilePropertyResponse: &api.GetProfilePropertyResponse{Status: status.Enum(), Properties: props},
+		},
+	}
+}
+
+// TestSnapshot verifies the layout, titles and tab colors are gathered with one color lookup per tab
+func TestSnapshot(t *testing.T) {
+	lsr := layout()
+	root := lsr.GetListSessionsResponse().GetWindows()[0].GetTabs()[0].GetRoot()
+	root.GetLinks()[0].GetSession().Title = str("vim")
+	colors := map[string]*api.ServerOriginatedMessage{
+		"sess-1": profileProperties(api.GetProfilePropertyResponse_OK,
+			"Use Tab Color", "true",
+			"Tab Color", `{"Red Component": 1, "Green Component": 0.5, "Blue Component": 0}`),
+		"sess-2": profileProperties(api.GetProfilePropertyResponse_OK,
+			"Use Tab Color", "false",
+			"Tab Color", `{"Red Component": 1, "Green Component": 1, "Blue Component": 1}`),
+		// The last tab closes before its color is fetched.
+		"sess-5": profileProperties(api.GetProfilePropertyResponse_SESSION_NOT_FOUND),
+	}
+	// The color lookups are sent concurrently, so they are answered by
+	// session rather than in order.
+	mock := &mockClient{callFunc: func(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+		if gpr := req.GetGetProfilePropertyRequest(); gpr != nil {
+			return colors[gpr.GetSession()], nil
+		}
+		return lsr, nil
+	}}
+	a := &app{c: mock}
+
+	snap, err := a.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if len(mock.calls) != 4 || mock.calls[0].GetListSessionsRequest() == nil {
+		t.Fatalf("expected the layout and 3 color Calls, got %d", len(mock.calls))
+	}
+	looked := map[string]bool{}
+	for _, call := range mock.calls[1:] {
+		looked[call.GetGetProfilePropertyRequest().GetSession()] = true
+	}
+	for _, want := range []string{"sess-1", "sess-2", "sess-5"} {
+		if !looked[want] {
+			t.Errorf("no color lookup for session %q", want)
+		}
+	}
+	if len(snap.Windows) != 2 || len(snap.Windows[0].Tabs) != 2 || len(snap.Windows[1].Tabs) != 1 {
+		t.Fatalf("Snapshot() = %+v", snap)
+	}
+	first := snap.Windows[0].Tabs[0]
+	if first.ID != "1" || len(first.Sessions) != 1 || first.Sessions[0] != (SessionSnapshot{ID: "sess-1", Title: "vim"}) {
+		t.Errorf("first tab = %+v", first)
+	}
+	if first.Color == nil || *first.Color != (Color{R: 255, G: 128, B: 0}) {
+		t.Errorf("first tab color = %v, want {255 128 0}", first.Color)
+	}
+	second := snap.Windows[0].Tabs[1]
+	if len(second.Sessions) != 3 || second.Sessions[2].ID != "sess-4" {
+		t.Errorf("second tab sessions = %+v", second.Sessions)
+	}
+	if second.Color != nil {
+		t.Errorf("second tab color = %v, want none", second.Color)
+	}
+	if closed := snap.Windows[1].Tabs[0]; closed.ID != "3" || closed.Color != nil {
+		t.Errorf("closed tab = %+v", closed)
+	}
+}
@@ -1,7 +1,10 @@
 package iterm2
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/Tombar/iterm2/api"
 )
@@ -11,31 +14,44 @@ type Tab interface {
 	SetTitle(string) error
 	ListSessions() ([]Session, error)
 	SetColor(r, g, b uint8) error
+	SetColorFromImage(path string) error
+	SetColorEnabled(enabled bool) error
 	Close() error
 	GetID() string
+	GetWindow() (Window, error)
+	MoveToWindow(target Window) error
+	MoveToNewWindow() (Window, error)
 }
 
 type tab struct {
-	c        ClientInterface
-	id       string
+	c  ClientInterface
+	id string
+
+	// mu guards windowID, which moving the tab changes.
+	mu       sync.Mutex
 	windowID string
 }
 
+// window returns the id of the window the tab was last known to be in.
+func (t *tab) window() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.windowID
+}
+
+func (t *tab) setWindow(windowID string) {
+	t.mu.Lock()
+	t.windowID = windowID
+	t.mu.Unlock()
+}
+
 func (t *tab) SetTitle(s string) error {
-	_, err := t.c.Call(&api.ClientOriginatedMessage{
-		Submessage: &api.ClientOriginatedMessage_InvokeFunctionRequest{
-			InvokeFunctionRequest: &api.InvokeFunctionRequest{
-				Invocation: str(fmt.Sprintf(`iterm2.set_title(title: "%s")`, s)),
-				Context: &api.InvokeFunctionRequest_Method_{
-					Method: &api.InvokeFunctionRequest_Method{
-						Receiver: &t.id,
-					},
-				},
-			},
-		},
-	})
+	_, err := invokeMethod(t.c, t.id, "iterm2.set_title(title: "+invocationString(s)+")")
+	if errors.Is(err, errInvalidID) {
+		return fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+	}
 	if err != nil {
-		return fmt.Errorf("could not call set_title: %w", err)
+		return fmt.Errorf("could not set title of tab %q: %w", t.id, err)
 	}
 	return nil
 }
@@ -51,22 +67,30 @@ func (t *tab) ListSessions() ([]Session, error) {
 		return nil, fmt.Errorf("error listing sessions for tab %q: %w", t.id, err)
 	}
 	lsr := resp.GetListSessionsResponse()
+	windowID := t.window()
+	found := false
 	for _, window := range lsr.GetWindows() {
-		if window.GetWindowId() != t.windowID {
+		if window.GetWindowId() != windowID {
 			continue
 		}
 		for _, wt := range window.GetTabs() {
 			if wt.GetTabId() != t.id {
 				continue
 			}
+			found = true
 			for _, link := range wt.GetRoot().GetLinks() {
 				list = append(list, &session{
-					c:  t.c,
-					id: link.GetSession().GetUniqueIdentifier(),
+					c:        t.c,
+					id:       link.GetSession().GetUniqueIdentifier(),
+					windowID: windowID,
+					tabID:    t.id,
 				})
 			}
 		}
 	}
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+	}
 	return list, nil
 }
 
@@ -75,20 +99,51 @@ func (t *tab) GetID() string {
 	return t.id
 }
 
-// SetColor sets the tab's background color using RGB values (0-255)
-func (t *tab) SetColor(r, g, b uint8) error {
-	// Get the first session in the tab to set its profile property
+// GetWindow returns the window the tab is in. Tabs remember the window
+// they were found in, so this normally needs no call to iTerm2; only tabs
+// without that information are looked up.
+func (t *tab) GetWindow() (Window, error) {
+	if windowID := t.window(); windowID != "" {
+		return &window{c: t.c, id: windowID}, nil
+	}
+	lsr, err := listSessions(t.c)
+	if err != nil {
+		return nil, fmt.Errorf("could not get window of tab %q: %w", t.id, err)
+	}
+	for _, w := range lsr.GetWindows() {
+		for _, lt := range w.GetTabs() {
+			if lt.GetTabId() == t.id {
+				return newWindow(t.c, w), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+}
+
+// firstSession returns the tab's first session, whose profile holds the
+// tab's color.
+func (t *tab) firstSession() (*session, error) {
 	sessions, err := t.ListSessions()
 	if err != nil {
-		return fmt.Errorf("could not list sessions for tab %q: %w", t.id, err)
+		return nil, fmt.Errorf("could not list sessions for tab %q: %w", t.id, err)
 	}
 	if len(sessions) == 0 {
-		return fmt.Errorf("tab %q has no sessions", t.id)
+		return nil, fmt.Errorf("tab %q has no sessions", t.id)
 	}
 
 	sess, ok := sessions[0].(*session)
 	if !ok {
-		return fmt.Errorf("session type assertion failed")
+		return nil, fmt.Errorf("unexpected type %T for first session of tab %q", sessions[0], t.id)
+	}
+	return sess, nil
+}
+
+// SetColor sets the tab's background color using RGB values (0-255)
+func (t *tab) SetColor(r, g, b uint8) error {
+	// Get the first session in the tab to set its profile property
+	sess, err := t.firstSession()
+	if err != nil {
+		return err
 	}
 
 	// Set both tab color and use_tab_color properties
@@ -96,31 +151,39 @@ func (t *tab) SetColor(r, g, b uint8) error {
 	colorJSON := fmt.Sprintf(`{"Red Component": %f, "Green Component": %f, "Blue Component": %f}`,
 		float64(r)/255.0, float64(g)/255.0, float64(b)/255.0)
 
-	_, err = t.c.Call(&api.ClientOriginatedMessage{
-		Submessage: &api.ClientOriginatedMessage_SetProfilePropertyRequest{
-			SetProfilePropertyRequest: &api.SetProfilePropertyRequest{
-				Target: &api.SetProfilePropertyRequest_Session{
-					Session: sess.id,
-				},
-				Assignments: []*api.SetProfilePropertyRequest_Assignment{
-					{
-						Key:       str("Tab Color"),
-						JsonValue: str(colorJSON),
-					},
-					{
-						Key:       str("Use Tab Color"),
-						JsonValue: str("true"),
-					},
-				},
-			},
+	err = setProfileProperties(t.c, sess.id,
+		&api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Tab Color"),
+			JsonValue: str(colorJSON),
 		},
-	})
+		&api.SetProfilePropertyRequest_Assignment{
+			Key:       str("Use Tab Color"),
+			JsonValue: str("true"),
+		},
+	)
 	if err != nil {
 		return fmt.Errorf("could not set color for tab %q: %w", t.id, err)
 	}
 	return nil
 }
 
+// SetColorEnabled shows or hides the tab's color while keeping the color
+// itself, so it can be turned back on later without calling SetColor.
+func (t *tab) SetColorEnabled(enabled bool) error {
+	sess, err := t.firstSession()
+	if err != nil {
+		return err
+	}
+	err = setProfileProperties(t.c, sess.id, &api.SetProfilePropertyRequest_Assignment{
+		Key:       str("Use Tab Color"),
+		JsonValue: str(strconv.FormatBool(enabled)),
+	})
+	if err != nil {
+		return fmt.Errorf("could not toggle color for tab %q: %w", t.id, err)
+	}
+	return nil
+}
+
 // Close closes this tab
 func (t *tab) Close() error {
 	resp, err := t.c.Call(&api.ClientOriginatedMessage{
@@ -142,9 +205,27 @@ func (t *tab) Close() error {
 	closeResp := resp.GetCloseResponse()
 	if len(closeResp.GetStatuses()) > 0 {
 		status := closeResp.GetStatuses()[0]
+		if status == api.CloseResponse_NOT_FOUND {
+			return fmt.Errorf("%w: %q", ErrTabNotFound, t.id)
+		}
 		if status != api.CloseResponse_OK {
 			return fmt.Errorf("failed to close tab %q: status %v", t.id, status)
 		}
 	}
 	return nil
 }
+
+// close closes the tab without asking the user for confirmation.
+func (t *tab) close() error {
+	_, err := t.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_CloseRequest{
+			CloseRequest: &api.CloseRequest{
+				Target: &api.CloseRequest_Tabs{
+					Tabs: &api.CloseRequest_CloseTabs{TabIds: []string{t.id}},
+				},
+				Force: b(true),
+			},
+		},
+	})
+	return err
+}
@@ -1,23 +1,47 @@
 package iterm2
 
 import (
+	"errors"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/Tombar/iterm2/api"
 )
 
-// mockClient implements ClientInterface for testing
+// mockClient implements ClientInterface for testing. Calls may be made
+// from several goroutines; mu guards calls and responses while they are.
 type mockClient struct {
+	mu        sync.Mutex
 	calls     []*api.ClientOriginatedMessage
 	responses []*api.ServerOriginatedMessage
 	callFunc  func(*api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error)
+	handlers  []func(*api.Notification)
+}
+
+func (m *mockClient) AddNotificationHandler(h func(*api.Notification)) func() {
+	i := len(m.handlers)
+	m.handlers = append(m.handlers, h)
+	return func() { m.handlers[i] = nil }
+}
+
+// notify delivers n to the registered notification handlers
+func (m *mockClient) notify(n *api.Notification) {
+	for _, h := range m.handlers {
+		if h != nil {
+			h(n)
+		}
+	}
 }
 
 func (m *mockClient) Call(req *api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+	m.mu.Lock()
 	m.calls = append(m.calls, req)
-	if m.callFunc != nil {
-		return m.callFunc(req)
+	if f := m.callFunc; f != nil {
+		m.mu.Unlock()
+		return f(req)
 	}
+	defer m.mu.Unlock()
 	if len(m.responses) > 0 {
 		resp := m.responses[0]
 		m.responses = m.responses[1:]
@@ -429,3 +453,53 @@ func TestSetColor_NoSessions(t *testing.T) {
 		t.Error("SetColor() expected error for tab with no sessions, got nil")
 	}
 }
+
+// TestSetColorEnabled verifies only the Use Tab Color key is written
+func TestSetColorEnabled(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		mock := &mockClient{
+			responses: []*api.ServerOriginatedMessage{layout(), {}},
+		}
+		tab := &tab{c: mock, id: "1", windowID: "win-1"}
+
+		if err := tab.SetColorEnabled(enabled); err != nil {
+			t.Fatalf("SetColorEnabled(%t) error = %v", enabled, err)
+		}
+		req := mock.calls[1].GetSetProfilePropertyRequest()
+		if req.GetSession() != "sess-1" {
+			t.Errorf("session = %q, want %q", req.GetSession(), "sess-1")
+		}
+		assignments := req.GetAssignments()
+		if len(assignments) != 1 || assignments[0].GetKey() != "Use Tab Color" {
+			t.Fatalf("expected a single Use Tab Color assignment, got %v", assignments)
+		}
+		if want := strconv.FormatBool(enabled); assignments[0].GetJsonValue() != want {
+			t.Errorf("Use Tab Color = %q, want %q", assignments[0].GetJsonValue(), want)
+		}
+	}
+}
+
+// TestTabGetWindow verifies the stored window is used and tabs without one are looked up
+func TestTabGetWindow(t *testing.T) {
+	mock := &mockClient{}
+	w, err := (&tab{c: mock, id: "2", windowID: "win-1"}).GetWindow()
+	if err != nil {
+		t.Fatalf("GetWindow() error = %v", err)
+	}
+	if w.(*window).id != "win-1" || len(mock.calls) != 0 {
+		t.Errorf("GetWindow() = %q after %d calls, want win-1 without calls", w.(*window).id, len(mock.calls))
+	}
+
+	w, err = (&tab{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}, id: "3"}).GetWindow()
+	if err != nil {
+		t.Fatalf("GetWindow() error = %v", err)
+	}
+	if w.(*window).id != "win-2" {
+		t.Errorf("GetWindow() = %q, want win-2", w.(*window).id)
+	}
+
+	_, err = (&tab{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}, id: "9"}).GetWindow()
+	if !errors.Is(err, ErrTabNotFound) {
+		t.Errorf("GetWindow() of a closed tab error = %v, want ErrTabNotFound", err)
+	}
+}
@@ -0,0 +1,63 @@
+package iterm2
+
+import (
+	"fmt"
+	"image"
+	// Register the formats SetColorFromImage can decode.
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// maxColorSamples bounds the number of pixels averageColor looks at along
+// each side of an image, so large photos are sampled rather than read in
+// full.
+const maxColorSamples = 256
+
+// SetColorFromImage sets the tab's color to the average color of the PNG or
+// JPEG image at path. Transparent pixels do not count towards the average.
+// Errors opening the file wrap *os.PathError; files that cannot be decoded
+// are reported with ErrInvalidImage. In both cases iTerm2 is not called.
+func (t *tab) SetColorFromImage(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not read image for tab %q: %w", t.id, err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("%w %q for tab %q: %v", ErrInvalidImage, path, t.id, err)
+	}
+	c, ok := averageColor(img)
+	if !ok {
+		return fmt.Errorf("%w %q for tab %q: every pixel is transparent", ErrInvalidImage, path, t.id)
+	}
+	return t.SetColor(c.R, c.G, c.B)
+}
+
+// averageColor returns the alpha-weighted average color of img. It reports
+// false if img has no visible pixels.
+func averageColor(img image.Image) (Color, bool) {
+	bounds := img.Bounds()
+	stepX := bounds.Dx()/maxColorSamples + 1
+	stepY := bounds.Dy()/maxColorSamples + 1
+	var r, g, b, a uint64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			// RGBA returns alpha-premultiplied values, so summing them
+			// weighs every pixel by its opacity.
+			pr, pg, pb, pa := img.At(x, y).RGBA()
+			r += uint64(pr)
+			g += uint64(pg)
+			b += uint64(pb)
+			a += uint64(pa)
+		}
+	}
+	if a == 0 {
+		return Color{}, false
+	}
+	scale := func(v uint64) uint8 {
+		return uint8((v*255 + a/2) / a)
+	}
+	return Color{R: scale(r), G: scale(g), B: scale(b)}, true
+}
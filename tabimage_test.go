@@ -0,0 +1,89 @@
+package iterm2
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestAverageColor verifies the average is weighted by opacity and fully transparent images are rejected
+func TestAverageColor(t *testing.T) {
+	image2 := func(a, b color.Color) image.Image {
+		img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+		img.Set(0, 0, a)
+		img.Set(1, 0, b)
+		return img
+	}
+	tests := []struct {
+		name   string
+		img    image.Image
+		want   Color
+		wantOK bool
+	}{
+		{
+			name:   "mixed",
+			img:    image2(color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}),
+			want:   Color{128, 0, 128},
+			wantOK: true,
+		},
+		{
+			name:   "transparent pixels ignored",
+			img:    image2(color.NRGBA{10, 200, 30, 255}, color.NRGBA{255, 255, 255, 0}),
+			want:   Color{10, 200, 30},
+			wantOK: true,
+		},
+		{
+			name: "fully transparent",
+			img:  image2(color.NRGBA{255, 0, 0, 0}, color.NRGBA{0, 0, 255, 0}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := averageColor(tt.img)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("averageColor() = %v, %t; want %v, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestSetColorFromImage_Errors verifies unreadable and undecodable files are told apart without calling iTerm2
+func TestSetColorFromImage_Errors(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "notes.png")
+	if err := os.WriteFile(text, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	blank := filepath.Join(dir, "blank.png")
+	f, err := os.Create(blank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tests := []struct {
+		path string
+		want error
+	}{
+		{filepath.Join(dir, "missing.png"), os.ErrNotExist},
+		{text, ErrInvalidImage},
+		{blank, ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		mock := &mockClient{}
+		tab := &tab{c: mock, id: "tab-1"}
+		if err := tab.SetColorFromImage(tt.path); !errors.Is(err, tt.want) {
+			t.Errorf("SetColorFromImage(%q) error = %v, want %v", filepath.Base(tt.path), err, tt.want)
+		}
+		if len(mock.calls) != 0 {
+			t.Errorf("expected no Calls, got %d", len(mock.calls))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TitleComponent is one of the pieces of information iTerm2 can show in a
+// session's title. The values are the bits iTerm2 stores in the "Title
+// Components" profile key.
+type TitleComponent int
+
+// Title components. iTerm2 joins the ones that are enabled with " — ", in
+// its own order, and keeps them up to date as they change.
+const (
+	// TitleSessionName is the session's name, which SetTitleFormat sets.
+	TitleSessionName TitleComponent = 1 << 0
+	// TitleJob is the name of the foreground job.
+	TitleJob TitleComponent = 1 << 1
+	// TitleWorkingDirectory is the current directory.
+	TitleWorkingDirectory TitleComponent = 1 << 2
+	// TitleTTY is the session's tty, such as /dev/ttys003.
+	TitleTTY TitleComponent = 1 << 3
+	// TitleProfileName is the name of the session's profile.
+	TitleProfileName TitleComponent = 1 << 5
+	// TitleProfileAndSessionName is the profile name followed by the
+	// session name, or just one of them when they are the same.
+	TitleProfileAndSessionName TitleComponent = 1 << 6
+	// TitleUser is the user name, as reported by shell integration.
+	TitleUser TitleComponent = 1 << 7
+	// TitleHost is the host name, as reported by shell integration.
+	TitleHost TitleComponent = 1 << 8
+	// TitleCommandLine is the full command line of the foreground job.
+	TitleCommandLine TitleComponent = 1 << 9
+	// TitleSize is the session's size in columns and rows.
+	TitleSize TitleComponent = 1 << 10
+)
+
+// allTitleComponents is the union of the components SetTitleComponents
+// accepts.
+const allTitleComponents = TitleSessionName | TitleJob | TitleWorkingDirectory | TitleTTY |
+	TitleProfileName | TitleProfileAndSessionName | TitleUser | TitleHost | TitleCommandLine | TitleSize
+
+// SetTitleComponents chooses what the session's title shows. Unlike a title
+// set with SetTitle, the components are kept up to date by iTerm2.
+func (s *session) SetTitleComponents(components []TitleComponent) error {
+	if len(components) == 0 {
+		return fmt.Errorf("could not set title components of session %q: at least one is required", s.id)
+	}
+	var mask TitleComponent
+	for _, c := range components {
+		if c == 0 || c&^allTitleComponents != 0 {
+			return fmt.Errorf("could not set title components of session %q: invalid component %d", s.id, int(c))
+		}
+		mask |= c
+	}
+	return s.setProfileProperty("Title Components", int(mask))
+}
+
+// SetTitleFormat makes the session's title show format, which may refer to
+// variables as interpolated strings, such as "\(session.jobName) in
+// \(session.path)". iTerm2 evaluates it whenever one of the variables
+// changes. It sets the session name and makes it the only title component,
+// like choosing "Session Name" in the profile's title settings.
+func (s *session) SetTitleFormat(format string) error {
+	name, err := json.Marshal(format)
+	if err != nil {
+		return fmt.Errorf("could not encode title format for session %q: %w", s.id, err)
+	}
+	err = setProfileProperties(s.c, s.id,
+		&api.SetProfilePropertyRequest_Assignment{Key: str("Name"), JsonValue: str(string(name))},
+		&api.SetProfilePropertyRequest_Assignment{Key: str("Title Components"), JsonValue: str(fmt.Sprint(int(TitleSessionName)))},
+	)
+	if err != nil {
+		return fmt.Errorf("could not set title format for session %q: %w", s.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package iterm2
+
+import "testing"
+
+// TestSetTitleComponents verifies components are combined into iTerm2's bitmask and invalid ones rejected
+func TestSetTitleComponents(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []TitleComponent
+		want       string
+		wantErr    bool
+	}{
+		{name: "single", components: []TitleComponent{TitleJob}, want: "2"},
+		{name: "combined", components: []TitleComponent{TitleSessionName, TitleWorkingDirectory, TitleHost}, want: "261"},
+		{name: "duplicates", components: []TitleComponent{TitleSize, TitleSize}, want: "1024"},
+		{name: "empty", wantErr: true},
+		{name: "custom title provider", components: []TitleComponent{1 << 4}, wantErr: true},
+		{name: "unknown bit", components: []TitleComponent{TitleJob, 1 << 11}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{}
+			s := &session{c: mock, id: "sess-1"}
+
+			err := s.SetTitleComponents(tt.components)
+			if tt.wantErr {
+				if err == nil || len(mock.calls) != 0 {
+					t.Errorf("SetTitleComponents() expected error without Calls, got %v and %d calls", err, len(mock.calls))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetTitleComponents() error = %v", err)
+			}
+			a := mock.calls[0].GetSetProfilePropertyRequest().GetAssignments()
+			if len(a) != 1 || a[0].GetKey() != "Title Components" || a[0].GetJsonValue() != tt.want {
+				t.Errorf("assignments = %v, want Title Components=%s", a, tt.want)
+			}
+		})
+	}
+}
+
+// TestSetTitleFormat verifies the format becomes the session name, shown as the only title component
+func TestSetTitleFormat(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.SetTitleFormat(`\(session.jobName) "here"`); err != nil {
+		t.Fatalf("SetTitleFormat() error = %v", err)
+	}
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected a single Call, got %d", len(mock.calls))
+	}
+	got := map[string]string{}
+	for _, a := range mock.calls[0].GetSetProfilePropertyRequest().GetAssignments() {
+		got[a.GetKey()] = a.GetJsonValue()
+	}
+	want := map[string]string{
+		"Name":             `"\\(session.jobName) \"here\""`,
+		"Title Components": "1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("assignments = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %s, want %s", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package iterm2
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SetTouchBarLabel sets the label of the status button iTerm2 shows in the
+// Touch Bar while the session is active, using iTerm2's SetKeyLabel escape
+// sequence. An empty text restores the default label. The text must not
+// contain control characters, which would end the escape sequence early.
+//
+// The sequence is processed by the terminal directly, so it works whatever
+// the session is running. iTerm2 accepts it whether or not the Mac has a
+// Touch Bar, and the status button only shows if the user added it to the
+// Touch Bar with View > Customize Touch Bar; neither can be detected through
+// the API, so no error is returned in those cases.
+func (s *session) SetTouchBarLabel(text string) error {
+	if i := strings.IndexFunc(text, unicode.IsControl); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(text[i:])
+		return fmt.Errorf("could not set Touch Bar label of session %q: control character %q in label", s.id, r)
+	}
+	return s.inject([]byte("\x1b]1337;SetKeyLabel=status=" + text + "\x07"))
+}
@@ -0,0 +1,34 @@
+package iterm2
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSetTouchBarLabel verifies the SetKeyLabel sequence is injected and control characters rejected
+func TestSetTouchBarLabel(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	if err := s.SetTouchBarLabel("prod ⚠️"); err != nil {
+		t.Fatalf("SetTouchBarLabel() error = %v", err)
+	}
+	req := mock.calls[0].GetInjectRequest()
+	if len(req.GetSessionId()) != 1 || req.GetSessionId()[0] != "sess-1" {
+		t.Errorf("session ids = %v, want [sess-1]", req.GetSessionId())
+	}
+	if want := "\x1b]1337;SetKeyLabel=status=prod ⚠️\x07"; string(req.GetData()) != want {
+		t.Errorf("data = %q, want %q", req.GetData(), want)
+	}
+
+	for _, text := range []string{"a\x07b", "\x1b]", "two\nlines"} {
+		mock := &mockClient{}
+		s := &session{c: mock, id: "sess-1"}
+		if err := s.SetTouchBarLabel(text); err == nil || len(mock.calls) != 0 {
+			t.Errorf("SetTouchBarLabel(%q) expected error without Calls, got %v and %d calls", text, err, len(mock.calls))
+		}
+	}
+	s = &session{c: &mockClient{}, id: "sess-1"}
+	if err := s.SetTouchBarLabel("next\u0085line"); err == nil || !strings.Contains(err.Error(), `'\u0085'`) {
+		t.Errorf("SetTouchBarLabel() error = %v, want it to name U+0085", err)
+	}
+}
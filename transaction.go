@@ -0,0 +1,40 @@
+package iterm2
+
+import (
+	"fmt"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// transaction runs fn while iTerm2's main loop is frozen, so the effects of
+// the calls fn makes show up on screen all at once. Keep fn short: iTerm2
+// does not respond to the user until the transaction ends.
+func (a *app) transaction(fn func() error) error {
+	if err := a.setTransaction(true); err != nil {
+		return err
+	}
+	err := fn()
+	if endErr := a.setTransaction(false); err == nil {
+		err = endErr
+	}
+	return err
+}
+
+func (a *app) setTransaction(begin bool) error {
+	resp, err := a.c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_TransactionRequest{
+			TransactionRequest: &api.TransactionRequest{Begin: &begin},
+		},
+	})
+	if err != nil {
+		action := "end"
+		if begin {
+			action = "begin"
+		}
+		return fmt.Errorf("could not %s transaction: %w", action, err)
+	}
+	if status := resp.GetTransactionResponse().GetStatus(); status != api.TransactionResponse_OK {
+		return fmt.Errorf("unexpected transaction status: %s", status)
+	}
+	return nil
+}
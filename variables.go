@@ -0,0 +1,218 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// Scope identifies the object a variable belongs to: the app itself, or a
+// particular window, tab or session.
+type Scope struct {
+	kind api.VariableScope
+	id   string
+}
+
+// AppScope is the scope of app-wide variables such as "effectiveTheme".
+func AppScope() Scope {
+	return Scope{kind: api.VariableScope_APP}
+}
+
+// WindowScope is the scope of the variables of the window with the given id.
+func WindowScope(id string) Scope {
+	return Scope{kind: api.VariableScope_WINDOW, id: id}
+}
+
+// TabScope is the scope of the variables of the tab with the given id.
+func TabScope(id string) Scope {
+	return Scope{kind: api.VariableScope_TAB, id: id}
+}
+
+// SessionScope is the scope of the variables of the session with the given id.
+func SessionScope(id string) Scope {
+	return Scope{kind: api.VariableScope_SESSION, id: id}
+}
+
+// SubscribeVariableChange delivers the new value of the variable name in
+// scope every time it changes. String values are delivered unquoted, an
+// unset variable as the empty string and anything else as JSON. Call the
+// returned function to stop the subscription; the channel is closed once it
+// returns. Values the consumer has not received yet are buffered as
+// described in WithBufferSize.
+func (a *app) SubscribeVariableChange(scope Scope, name string, opts ...SubscribeOption) (<-chan string, func(), error) {
+	o := newSubscribeOptions(opts)
+	ch := make(chan string, o.bufferSize)
+	cancel, err := subscribe(a.c, &api.NotificationRequest{
+		NotificationType: api.NotificationType_NOTIFY_ON_VARIABLE_CHANGE.Enum(),
+		Arguments: &api.NotificationRequest_VariableMonitorRequest{
+			VariableMonitorRequest: &api.VariableMonitorRequest{
+				Name:       &name,
+				Scope:      scope.kind.Enum(),
+				Identifier: &scope.id,
+			},
+		},
+	}, func(n *api.Notification) {
+		vc := n.GetVariableChangedNotification()
+		if vc == nil || vc.GetName() != name || vc.GetScope() != scope.kind {
+			return
+		}
+		if scope.kind != api.VariableScope_APP && vc.GetIdentifier() != scope.id {
+			return
+		}
+		o.send(ch, decodeVariable(vc.GetJsonNewValue()))
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not watch variable %q: %w", name, err)
+	}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			cancel()
+			close(ch)
+		})
+	}, nil
+}
+
+// request returns a VariableRequest addressing the scope.
+func (s Scope) request() *api.VariableRequest {
+	req := &api.VariableRequest{}
+	switch s.kind {
+	case api.VariableScope_APP:
+		req.Scope = &api.VariableRequest_App{App: true}
+	case api.VariableScope_WINDOW:
+		req.Scope = &api.VariableRequest_WindowId{WindowId: s.id}
+	case api.VariableScope_TAB:
+		req.Scope = &api.VariableRequest_TabId{TabId: s.id}
+	case api.VariableScope_SESSION:
+		req.Scope = &api.VariableRequest_SessionId{SessionId: s.id}
+	}
+	return req
+}
+
+// String describes the scope for error messages, such as `session "abc"`.
+func (s Scope) String() string {
+	switch s.kind {
+	case api.VariableScope_APP:
+		return "app"
+	case api.VariableScope_WINDOW:
+		return fmt.Sprintf("window %q", s.id)
+	case api.VariableScope_TAB:
+		return fmt.Sprintf("tab %q", s.id)
+	default:
+		return fmt.Sprintf("session %q", s.id)
+	}
+}
+
+// notFound returns the sentinel error for a missing window, tab or session
+// in the scope.
+func (s Scope) notFound() error {
+	switch s.kind {
+	case api.VariableScope_WINDOW:
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, s.id)
+	case api.VariableScope_TAB:
+		return fmt.Errorf("%w: %q", ErrTabNotFound, s.id)
+	default:
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, s.id)
+	}
+}
+
+// getVariables fetches the variables names in scope with a single request.
+// Unset variables map to the empty string.
+func getVariables(c ClientInterface, scope Scope, names []string) (map[string]string, error) {
+	req := scope.request()
+	req.Get = names
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_VariableRequest{VariableRequest: req},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not get variables %q of %s: %w", names, scope, err)
+	}
+	vr := resp.GetVariableResponse()
+	switch status := vr.GetStatus(); status {
+	case api.VariableResponse_OK:
+	case api.VariableResponse_SESSION_NOT_FOUND, api.VariableResponse_TAB_NOT_FOUND, api.VariableResponse_WINDOW_NOT_FOUND:
+		return nil, scope.notFound()
+	default:
+		return nil, fmt.Errorf("unexpected status getting variables %q of %s: %s", names, scope, status)
+	}
+	if len(vr.GetValues()) != len(names) {
+		return nil, fmt.Errorf("expected %d variable values of %s, got %d", len(names), scope, len(vr.GetValues()))
+	}
+	values := make(map[string]string, len(names))
+	for i, name := range names {
+		values[name] = decodeVariable(vr.GetValues()[i])
+	}
+	return values, nil
+}
+
+// setVariable sets the variable name in scope to the JSON value.
+func setVariable(c ClientInterface, scope Scope, name, value string) error {
+	req := scope.request()
+	req.Set = []*api.VariableRequest_Set{{Name: &name, Value: &value}}
+	resp, err := c.Call(&api.ClientOriginatedMessage{
+		Submessage: &api.ClientOriginatedMessage_VariableRequest{VariableRequest: req},
+	})
+	if err != nil {
+		return fmt.Errorf("could not set variable %q of %s: %w", name, scope, err)
+	}
+	switch status := resp.GetVariableResponse().GetStatus(); status {
+	case api.VariableResponse_OK:
+		return nil
+	case api.VariableResponse_SESSION_NOT_FOUND, api.VariableResponse_TAB_NOT_FOUND, api.VariableResponse_WINDOW_NOT_FOUND:
+		return scope.notFound()
+	default:
+		return fmt.Errorf("unexpected status setting variable %q of %s: %s", name, scope, status)
+	}
+}
+
+// userVariable returns name with the "user." prefix iTerm2 requires for
+// variables set through the API, adding it if it is missing.
+func userVariable(name string) string {
+	if strings.HasPrefix(name, "user.") {
+		return name
+	}
+	return "user." + name
+}
+
+// SetVariable stores value in the app-scoped user variable name, adding the
+// "user." prefix if name lacks it. Values are stored as strings and live
+// until iTerm2 quits, which makes them a simple way for tools to share state
+// for the lifetime of the iTerm2 process. Other scripts and interpolated
+// strings see them as \(user.name).
+func (a *app) SetVariable(name, value string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("could not encode variable %q: %w", name, err)
+	}
+	return setVariable(a.c, AppScope(), userVariable(name), string(data))
+}
+
+// GetVariable returns the app-scoped user variable name, adding the "user."
+// prefix if name lacks it. As with SubscribeVariableChange, an unset
+// variable is returned as the empty string and a value that is not a string,
+// such as one set by a Python script, as JSON.
+func (a *app) GetVariable(name string) (string, error) {
+	name = userVariable(name)
+	values, err := getVariables(a.c, AppScope(), []string{name})
+	if err != nil {
+		return "", err
+	}
+	return values[name], nil
+}
+
+// decodeVariable turns the JSON encoding iTerm2 uses for variable values
+// into a string: strings are unquoted, unset variables ("null") become the
+// empty string and any other value is returned as JSON.
+func decodeVariable(value string) string {
+	var s string
+	if err := json.Unmarshal([]byte(value), &s); err == nil {
+		return s
+	}
+	if value == "null" {
+		return ""
+	}
+	return value
+}
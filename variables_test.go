@@ -0,0 +1,168 @@
+package iterm2
+
+import (
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+func notificationOK() *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_NotificationResponse{
+			NotificationResponse: &api.NotificationResponse{Status: api.NotificationResponse_OK.Enum()},
+		},
+	}
+}
+
+func variableChanged(scope api.VariableScope, id, name, value string) *api.Notification {
+	return &api.Notification{
+		VariableChangedNotification: &api.VariableChangedNotification{
+			Scope:        scope.Enum(),
+			Identifier:   &id,
+			Name:         &name,
+			JsonNewValue: &value,
+		},
+	}
+}
+
+// TestSubscribeVariableChange verifies matching changes are delivered and the subscription is cancelled
+func TestSubscribeVariableChange(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{notificationOK(), notificationOK()},
+	}
+	a := &app{c: mock}
+
+	ch, cancel, err := a.SubscribeVariableChange(SessionScope("sess-1"), "jobName")
+	if err != nil {
+		t.Fatalf("SubscribeVariableChange() error = %v", err)
+	}
+	req := mock.calls[0].GetNotificationRequest()
+	if !req.GetSubscribe() || req.GetNotificationType() != api.NotificationType_NOTIFY_ON_VARIABLE_CHANGE {
+		t.Errorf("unexpected request %v", req)
+	}
+	mon := req.GetVariableMonitorRequest()
+	if mon.GetName() != "jobName" || mon.GetScope() != api.VariableScope_SESSION || mon.GetIdentifier() != "sess-1" {
+		t.Errorf("unexpected monitor request %v", mon)
+	}
+
+	go func() {
+		mock.notify(variableChanged(api.VariableScope_SESSION, "sess-2", "jobName", `"top"`))
+		mock.notify(variableChanged(api.VariableScope_SESSION, "sess-1", "path", `"/tmp"`))
+		mock.notify(variableChanged(api.VariableScope_SESSION, "sess-1", "jobName", `"vim"`))
+	}()
+	if got := <-ch; got != "vim" {
+		t.Errorf("received %q, want %q", got, "vim")
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if len(mock.calls) != 2 || mock.calls[1].GetNotificationRequest().GetSubscribe() {
+		t.Errorf("expected a single unsubscribe request, got %d calls", len(mock.calls))
+	}
+}
+
+// TestSubscribeVariableChange_Rejected verifies a failed subscription is reported and leaves no handler behind
+func TestSubscribeVariableChange_Rejected(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{{
+			Submessage: &api.ServerOriginatedMessage_NotificationResponse{
+				NotificationResponse: &api.NotificationResponse{Status: api.NotificationResponse_INVALID_IDENTIFIER.Enum()},
+			},
+		}},
+	}
+	a := &app{c: mock}
+
+	if _, _, err := a.SubscribeVariableChange(TabScope("bogus"), "title"); err == nil {
+		t.Fatal("SubscribeVariableChange() expected error, got nil")
+	}
+	for _, h := range mock.handlers {
+		if h != nil {
+			t.Error("expected handler to be removed")
+		}
+	}
+}
+
+// TestSubscribeVariableChange_DropOldest verifies a full buffer drops the oldest values and reports each drop
+func TestSubscribeVariableChange_DropOldest(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{notificationOK(), notificationOK()}}
+	a := &app{c: mock}
+
+	dropped := 0
+	ch, cancel, err := a.SubscribeVariableChange(AppScope(), "effectiveTheme",
+		WithBufferSize(2), WithDropHandler(func() { dropped++ }))
+	if err != nil {
+		t.Fatalf("SubscribeVariableChange() error = %v", err)
+	}
+	defer cancel()
+
+	for _, v := range []string{`"light"`, `"dark"`, `"light dark"`, `"dark highContrast"`} {
+		mock.notify(variableChanged(api.VariableScope_APP, "", "effectiveTheme", v))
+	}
+	if dropped != 2 {
+		t.Errorf("dropped = %d, want 2", dropped)
+	}
+	for _, want := range []string{"light dark", "dark highContrast"} {
+		if got := <-ch; got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	}
+}
+
+// TestDecodeVariable verifies JSON variable values are turned into strings
+func TestDecodeVariable(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: `"zsh"`, want: "zsh"},
+		{value: `null`, want: ""},
+		{value: `42`, want: "42"},
+		{value: `{"a":1}`, want: `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		if got := decodeVariable(tt.value); got != tt.want {
+			t.Errorf("decodeVariable(%s) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestAppVariables verifies app variables are set as JSON strings in app scope with the user. prefix added once
+func TestAppVariables(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{
+		variableResponse(api.VariableResponse_OK),
+		variableResponse(api.VariableResponse_OK),
+		variableResponse(api.VariableResponse_OK, `"say \"hi\""`),
+	}}
+	a := &app{c: mock}
+
+	if err := a.SetVariable("state", `say "hi"`); err != nil {
+		t.Fatalf("SetVariable() error = %v", err)
+	}
+	if err := a.SetVariable("user.state", ""); err != nil {
+		t.Fatalf("SetVariable() error = %v", err)
+	}
+	got, err := a.GetVariable("state")
+	if err != nil {
+		t.Fatalf("GetVariable() error = %v", err)
+	}
+	if got != `say "hi"` {
+		t.Errorf("GetVariable() = %q, want %q", got, `say "hi"`)
+	}
+
+	for i, want := range []string{`"say \"hi\""`, `""`} {
+		req := mock.calls[i].GetVariableRequest()
+		if !req.GetApp() || len(req.GetSet()) != 1 {
+			t.Fatalf("unexpected VariableRequest %v", req)
+		}
+		if set := req.GetSet()[0]; set.GetName() != "user.state" || set.GetValue() != want {
+			t.Errorf("set %s = %s, want user.state = %s", set.GetName(), set.GetValue(), want)
+		}
+	}
+	if req := mock.calls[2].GetVariableRequest(); !req.GetApp() || len(req.GetGet()) != 1 || req.GetGet()[0] != "user.state" {
+		t.Errorf("unexpected VariableRequest %v", req)
+	}
+}
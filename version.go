@@ -0,0 +1,78 @@
+package iterm2
+
+import "fmt"
+
+// APIVersion is the version of the API protocol spoken by iTerm2. It is
+// separate from the version of the iTerm2 application: the minor version
+// goes up whenever iTerm2 adds requests or fields to the protocol.
+type APIVersion struct {
+	Major, Minor int
+}
+
+// String returns the version as "major.minor".
+func (v APIVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// AtLeast reports whether v is the given version or a later one.
+func (v APIVersion) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+// protocolVersioner is implemented by clients that know the protocol
+// version iTerm2 announced for their connection.
+type protocolVersioner interface {
+	ProtocolVersion() string
+}
+
+// GetAPIVersion returns the API protocol version iTerm2 announced when the
+// App connected, so that callers can check for features added in later
+// revisions before using them. The version has a major and a minor part,
+// as in "1.8", so it is returned as an APIVersion rather than a single
+// number. Versions of iTerm2 too old to announce one return an error
+// matching ErrUnsupportedByServer.
+//
+// Most methods work with any version of iTerm2 that has the Python API.
+// These need a minimum API version and return an error matching
+// ErrUnsupportedByServer, without calling iTerm2, if the connection
+// announced an older one:
+//
+//	1.1  every Session method that changes the session's profile, such as
+//	     SetTransparency or SetProfileProperties, and the Tab methods that
+//	     set its color
+//	1.8  Session.ListMarks and Session.GetLastCommand
+func (a *app) GetAPIVersion() (APIVersion, error) {
+	pv, ok := a.c.(protocolVersioner)
+	if !ok || pv.ProtocolVersion() == "" {
+		return APIVersion{}, fmt.Errorf("%w: protocol version", ErrUnsupportedByServer)
+	}
+	return parseAPIVersion(pv.ProtocolVersion())
+}
+
+// requireAPIVersion returns an error matching ErrUnsupportedByServer if c
+// announced an API version older than major.minor; feature names what needs
+// it. Connections that announced no version, or one that does not parse,
+// are let through and iTerm2 is left to turn the request down.
+func requireAPIVersion(c ClientInterface, major, minor int, feature string) error {
+	pv, ok := c.(protocolVersioner)
+	if !ok || pv.ProtocolVersion() == "" {
+		return nil
+	}
+	v, err := parseAPIVersion(pv.ProtocolVersion())
+	if err != nil || v.AtLeast(major, minor) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s needs API version %d.%d, iTerm2 offers %s", ErrUnsupportedByServer, feature, major, minor, v)
+}
+
+func parseAPIVersion(s string) (APIVersion, error) {
+	var v APIVersion
+	var rest string
+	if n, _ := fmt.Sscanf(s, "%d.%d%s", &v.Major, &v.Minor, &rest); n != 2 || v.Major < 0 || v.Minor < 0 {
+		return APIVersion{}, fmt.Errorf("%w: protocol version %q", ErrMalformedResponse, s)
+	}
+	return v, nil
+}
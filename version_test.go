@@ -0,0 +1,103 @@
+package iterm2
+
+import (
+	"errors"
+	"testing"
+)
+
+// versionClient is a mockClient that announces a protocol version
+type versionClient struct {
+	mockClient
+	version string
+}
+
+func (v *versionClient) ProtocolVersion() string {
+	return v.version
+}
+
+// TestGetAPIVersion verifies the announced protocol version is parsed and missing or garbled ones are reported
+func TestGetAPIVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       ClientInterface
+		want    APIVersion
+		wantErr error
+	}{
+		{name: "announced", c: &versionClient{version: "1.10"}, want: APIVersion{1, 10}},
+		{name: "not announced", c: &versionClient{}, wantErr: ErrUnsupportedByServer},
+		{name: "client without version", c: &mockClient{}, wantErr: ErrUnsupportedByServer},
+		{name: "garbled", c: &versionClient{version: "1.x"}, wantErr: ErrMalformedResponse},
+		{name: "trailing garbage", c: &versionClient{version: "1.9beta"}, wantErr: ErrMalformedResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &app{c: tt.c}
+			got, err := a.GetAPIVersion()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("GetAPIVersion() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAPIVersion() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetAPIVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAPIVersionAtLeast verifies versions compare by major, then minor version
+func TestAPIVersionAtLeast(t *testing.T) {
+	v := APIVersion{Major: 1, Minor: 9}
+	for _, tt := range []struct {
+		major, minor int
+		want         bool
+	}{
+		{1, 8, true},
+		{1, 9, true},
+		{1, 10, false},
+		{0, 20, true},
+		{2, 0, false},
+	} {
+		if got := v.AtLeast(tt.major, tt.minor); got != tt.want {
+			t.Errorf("%v.AtLeast(%d, %d) = %v, want %v", v, tt.major, tt.minor, got, tt.want)
+		}
+	}
+}
+
+// TestRequireAPIVersion verifies methods needing a newer API fail without calling iTerm2 on older versions
+func TestRequireAPIVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		call    func(s *session) error
+		wantErr bool
+	}{
+		{"profile setter on 1.0", "1.0", func(s *session) error { return s.SetTransparency(0.5) }, true},
+		{"profile setter on 1.1", "1.1", func(s *session) error { return s.SetTransparency(0.5) }, false},
+		{"marks on 1.7", "1.7", func(s *session) error { _, err := s.ListMarks(); return err }, true},
+		{"marks on 1.10", "1.10", func(s *session) error { _, err := s.ListMarks(); return err }, false},
+		{"marks without version", "", func(s *session) error { _, err := s.ListMarks(); return err }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &versionClient{version: tt.version}
+			err := tt.call(&session{c: c, id: "sess-1"})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupportedByServer) {
+				t.Errorf("error = %v, want ErrUnsupportedByServer", err)
+			}
+			if len(c.calls) != 0 {
+				t.Errorf("expected no Calls, got %d", len(c.calls))
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package iterm2
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// waitPollInterval is how often WaitForText looks at the screen.
+const waitPollInterval = 100 * time.Millisecond
+
+// WaitTimeoutError is returned when the text a session was waiting for did
+// not show up in time. It matches ErrWaitTimeout with errors.Is().
+type WaitTimeoutError struct {
+	// Want describes what was being waited for.
+	Want string
+	// Contents is the last screen seen, to help work out what went wrong.
+	Contents string
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for %s; last screen contents:\n%s", e.Want, e.Contents)
+}
+
+func (e *WaitTimeoutError) Unwrap() error {
+	return ErrWaitTimeout
+}
+
+// WaitForText blocks until substr appears on the session's screen or the
+// timeout expires, in which case the error is a *WaitTimeoutError holding
+// the last screen contents.
+func (s *session) WaitForText(substr string, timeout time.Duration) error {
+	return s.waitFor(fmt.Sprintf("%q", substr), timeout, func(screen string) bool {
+		return strings.Contains(screen, substr)
+	})
+}
+
+// WaitForMatch blocks until re matches the session's screen or the timeout
+// expires, in which case the error is a *WaitTimeoutError holding the last
+// screen contents. On a match it returns the same slice as
+// re.FindStringSubmatch: the text of the whole match followed by the text
+// of each capture group. A nil re is reported as an error rather than
+// waited on.
+func (s *session) WaitForMatch(re *regexp.Regexp, timeout time.Duration) ([]string, error) {
+	if re == nil {
+		return nil, fmt.Errorf("could not wait for session %q: nil regexp", s.id)
+	}
+	var match []string
+	err := s.waitFor("match of "+re.String(), timeout, func(screen string) bool {
+		match = re.FindStringSubmatch(screen)
+		return match != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
+// waitFor polls the screen until found reports a match or timeout expires.
+func (s *session) waitFor(want string, timeout time.Duration, found func(screen string) bool) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		contents, err := s.GetScreenContents()
+		if err != nil {
+			return err
+		}
+		screen := contents.String()
+		if found(screen) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return &WaitTimeoutError{Want: want, Contents: screen}
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
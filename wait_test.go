@@ -0,0 +1,97 @@
+package iterm2
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// TestWaitForText verifies polling stops once the text shows up
+func TestWaitForText(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{
+			bufferResponse(0, "$ make"),
+			bufferResponse(0, "$ make", "building..."),
+			bufferResponse(0, "$ make", "building...", "done"),
+		},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	if err := s.WaitForText("done", 5*time.Second); err != nil {
+		t.Fatalf("WaitForText() error = %v", err)
+	}
+	if len(mock.calls) != 3 {
+		t.Errorf("expected 3 polls, got %d", len(mock.calls))
+	}
+}
+
+// TestWaitForText_Timeout verifies the timeout error carries the last screen
+func TestWaitForText_Timeout(t *testing.T) {
+	mock := &mockClient{
+		callFunc: func(*api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+			return bufferResponse(0, "$ make", "error: no rule"), nil
+		},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	err := s.WaitForText("done", 50*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("WaitForText() error = %v, want ErrWaitTimeout", err)
+	}
+	var timeout *WaitTimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("WaitForText() error is %T, want *WaitTimeoutError", err)
+	}
+	if !strings.Contains(timeout.Contents, "error: no rule") {
+		t.Errorf("Contents = %q, want the last screen", timeout.Contents)
+	}
+}
+
+// TestWaitForMatch verifies capture groups are returned once the pattern matches
+func TestWaitForMatch(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{
+			bufferResponse(0, "$ ./server"),
+			bufferResponse(0, "$ ./server", "listening on 127.0.0.1:49152"),
+		},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	got, err := s.WaitForMatch(regexp.MustCompile(`listening on ([\d.]+):(\d+)`), 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForMatch() error = %v", err)
+	}
+	want := []string{"listening on 127.0.0.1:49152", "127.0.0.1", "49152"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("WaitForMatch() = %q, want %q", got, want)
+	}
+}
+
+// TestWaitForMatch_Timeout verifies the timeout error carries the last screen
+func TestWaitForMatch_Timeout(t *testing.T) {
+	mock := &mockClient{
+		callFunc: func(*api.ClientOriginatedMessage) (*api.ServerOriginatedMessage, error) {
+			return bufferResponse(0, "$ ./server", "bind: address already in use"), nil
+		},
+	}
+	s := &session{c: mock, id: "sess-1"}
+
+	_, err := s.WaitForMatch(regexp.MustCompile(`port (\d+)`), 50*time.Millisecond)
+	var timeout *WaitTimeoutError
+	if !errors.As(err, &timeout) || !strings.Contains(timeout.Contents, "already in use") {
+		t.Errorf("WaitForMatch() error = %v, want *WaitTimeoutError with the last screen", err)
+	}
+}
+
+// TestWaitForMatch_NilRegexp verifies a nil regexp is rejected before calling iTerm2
+func TestWaitForMatch_NilRegexp(t *testing.T) {
+	mock := &mockClient{}
+	s := &session{c: mock, id: "sess-1"}
+	if _, err := s.WaitForMatch(nil, time.Second); err == nil || len(mock.calls) != 0 {
+		t.Errorf("WaitForMatch(nil) expected error without Calls, got %v and %d calls", err, len(mock.calls))
+	}
+}
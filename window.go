@@ -1,18 +1,36 @@
 package iterm2
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/Tombar/iterm2/api"
 )
 
 // Window represents an iTerm2 Window
 type Window interface {
+	GetID() string
+	GetNumber() (int, error)
 	SetTitle(s string) error
 	CreateTab() (Tab, error)
+	CreateTabWithEnv(profile string, env map[string]string) (Tab, error)
+	CreateTabWithCommand(command string) (Tab, error)
 	ListTabs() ([]Tab, error)
+	GetTabCount() (int, error)
+	GetTab(id string) (Tab, error)
+	GetCurrentTab() (Tab, error)
 	Activate() error
+	CreateGrid(rows, cols int, profile string) ([][]Session, error)
+	Minimize() error
+	Deminimize() error
+	IsMinimized() (bool, error)
+	SetAlpha(alpha float64) error
+	GetFrame() (Frame, error)
+	SubscribeFrameChange() (<-chan Frame, func(), error)
 }
 
 type window struct {
@@ -21,39 +39,174 @@ type window struct {
 	session string
 }
 
+// GetID returns the unique identifier for this window
+func (w *window) GetID() string {
+	return w.id
+}
+
+// GetNumber returns the number iTerm2 shows in the window's title bar and
+// uses for the Cmd-Option-number shortcuts, which unlike GetID is how users
+// refer to windows. Versions of iTerm2 that do not expose the number return
+// an error matching ErrUnsupportedByServer.
+func (w *window) GetNumber() (int, error) {
+	values, err := getVariables(w.c, WindowScope(w.id), []string{"number"})
+	if err != nil {
+		return 0, fmt.Errorf("could not get number of window %q: %w", w.id, err)
+	}
+	v := values["number"]
+	if v == "" {
+		return 0, fmt.Errorf("%w: number of window %q", ErrUnsupportedByServer, w.id)
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: number %q of window %q", ErrMalformedResponse, v, w.id)
+	}
+	return n, nil
+}
+
+// newWindow returns the handle for a window as described by ListSessions.
+func newWindow(c ClientInterface, w *api.ListSessionsResponse_Window) *window {
+	return &window{
+		c:  c,
+		id: w.GetWindowId(),
+	}
+}
+
 func (w *window) CreateTab() (Tab, error) {
+	return w.createTab(&api.CreateTabRequest{})
+}
+
+// CreateTabWithEnv creates a new tab using the given profile (or the default
+// profile when empty) and exports env in the tab's shell.
+//
+// iTerm2 profiles have no setting for environment variables, so they are
+// exported through the profile's "Initial Text", which iTerm2 types into the
+// shell as soon as it starts. Values are single-quoted so the shell never
+// expands them, and names must be valid shell identifiers.
+func (w *window) CreateTabWithEnv(profile string, env map[string]string) (Tab, error) {
+	req := &api.CreateTabRequest{}
+	if profile != "" {
+		req.ProfileName = str(profile)
+	}
+	if len(env) > 0 {
+		names := make([]string, 0, len(env))
+		for name := range env {
+			if !envNameRe.MatchString(name) {
+				return nil, fmt.Errorf("could not create tab in window %q: invalid environment variable name %q", w.id, name)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		exports := make([]string, 0, len(names))
+		for _, name := range names {
+			exports = append(exports, name+"="+shellQuote(env[name]))
+		}
+		prop, err := profileProperty("Initial Text", "export "+strings.Join(exports, " "))
+		if err != nil {
+			return nil, err
+		}
+		req.CustomProfileProperties = append(req.CustomProfileProperties, prop)
+	}
+	return w.createTab(req)
+}
+
+// CreateTabWithCommand creates a new tab that runs command instead of the
+// login shell. The command line is handed to iTerm2 as is, which splits it
+// into arguments itself; the tab closes when the command exits unless the
+// profile says otherwise.
+func (w *window) CreateTabWithCommand(command string) (Tab, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("could not create tab in window %q: command must not be empty", w.id)
+	}
+	custom, err := profileProperty("Custom Command", "Yes")
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := profileProperty("Command", command)
+	if err != nil {
+		return nil, err
+	}
+	return w.createTab(&api.CreateTabRequest{
+		CustomProfileProperties: []*api.ProfileProperty{custom, cmd},
+	})
+}
+
+// profileProperty builds a profile property assignment, JSON-encoding value
+// as iTerm2 expects.
+func profileProperty(key string, value interface{}) (*api.ProfileProperty, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode profile property %q: %w", key, err)
+	}
+	return &api.ProfileProperty{
+		Key:       str(key),
+		JsonValue: str(string(data)),
+	}, nil
+}
+
+func (w *window) createTab(req *api.CreateTabRequest) (Tab, error) {
+	t, _, err := w.createTabSession(req)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// createTabSession creates a tab as described by req and returns it along
+// with its session.
+func (w *window) createTabSession(req *api.CreateTabRequest) (*tab, *session, error) {
+	req.WindowId = str(w.id)
 	resp, err := w.c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_CreateTabRequest{
-			CreateTabRequest: &api.CreateTabRequest{
-				WindowId: str(w.id),
-			},
+			CreateTabRequest: req,
 		},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("could not create tab for window %q: %w", w.id, err)
+		return nil, nil, fmt.Errorf("could not create tab for window %q: %w", w.id, err)
 	}
 	ctr := resp.GetCreateTabResponse()
-	if ctr.GetStatus() != api.CreateTabResponse_OK {
-		return nil, fmt.Errorf("unexpected tab status: %s", ctr.GetStatus())
-	}
-	return &tab{
+	t := &tab{
 		c:        w.c,
 		id:       strconv.Itoa(int(ctr.GetTabId())),
 		windowID: w.id,
-	}, nil
+	}
+	if err := createTabError(req, ctr.GetStatus()); err != nil {
+		if ctr.GetStatus() == api.CreateTabResponse_INVALID_TAB_INDEX {
+			// iTerm2 created the tab at the end of the window instead;
+			// close it so a failed call leaves nothing behind.
+			t.close()
+		}
+		return nil, nil, fmt.Errorf("could not create tab in window %q: %w", w.id, err)
+	}
+	return t, &session{c: w.c, id: ctr.GetSessionId(), windowID: w.id, tabID: t.id}, nil
+}
+
+// createTabError maps the status of a CreateTabRequest to an error, or nil
+// for OK.
+func createTabError(req *api.CreateTabRequest, status api.CreateTabResponse_Status) error {
+	switch status {
+	case api.CreateTabResponse_OK:
+		return nil
+	case api.CreateTabResponse_INVALID_PROFILE_NAME:
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, req.GetProfileName())
+	case api.CreateTabResponse_INVALID_WINDOW_ID:
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, req.GetWindowId())
+	case api.CreateTabResponse_INVALID_TAB_INDEX:
+		return fmt.Errorf("%w: %d", ErrInvalidTabIndex, req.GetTabIndex())
+	case api.CreateTabResponse_MISSING_SUBSTITUTION:
+		return fmt.Errorf("%w in profile %q", ErrMissingSubstitution, req.GetProfileName())
+	default:
+		return fmt.Errorf("unexpected tab status: %s", status)
+	}
 }
 
 func (w *window) ListTabs() ([]Tab, error) {
 	list := []Tab{}
-	resp, err := w.c.Call(&api.ClientOriginatedMessage{
-		Submessage: &api.ClientOriginatedMessage_ListSessionsRequest{
-			ListSessionsRequest: &api.ListSessionsRequest{},
-		},
-	})
+	lsr, err := listSessions(w.c)
 	if err != nil {
-		return nil, fmt.Errorf("could not list sessions: %w", err)
+		return nil, fmt.Errorf("could not list tabs of window %q: %w", w.id, err)
 	}
-	for _, window := range resp.GetListSessionsResponse().GetWindows() {
+	for _, window := range lsr.GetWindows() {
 		if window.GetWindowId() != w.id {
 			continue
 		}
@@ -64,20 +217,131 @@ func (w *window) ListTabs() ([]Tab, error) {
 				windowID: w.id,
 			})
 		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+}
+
+// GetTabCount returns the number of tabs in the window. It costs the same
+// single request as ListTabs but does not build a handle for every tab.
+func (w *window) GetTabCount() (int, error) {
+	lsr, err := listSessions(w.c)
+	if err != nil {
+		return 0, fmt.Errorf("could not count tabs of window %q: %w", w.id, err)
+	}
+	for _, window := range lsr.GetWindows() {
+		if window.GetWindowId() == w.id {
+			return len(window.GetTabs()), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+}
+
+// addTabs styles first according to specs[0], then creates and styles a
+// tab for each of the remaining specs.
+func (w *window) addTabs(first *tab, specs []TabSpec) ([]Tab, error) {
+	tabs := make([]Tab, 0, len(specs))
+	for i, spec := range specs {
+		var t Tab = first
+		if i > 0 {
+			var err error
+			t, err = w.createTab(tabRequest(spec))
+			if err != nil {
+				return nil, fmt.Errorf("tab %d: %w", i, err)
+			}
+		}
+		if spec.Title != "" {
+			if err := t.SetTitle(spec.Title); err != nil {
+				return nil, fmt.Errorf("tab %d: %w", i, err)
+			}
+		}
+		if spec.Color != nil {
+			if err := t.SetColor(spec.Color.R, spec.Color.G, spec.Color.B); err != nil {
+				return nil, fmt.Errorf("tab %d: %w", i, err)
+			}
+		}
+		tabs = append(tabs, t)
 	}
-	return list, nil
+	return tabs, nil
+}
+
+// GetTab returns the tab of this window with the given id, or
+// ErrTabNotFound if the window has no such tab.
+func (w *window) GetTab(id string) (Tab, error) {
+	tabs, err := w.ListTabs()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tabs {
+		if t.GetID() == id {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q in window %q", ErrTabNotFound, id, w.id)
 }
 
 func (w *window) SetTitle(s string) error {
+	_, err := invokeMethod(w.c, w.id, "iterm2.set_title(title: "+invocationString(s)+")")
+	if errors.Is(err, errInvalidID) {
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+	}
+	if err != nil {
+		return fmt.Errorf("could not set title of window %q: %w", w.id, err)
+	}
+	return nil
+}
+
+// setProperty sets a window property such as "frame" or "fullscreen" to the
+// JSON encoding of value.
+func (w *window) setProperty(name string, value interface{}) error {
+	status, err := setProperty(w.c, &api.SetPropertyRequest{
+		Identifier: &api.SetPropertyRequest_WindowId{WindowId: w.id},
+	}, name, value)
+	if err != nil {
+		return fmt.Errorf("could not set %s of window %q: %w", name, w.id, err)
+	}
+	switch status {
+	case api.SetPropertyResponse_OK:
+		return nil
+	case api.SetPropertyResponse_INVALID_TARGET:
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+	default:
+		return fmt.Errorf("unexpected status setting %s of window %q: %s", name, w.id, status)
+	}
+}
+
+// getProperty reads a window property and decodes its JSON value into v.
+func (w *window) getProperty(name string, v interface{}) error {
+	value, status, err := getProperty(w.c, &api.GetPropertyRequest{
+		Identifier: &api.GetPropertyRequest_WindowId{WindowId: w.id},
+	}, name)
+	if err != nil {
+		return fmt.Errorf("could not get %s of window %q: %w", name, w.id, err)
+	}
+	switch status {
+	case api.GetPropertyResponse_OK:
+	case api.GetPropertyResponse_INVALID_TARGET:
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+	case api.GetPropertyResponse_UNRECOGNIZED_NAME:
+		return fmt.Errorf("%w: property %q of window %q", ErrUnsupportedByServer, name, w.id)
+	default:
+		return fmt.Errorf("unexpected status getting %s of window %q: %s", name, w.id, status)
+	}
+	if err := json.Unmarshal([]byte(value), v); err != nil {
+		return fmt.Errorf("could not decode %s of window %q: %w", name, w.id, err)
+	}
+	return nil
+}
+
+// close force-closes the window without asking the user.
+func (w *window) close() error {
 	_, err := w.c.Call(&api.ClientOriginatedMessage{
-		Submessage: &api.ClientOriginatedMessage_InvokeFunctionRequest{
-			InvokeFunctionRequest: &api.InvokeFunctionRequest{
-				Invocation: str(fmt.Sprintf(`iterm2.set_title(title: "%s")`, s)),
-				Context: &api.InvokeFunctionRequest_Method_{
-					Method: &api.InvokeFunctionRequest_Method{
-						Receiver: &w.id,
-					},
+		Submessage: &api.ClientOriginatedMessage_CloseRequest{
+			CloseRequest: &api.CloseRequest{
+				Target: &api.CloseRequest_Windows{
+					Windows: &api.CloseRequest_CloseWindows{WindowIds: []string{w.id}},
 				},
+				Force: b(true),
 			},
 		},
 	})
@@ -85,11 +349,95 @@ func (w *window) SetTitle(s string) error {
 }
 
 func (w *window) Activate() error {
-	_, err := w.c.Call(&api.ClientOriginatedMessage{
+	resp, err := w.c.Call(&api.ClientOriginatedMessage{
 		Submessage: &api.ClientOriginatedMessage_ActivateRequest{ActivateRequest: &api.ActivateRequest{
 			Identifier:       &api.ActivateRequest_WindowId{WindowId: w.id},
 			OrderWindowFront: b(true),
 		}},
 	})
-	return err
+	if err != nil {
+		return fmt.Errorf("could not activate window %q: %w", w.id, err)
+	}
+	if resp.GetActivateResponse().GetStatus() == api.ActivateResponse_BAD_IDENTIFIER {
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+	}
+	return nil
+}
+
+// IsMinimized reports whether the window is minimized to the Dock. Versions
+// of iTerm2 that do not expose this return an error matching
+// ErrUnsupportedByServer.
+func (w *window) IsMinimized() (bool, error) {
+	var minimized bool
+	if err := w.getProperty("minimized", &minimized); err != nil {
+		return false, err
+	}
+	return minimized, nil
+}
+
+// Minimize minimizes the window to the Dock. Minimizing a window that
+// already is minimized is not an error.
+//
+// iTerm2 has no API call for this, so the window is made key and the
+// Window > Minimize menu item selected, which briefly brings it to the front.
+func (w *window) Minimize() error {
+	minimized, err := w.IsMinimized()
+	switch {
+	case err == nil && minimized:
+		return nil
+	case err != nil && !errors.Is(err, ErrUnsupportedByServer):
+		return err
+	}
+	if err := w.Activate(); err != nil {
+		return err
+	}
+	if err := selectMenuItem(w.c, "Minimize"); err != nil {
+		return fmt.Errorf("could not minimize window %q: %w", w.id, err)
+	}
+	return nil
+}
+
+// Deminimize restores the window from the Dock and brings it to the front.
+// Restoring a window that is not minimized only brings it to the front.
+func (w *window) Deminimize() error {
+	return w.Activate()
+}
+
+// SetAlpha sets how opaque the window's contents are, from 0 (fully
+// transparent) to 1 (opaque).
+//
+// iTerm2 has no transparency setting for a window, only for profiles, so
+// SetAlpha sets the Transparency of every session in the window to 1-alpha
+// in a single transaction, as SetTransparency would. Sessions added to the
+// window later keep the transparency of their own profile.
+func (w *window) SetAlpha(alpha float64) error {
+	if !(alpha >= 0 && alpha <= 1) {
+		return fmt.Errorf("could not set alpha of window %q: %v out of range [0, 1]", w.id, alpha)
+	}
+	lsr, err := listSessions(w.c)
+	if err != nil {
+		return fmt.Errorf("could not set alpha of window %q: %w", w.id, err)
+	}
+	var ids []string
+	found := false
+	for _, window := range lsr.GetWindows() {
+		if window.GetWindowId() != w.id {
+			continue
+		}
+		found = true
+		for _, t := range window.GetTabs() {
+			ids = append(ids, sessionIDs(t.GetRoot())...)
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w: %q", ErrWindowNotFound, w.id)
+	}
+	return (&app{c: w.c}).transaction(func() error {
+		for _, id := range ids {
+			if err := (&session{c: w.c, id: id}).SetTransparency(1 - alpha); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
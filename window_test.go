@@ -0,0 +1,341 @@
+package iterm2
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Tombar/iterm2/api"
+)
+
+// createTabOK is a canned successful CreateTabResponse
+func createTabOK(tabID int32) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_CreateTabResponse{
+			CreateTabResponse: &api.CreateTabResponse{
+				Status:    api.CreateTabResponse_OK.Enum(),
+				WindowId:  str("win-1"),
+				TabId:     &tabID,
+				SessionId: str("sess-1"),
+			},
+		},
+	}
+}
+
+// TestCreateTabWithEnv verifies env vars are exported through the Initial Text profile property
+func TestCreateTabWithEnv(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{createTabOK(7)},
+	}
+	w := &window{c: mock, id: "win-1"}
+
+	tab, err := w.CreateTabWithEnv("Work", map[string]string{
+		"QUOTE": "it's",
+		"FOO":   "bar baz",
+	})
+	if err != nil {
+		t.Fatalf("CreateTabWithEnv() error = %v", err)
+	}
+	if tab.GetID() != "7" {
+		t.Errorf("tab id = %q, want %q", tab.GetID(), "7")
+	}
+
+	if len(mock.calls) != 1 {
+		t.Fatalf("expected 1 Call, got %d", len(mock.calls))
+	}
+	req := mock.calls[0].GetCreateTabRequest()
+	if req.GetWindowId() != "win-1" {
+		t.Errorf("window id = %q, want %q", req.GetWindowId(), "win-1")
+	}
+	if req.GetProfileName() != "Work" {
+		t.Errorf("profile = %q, want %q", req.GetProfileName(), "Work")
+	}
+	props := req.GetCustomProfileProperties()
+	if len(props) != 1 || props[0].GetKey() != "Initial Text" {
+		t.Fatalf("expected a single Initial Text property, got %v", props)
+	}
+	var text string
+	if err := json.Unmarshal([]byte(props[0].GetJsonValue()), &text); err != nil {
+		t.Fatalf("Initial Text is not a JSON string: %v", err)
+	}
+	want := `export FOO='bar baz' QUOTE='it'\''s'`
+	if text != want {
+		t.Errorf("Initial Text = %q, want %q", text, want)
+	}
+}
+
+// TestCreateTabWithEnv_InvalidName verifies invalid names are rejected before calling iTerm2
+func TestCreateTabWithEnv_InvalidName(t *testing.T) {
+	mock := &mockClient{}
+	w := &window{c: mock, id: "win-1"}
+
+	for _, name := range []string{"", "1ABC", "A-B", "A B", "A;rm"} {
+		if _, err := w.CreateTabWithEnv("", map[string]string{name: "x"}); err == nil {
+			t.Errorf("CreateTabWithEnv() accepted invalid name %q", name)
+		}
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected no Calls, got %d", len(mock.calls))
+	}
+}
+
+// TestCreateTabWithCommand verifies the command is set through the Custom Command profile properties
+func TestCreateTabWithCommand(t *testing.T) {
+	mock := &mockClient{
+		responses: []*api.ServerOriginatedMessage{createTabOK(3)},
+	}
+	w := &window{c: mock, id: "win-1"}
+
+	if _, err := w.CreateTabWithCommand(`htop -d "10"`); err != nil {
+		t.Fatalf("CreateTabWithCommand() error = %v", err)
+	}
+	props := mock.calls[0].GetCreateTabRequest().GetCustomProfileProperties()
+	got := map[string]string{}
+	for _, p := range props {
+		got[p.GetKey()] = p.GetJsonValue()
+	}
+	want := map[string]string{
+		"Custom Command": `"Yes"`,
+		"Command":        `"htop -d \"10\""`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("properties = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %s, want %s", k, got[k], v)
+		}
+	}
+}
+
+// TestCreateTabWithCommand_Empty verifies an empty command is rejected before calling iTerm2
+func TestCreateTabWithCommand_Empty(t *testing.T) {
+	mock := &mockClient{}
+	w := &window{c: mock, id: "win-1"}
+
+	if _, err := w.CreateTabWithCommand("  "); err == nil {
+		t.Fatal("CreateTabWithCommand() expected error, got nil")
+	}
+	if len(mock.calls) != 0 {
+		t.Errorf("expected no Calls, got %d", len(mock.calls))
+	}
+}
+
+// TestWindowGetTab verifies the lookup is limited to the window's own tabs
+func TestWindowGetTab(t *testing.T) {
+	w := &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}, id: "win-1"}
+	got, err := w.GetTab("2")
+	if err != nil {
+		t.Fatalf("GetTab() error = %v", err)
+	}
+	if got.GetID() != "2" || got.(*tab).windowID != "win-1" {
+		t.Errorf("GetTab() = %+v", got)
+	}
+
+	w = &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}, id: "win-1"}
+	if _, err := w.GetTab("3"); !errors.Is(err, ErrTabNotFound) {
+		t.Errorf("GetTab() of another window's tab error = %v, want ErrTabNotFound", err)
+	}
+}
+
+// TestGetTabCount verifies the tabs of the window are counted and a missing window reported
+func TestGetTabCount(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    int
+		wantErr error
+	}{
+		{id: "win-1", want: 2},
+		{id: "win-2", want: 1},
+		{id: "win-9", wantErr: ErrWindowNotFound},
+	}
+	for _, tt := range tests {
+		w := &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}, id: tt.id}
+		got, err := w.GetTabCount()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetTabCount() of %s error = %v, want %v", tt.id, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("GetTabCount() of %s = %d, want %d", tt.id, got, tt.want)
+		}
+	}
+}
+
+// windowProperty is a canned GetPropertyResponse
+func windowProperty(status api.GetPropertyResponse_Status, value string) *api.ServerOriginatedMessage {
+	return &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_GetPropertyResponse{
+			GetPropertyResponse: &api.GetPropertyResponse{Status: status.Enum(), JsonValue: str(value)},
+		},
+	}
+}
+
+// TestMinimize verifies the menu item is only selected for windows that are not known to be minimized
+func TestMinimize(t *testing.T) {
+	activateOK := &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_ActivateResponse{
+			ActivateResponse: &api.ActivateResponse{Status: api.ActivateResponse_OK.Enum()},
+		},
+	}
+	menuOK := &api.ServerOriginatedMessage{
+		Submessage: &api.ServerOriginatedMessage_MenuItemResponse{
+			MenuItemResponse: &api.MenuItemResponse{Status: api.MenuItemResponse_OK.Enum()},
+		},
+	}
+	tests := []struct {
+		name      string
+		responses []*api.ServerOriginatedMessage
+		wantCalls int
+	}{
+		{
+			name:      "already minimized",
+			responses: []*api.ServerOriginatedMessage{windowProperty(api.GetPropertyResponse_OK, "true")},
+			wantCalls: 1,
+		},
+		{
+			name:      "not minimized",
+			responses: []*api.ServerOriginatedMessage{windowProperty(api.GetPropertyResponse_OK, "false"), activateOK, menuOK},
+			wantCalls: 3,
+		},
+		{
+			name:      "state unsupported",
+			responses: []*api.ServerOriginatedMessage{windowProperty(api.GetPropertyResponse_UNRECOGNIZED_NAME, ""), activateOK, menuOK},
+			wantCalls: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockClient{responses: tt.responses}
+			w := &window{c: mock, id: "win-1"}
+			if err := w.Minimize(); err != nil {
+				t.Fatalf("Minimize() error = %v", err)
+			}
+			if len(mock.calls) != tt.wantCalls {
+				t.Fatalf("expected %d Calls, got %d", tt.wantCalls, len(mock.calls))
+			}
+			if tt.wantCalls == 3 && mock.calls[2].GetMenuItemRequest().GetIdentifier() != "Minimize" {
+				t.Errorf("menu item = %v, want Minimize", mock.calls[2])
+			}
+		})
+	}
+}
+
+// TestIsMinimized_Unsupported verifies servers without the property are reported with ErrUnsupportedByServer
+func TestIsMinimized_Unsupported(t *testing.T) {
+	w := &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{
+		windowProperty(api.GetPropertyResponse_UNRECOGNIZED_NAME, ""),
+	}}, id: "win-1"}
+	if _, err := w.IsMinimized(); !errors.Is(err, ErrUnsupportedByServer) {
+		t.Errorf("IsMinimized() error = %v, want ErrUnsupportedByServer", err)
+	}
+}
+
+// TestCreateTabErrors verifies every CreateTabResponse status maps to its sentinel error for tabs and windows
+func TestCreateTabErrors(t *testing.T) {
+	tests := []struct {
+		status api.CreateTabResponse_Status
+		want   error
+	}{
+		{api.CreateTabResponse_INVALID_PROFILE_NAME, ErrProfileNotFound},
+		{api.CreateTabResponse_INVALID_WINDOW_ID, ErrWindowNotFound},
+		{api.CreateTabResponse_INVALID_TAB_INDEX, ErrInvalidTabIndex},
+		{api.CreateTabResponse_MISSING_SUBSTITUTION, ErrMissingSubstitution},
+	}
+	response := func(status api.CreateTabResponse_Status) []*api.ServerOriginatedMessage {
+		resp := createTabOK(5)
+		resp.GetCreateTabResponse().Status = status.Enum()
+		return []*api.ServerOriginatedMessage{resp}
+	}
+	for _, tt := range tests {
+		t.Run(tt.status.String(), func(t *testing.T) {
+			mock := &mockClient{responses: response(tt.status)}
+			w := &window{c: mock, id: "win-1"}
+			tab, err := w.CreateTab()
+			if !errors.Is(err, tt.want) || tab != nil {
+				t.Errorf("CreateTab() = %v, %v, want no tab and %v", tab, err, tt.want)
+			}
+			closed := len(mock.calls) == 2 && mock.calls[1].GetCloseRequest().GetTabs().GetTabIds()[0] == "5"
+			if closed != (tt.status == api.CreateTabResponse_INVALID_TAB_INDEX) {
+				t.Errorf("CreateTab() made %d calls; only a misplaced tab should be closed", len(mock.calls))
+			}
+
+			a := &app{c: &mockClient{responses: response(tt.status)}}
+			if _, err := a.CreateWindow(); !errors.Is(err, tt.want) {
+				t.Errorf("CreateWindow() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestGetNumber verifies the number variable is parsed and a missing one is reported as unsupported
+func TestGetNumber(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{variableResponse(api.VariableResponse_OK, "3")}}
+	w := &window{c: mock, id: "win-1"}
+	n, err := w.GetNumber()
+	if err != nil {
+		t.Fatalf("GetNumber() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("GetNumber() = %d, want 3", n)
+	}
+	req := mock.calls[0].GetVariableRequest()
+	if req.GetWindowId() != "win-1" || len(req.GetGet()) != 1 || req.GetGet()[0] != "number" {
+		t.Errorf("unexpected VariableRequest %v", req)
+	}
+
+	tests := []struct {
+		resp *api.ServerOriginatedMessage
+		want error
+	}{
+		{variableResponse(api.VariableResponse_OK, "null"), ErrUnsupportedByServer},
+		{variableResponse(api.VariableResponse_OK, `"x"`), ErrMalformedResponse},
+		{variableResponse(api.VariableResponse_WINDOW_NOT_FOUND), ErrWindowNotFound},
+	}
+	for _, tt := range tests {
+		w := &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{tt.resp}}, id: "win-1"}
+		if _, err := w.GetNumber(); !errors.Is(err, tt.want) {
+			t.Errorf("GetNumber() error = %v, want %v", err, tt.want)
+		}
+	}
+}
+
+// TestSetAlpha verifies the transparency of every session in the window is set in one transaction
+func TestSetAlpha(t *testing.T) {
+	mock := &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}
+	w := &window{c: mock, id: "win-1"}
+	if err := w.SetAlpha(0.75); err != nil {
+		t.Fatalf("SetAlpha() error = %v", err)
+	}
+	if len(mock.calls) != 7 {
+		t.Fatalf("expected 7 Calls, got %d", len(mock.calls))
+	}
+	if !mock.calls[1].GetTransactionRequest().GetBegin() || mock.calls[6].GetTransactionRequest() == nil {
+		t.Errorf("expected the changes to be wrapped in a transaction, got %v and %v", mock.calls[1], mock.calls[6])
+	}
+	var got []string
+	for _, call := range mock.calls[2:6] {
+		req := call.GetSetProfilePropertyRequest()
+		a := req.GetAssignments()
+		if len(a) != 1 || a[0].GetKey() != "Transparency" || a[0].GetJsonValue() != "0.25" {
+			t.Errorf("assignments = %v, want Transparency=0.25", a)
+		}
+		got = append(got, req.GetSession())
+	}
+	if len(got) != 4 || got[0] != "sess-1" || got[3] != "sess-4" {
+		t.Errorf("sessions = %v, want sess-1 through sess-4", got)
+	}
+
+	for _, alpha := range []float64{-0.5, 2, math.NaN()} {
+		mock := &mockClient{}
+		w := &window{c: mock, id: "win-1"}
+		if err := w.SetAlpha(alpha); err == nil || len(mock.calls) != 0 {
+			t.Errorf("SetAlpha(%v) expected error without Calls, got %v and %d calls", alpha, err, len(mock.calls))
+		}
+	}
+
+	w = &window{c: &mockClient{responses: []*api.ServerOriginatedMessage{layout()}}, id: "win-9"}
+	if err := w.SetAlpha(1); !errors.Is(err, ErrWindowNotFound) {
+		t.Errorf("SetAlpha() of a missing window error = %v, want ErrWindowNotFound", err)
+	}
+}